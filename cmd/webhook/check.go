@@ -0,0 +1,126 @@
+/*
+ * Check - configuration validation and self-test.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/configfile"
+	"external-dns-hetzner-webhook/internal/hetzner"
+	"external-dns-hetzner-webhook/internal/logging"
+	"external-dns-hetzner-webhook/internal/metrics"
+	"external-dns-hetzner-webhook/internal/server"
+	"external-dns-hetzner-webhook/internal/tracing"
+)
+
+// checkTimeout is the maximum time for the API call of the self-test.
+const checkTimeout = 30 * time.Second
+
+// validator is the interface of the options that can be validated.
+type validator interface {
+	Validate() error
+}
+
+// validateAll validates the options and returns all the problems found.
+func validateAll(options ...validator) error {
+	errs := make([]error, 0, len(options))
+	for _, o := range options {
+		errs = append(errs, o.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+// readAllOptions reads and validates all the options. It returns the socket
+// options and the provider configuration.
+func readAllOptions(values configfile.Values) (*server.SocketOptions, *hetzner.Configuration, error) {
+	loggingOptions, err := readOptions(logging.NewOptions, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracingOptions, err := readOptions(tracing.NewOptions, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	metricsOptions, err := readOptions(metrics.NewOptions, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	socketOptions, err := readOptions(server.NewSocketOptions, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := readOptions(hetzner.NewConfiguration, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = validateAll(loggingOptions, tracingOptions, metricsOptions, socketOptions, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return socketOptions, config, nil
+}
+
+// check validates the configuration and prints the effective configuration,
+// with the secrets redacted. With --api, it also lists the first zone of every
+// project, to verify that the API keys are accepted.
+func check(args []string, values configfile.Values, out io.Writer) error {
+	var callAPI bool
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "--api":
+		callAPI = true
+	default:
+		return errors.New("usage: webhook --check [--api]")
+	}
+	socketOptions, config, err := readAllOptions(values)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(effectiveConfig{
+		Provider: config.Redacted(),
+		Sockets:  *socketOptions,
+	})
+	if err != nil {
+		return err
+	}
+	if !callAPI {
+		return nil
+	}
+	p, err := createProvider(config, nil)
+	if err != nil {
+		return err
+	}
+	pinger, ok := p.(server.Pinger)
+	if !ok {
+		return errors.New("the provider does not support the API check")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	if err := pinger.Ping(ctx); err != nil {
+		return fmt.Errorf("API check failed: %w", err)
+	}
+	fmt.Fprintln(out, "API check successful.")
+	return nil
+}
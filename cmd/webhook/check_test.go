@@ -0,0 +1,183 @@
+/*
+ * Check - unit tests
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"external-dns-hetzner-webhook/internal/configfile"
+	"external-dns-hetzner-webhook/internal/hetzner"
+	"external-dns-hetzner-webhook/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// mockPingProvider is a provider that only answers to the health checks.
+type mockPingProvider struct {
+	provider.Provider
+	err    error
+	called bool
+}
+
+// Ping records the call and returns the configured error.
+func (p *mockPingProvider) Ping(ctx context.Context) error {
+	p.called = true
+	return p.err
+}
+
+// Test_check tests check().
+func Test_check(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			args   []string
+			values configfile.Values
+			ping   error
+		}
+		expected struct {
+			output []string
+			called bool
+			err    string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		t.Setenv("HETZNER_API_KEY", "TEST_API_KEY")
+		p := &mockPingProvider{err: inp.ping}
+		bkpCreateProvider := createProvider
+		createProvider = func(config *hetzner.Configuration, m *metrics.OpenMetrics) (provider.Provider, error) {
+			return p, nil
+		}
+		defer func() { createProvider = bkpCreateProvider }()
+		var out bytes.Buffer
+		err := check(inp.args, inp.values, &out)
+		if exp.err != "" {
+			assert.EqualError(t, err, exp.err)
+		} else {
+			assert.Nil(t, err)
+		}
+		for _, s := range exp.output {
+			assert.Contains(t, out.String(), s)
+		}
+		assert.NotContains(t, out.String(), "TEST_API_KEY")
+		assert.Equal(t, exp.called, p.called)
+	}
+
+	testCases := []testCase{
+		{
+			name: "valid configuration",
+			expected: struct {
+				output []string
+				called bool
+				err    string
+			}{
+				output: []string{`"APIKey": "REDACTED"`, `"WebhookPort": 8888`},
+			},
+		},
+		{
+			name: "API check",
+			input: struct {
+				args   []string
+				values configfile.Values
+				ping   error
+			}{
+				args: []string{"--api"},
+			},
+			expected: struct {
+				output []string
+				called bool
+				err    string
+			}{
+				output: []string{`"APIKey": "REDACTED"`, "API check successful."},
+				called: true,
+			},
+		},
+		{
+			name: "API check failed",
+			input: struct {
+				args   []string
+				values configfile.Values
+				ping   error
+			}{
+				args: []string{"--api"},
+				ping: errors.New("unauthorized"),
+			},
+			expected: struct {
+				output []string
+				called bool
+				err    string
+			}{
+				called: true,
+				err:    "API check failed: unauthorized",
+			},
+		},
+		{
+			name: "invalid configuration",
+			input: struct {
+				args   []string
+				values configfile.Values
+				ping   error
+			}{
+				args: []string{"--api"},
+				values: configfile.Values{
+					"LOG_FORMAT":       "xml",
+					"BATCH_SIZE":       "500",
+					"UNIX_SOCKET_MODE": "0999",
+				},
+			},
+			expected: struct {
+				output []string
+				called bool
+				err    string
+			}{
+				err: "unsupported log format xml\n" +
+					"invalid unix socket mode 0999\n" +
+					"BATCH_SIZE must be between 1 and 100, got 500",
+			},
+		},
+		{
+			name: "wrong arguments",
+			input: struct {
+				args   []string
+				values configfile.Values
+				ping   error
+			}{
+				args: []string{"--zones"},
+			},
+			expected: struct {
+				output []string
+				called bool
+				err    string
+			}{
+				err: "usage: webhook --check [--api]",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
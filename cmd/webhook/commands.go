@@ -0,0 +1,390 @@
+/*
+ * Commands - zone operations from the command line.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"external-dns-hetzner-webhook/internal/configfile"
+	"external-dns-hetzner-webhook/internal/hetzner"
+	hetznercloud "external-dns-hetzner-webhook/internal/hetzner/cloud"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultOwner is the default owner ID of the reconciled records.
+const defaultOwner = "reconcile"
+
+// errDrift is returned when the records differ from the desired ones.
+var errDrift = errors.New("drift detected")
+
+// Output formats of the commands.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// zoneProvider is the interface of the provider used by the commands.
+type zoneProvider interface {
+	ListZones(ctx context.Context) ([]hetznercloud.ZoneSummary, error)
+	Records(ctx context.Context) ([]*endpoint.Endpoint, error)
+	ZoneRecords(ctx context.Context, zoneName string) ([]*endpoint.Endpoint, error)
+	ExportZonefile(ctx context.Context, zoneName string) (string, error)
+	ImportZonefile(ctx context.Context, zoneName, zf string) error
+	ApplyChanges(ctx context.Context, changes *plan.Changes) error
+	PlanReconcile(ctx context.Context, desired []*endpoint.Endpoint, owner string) (*hetznercloud.ReconcilePlan, error)
+	RestoreZonefile(ctx context.Context, zoneName, backupName string) error
+}
+
+// newZoneProvider creates the provider used by the commands. It is mockable.
+var newZoneProvider = func(config *hetzner.Configuration) (zoneProvider, error) {
+	// The commands are one-off: no metrics are collected.
+	return hetznercloud.NewHetznerProvider(config, nil)
+}
+
+// commandArgs contains the parsed arguments of a command.
+type commandArgs struct {
+	// Positional arguments
+	args []string
+	// Output format
+	format string
+	// True if the changes must only be planned
+	planOnly bool
+	// Owner ID of the reconciled records
+	owner string
+}
+
+// command is a zone operation that can be run from the command line.
+type command struct {
+	// Usage, without the program name
+	usage string
+	// Minimum and maximum number of positional arguments
+	minArgs, maxArgs int
+	// True if the command accepts the --output flag
+	output bool
+	// True if the command accepts the --dry-run flag
+	dryRun bool
+	// Adds the flags specific to the command
+	flags func(flags *flag.FlagSet, a *commandArgs)
+	// Runs the command
+	run func(ctx context.Context, p zoneProvider, a commandArgs, out io.Writer) error
+}
+
+// commands contains the commands, indexed by name.
+var commands = map[string]command{
+	"zones": {
+		usage:  "zones [--output table|json|yaml]",
+		output: true,
+		run:    listZones,
+	},
+	"records": {
+		usage:   "records [--output table|json|yaml] [<zone>]",
+		maxArgs: 1,
+		output:  true,
+		run:     dumpRecords,
+	},
+	"export": {
+		usage:   "export <zone> [<file>]",
+		minArgs: 1,
+		maxArgs: 2,
+		run:     exportZonefile,
+	},
+	"import": {
+		usage:   "import [--dry-run] <zone> <file>",
+		minArgs: 2,
+		maxArgs: 2,
+		dryRun:  true,
+		run:     importZonefile,
+	},
+	"restore": {
+		usage:   "restore [--dry-run] <zone> [<backup>]",
+		minArgs: 1,
+		maxArgs: 2,
+		dryRun:  true,
+		run:     restoreZonefile,
+	},
+	"apply": {
+		usage:   "apply [--dry-run] <changes.json>",
+		minArgs: 1,
+		maxArgs: 1,
+		dryRun:  true,
+		run:     applyChanges,
+	},
+	"reconcile": {
+		usage:   "reconcile [--plan] [--owner <id>] [--output table|json|yaml] <file>",
+		minArgs: 1,
+		maxArgs: 1,
+		output:  true,
+		flags: func(flags *flag.FlagSet, a *commandArgs) {
+			flags.BoolVar(&a.planOnly, "plan", false, "only plan the changes")
+			flags.StringVar(&a.owner, "owner", defaultOwner, "owner ID of the records")
+		},
+		run: reconcile,
+	},
+}
+
+// runCommand parses the arguments of a command, creates the provider and runs
+// the command. The results are written to out.
+func runCommand(name string, args []string, values configfile.Values, out io.Writer) error {
+	cmd, found := commands[name]
+	if !found {
+		return fmt.Errorf("unknown command %s", name)
+	}
+	usage := errors.New("usage: webhook " + cmd.usage)
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	a := commandArgs{format: formatTable}
+	if cmd.output {
+		flags.StringVar(&a.format, "output", formatTable, "output format")
+	}
+	var dryRun bool
+	if cmd.dryRun {
+		flags.BoolVar(&dryRun, "dry-run", false, "do not apply the changes")
+	}
+	if cmd.flags != nil {
+		cmd.flags(flags, &a)
+	}
+	if err := flags.Parse(args); err != nil {
+		return usage
+	}
+	if flags.NArg() < cmd.minArgs || flags.NArg() > cmd.maxArgs {
+		return usage
+	}
+	a.args = flags.Args()
+	switch a.format {
+	case formatTable, formatJSON, formatYAML:
+	default:
+		return fmt.Errorf("unsupported output format %s", a.format)
+	}
+	config, err := readOptions(hetzner.NewConfiguration, values)
+	if err != nil {
+		return err
+	}
+	config.DryRun = config.DryRun || dryRun
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	p, err := newZoneProvider(config)
+	if err != nil {
+		return err
+	}
+	return cmd.run(context.Background(), p, a, out)
+}
+
+// writeOutput writes the value in the requested format. The table format is
+// written by the table function.
+func writeOutput(out io.Writer, format string, value any, table func(w io.Writer)) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case formatYAML:
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	default:
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	}
+}
+
+// listZones lists the zones matching the domain filter.
+func listZones(ctx context.Context, p zoneProvider, a commandArgs, out io.Writer) error {
+	zones, err := p.ListZones(ctx)
+	if err != nil {
+		return err
+	}
+	return writeOutput(out, a.format, zones, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tTTL\tMODE\tSTATUS\tRECORDS")
+		for _, z := range zones {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%d\n", z.ID, z.Name, z.TTL, z.Mode, z.Status, z.RecordCount)
+		}
+	})
+}
+
+// formatProviderSpecific formats the provider specific properties of an
+// endpoint as name=value pairs.
+func formatProviderSpecific(ps endpoint.ProviderSpecific) string {
+	pairs := make([]string, 0, len(ps))
+	for _, p := range ps {
+		pairs = append(pairs, p.Name+"="+p.Value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// dumpRecords lists the records of a zone, or of all the zones matching the
+// domain filter, as they are returned to ExternalDNS.
+func dumpRecords(ctx context.Context, p zoneProvider, a commandArgs, out io.Writer) error {
+	var endpoints []*endpoint.Endpoint
+	var err error
+	if len(a.args) == 1 {
+		endpoints, err = p.ZoneRecords(ctx, a.args[0])
+	} else {
+		endpoints, err = p.Records(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	return writeOutput(out, a.format, endpoints, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tTYPE\tTTL\tTARGETS\tPROVIDER SPECIFIC")
+		for _, ep := range endpoints {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", ep.DNSName, ep.RecordType, ep.RecordTTL,
+				strings.Join(ep.Targets, ","), formatProviderSpecific(ep.ProviderSpecific))
+		}
+	})
+}
+
+// exportZonefile writes the zonefile of a zone to a file or, if no file is
+// given, to the output.
+func exportZonefile(ctx context.Context, p zoneProvider, a commandArgs, out io.Writer) error {
+	zf, err := p.ExportZonefile(ctx, a.args[0])
+	if err != nil {
+		return err
+	}
+	if len(a.args) == 2 {
+		return os.WriteFile(a.args[1], []byte(zf), 0o600)
+	}
+	_, err = io.WriteString(out, zf)
+	return err
+}
+
+// importZonefile replaces the records of a zone with the ones of a zonefile.
+func importZonefile(ctx context.Context, p zoneProvider, a commandArgs, out io.Writer) error {
+	zf, err := os.ReadFile(a.args[1])
+	if err != nil {
+		return err
+	}
+	return p.ImportZonefile(ctx, a.args[0], string(zf))
+}
+
+// restoreZonefile restores a zonefile backup. If the backup name is omitted,
+// the newest backup is restored.
+func restoreZonefile(ctx context.Context, p zoneProvider, a commandArgs, out io.Writer) error {
+	var backupName string
+	if len(a.args) == 2 {
+		backupName = a.args[1]
+	}
+	return p.RestoreZonefile(ctx, a.args[0], backupName)
+}
+
+// readChanges reads a JSON file containing the changes planned by
+// ExternalDNS. Unknown fields are rejected.
+func readChanges(path string) (*plan.Changes, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	changes := &plan.Changes{}
+	if err := decoder.Decode(changes); err != nil {
+		return nil, fmt.Errorf("cannot read changes from %s: %w", path, err)
+	}
+	return changes, nil
+}
+
+// applyChanges applies the changes of a JSON file.
+func applyChanges(ctx context.Context, p zoneProvider, a commandArgs, out io.Writer) error {
+	changes, err := readChanges(a.args[0])
+	if err != nil {
+		return err
+	}
+	if err := p.ApplyChanges(ctx, changes); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Processed %d creates, %d updates and %d deletes.\n",
+		len(changes.Create), len(changes.UpdateNew), len(changes.Delete))
+	return nil
+}
+
+// readDesired reads a YAML or JSON file containing a list of endpoints.
+// Unknown fields are rejected.
+func readDesired(path string) ([]*endpoint.Endpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	desired := []*endpoint.Endpoint{}
+	if err := yaml.UnmarshalStrict(data, &desired); err != nil {
+		return nil, fmt.Errorf("cannot read records from %s: %w", path, err)
+	}
+	return desired, nil
+}
+
+// writePlanRows writes the endpoints of a plan as table rows.
+func writePlanRows(w io.Writer, action string, endpoints []*endpoint.Endpoint) {
+	for _, ep := range endpoints {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", action, ep.DNSName, ep.RecordType, ep.RecordTTL,
+			strings.Join(ep.Targets, ","))
+	}
+}
+
+// reconcile brings the records of the managed zones to the ones of a file.
+// The plan is written to the output and then applied, unless only planning
+// is requested. It returns errDrift if the plan has changes and is not
+// applied, or if there are conflicts with records not owned.
+func reconcile(ctx context.Context, p zoneProvider, a commandArgs, out io.Writer) error {
+	desired, err := readDesired(a.args[0])
+	if err != nil {
+		return err
+	}
+	rp, err := p.PlanReconcile(ctx, desired, a.owner)
+	if err != nil {
+		return err
+	}
+	err = writeOutput(out, a.format, rp, func(w io.Writer) {
+		fmt.Fprintln(w, "ACTION\tNAME\tTYPE\tTTL\tTARGETS")
+		writePlanRows(w, "create", rp.Changes.Create)
+		writePlanRows(w, "update", rp.Changes.UpdateNew)
+		writePlanRows(w, "delete", rp.Changes.Delete)
+		writePlanRows(w, "conflict", rp.Conflicts)
+	})
+	if err != nil {
+		return err
+	}
+	if a.planOnly {
+		if rp.HasDrift() {
+			return errDrift
+		}
+		return nil
+	}
+	if err := p.ApplyChanges(ctx, rp.Changes); err != nil {
+		return err
+	}
+	if len(rp.Conflicts) > 0 {
+		return fmt.Errorf("%w: %d records not owned by %s", errDrift, len(rp.Conflicts), a.owner)
+	}
+	return nil
+}
@@ -0,0 +1,587 @@
+/*
+ * Commands - unit tests
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"external-dns-hetzner-webhook/internal/configfile"
+	"external-dns-hetzner-webhook/internal/hetzner"
+	hetznercloud "external-dns-hetzner-webhook/internal/hetzner/cloud"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// mockZoneProvider simulates the provider used by the commands.
+type mockZoneProvider struct {
+	config   *hetzner.Configuration
+	zones    []hetznercloud.ZoneSummary
+	records  []*endpoint.Endpoint
+	zonefile string
+	imported string
+	backup   string
+	changes  *plan.Changes
+	zone     string
+	plan     *hetznercloud.ReconcilePlan
+	desired  []*endpoint.Endpoint
+	owner    string
+	err      error
+}
+
+// ListZones returns the configured zones.
+func (p *mockZoneProvider) ListZones(ctx context.Context) ([]hetznercloud.ZoneSummary, error) {
+	return p.zones, p.err
+}
+
+// Records returns the configured records.
+func (p *mockZoneProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return p.records, p.err
+}
+
+// ZoneRecords records the zone and returns the configured records.
+func (p *mockZoneProvider) ZoneRecords(ctx context.Context, zoneName string) ([]*endpoint.Endpoint, error) {
+	p.zone = zoneName
+	return p.records, p.err
+}
+
+// ExportZonefile records the zone and returns the configured zonefile.
+func (p *mockZoneProvider) ExportZonefile(ctx context.Context, zoneName string) (string, error) {
+	p.zone = zoneName
+	return p.zonefile, p.err
+}
+
+// ImportZonefile records the zone and the imported zonefile.
+func (p *mockZoneProvider) ImportZonefile(ctx context.Context, zoneName, zf string) error {
+	p.zone = zoneName
+	p.imported = zf
+	return p.err
+}
+
+// ApplyChanges records the changes.
+func (p *mockZoneProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	p.changes = changes
+	return p.err
+}
+
+// PlanReconcile records the desired records and the owner and returns the
+// configured plan.
+func (p *mockZoneProvider) PlanReconcile(ctx context.Context, desired []*endpoint.Endpoint, owner string) (*hetznercloud.ReconcilePlan, error) {
+	p.desired = desired
+	p.owner = owner
+	return p.plan, p.err
+}
+
+// RestoreZonefile records the zone and the backup name.
+func (p *mockZoneProvider) RestoreZonefile(ctx context.Context, zoneName, backupName string) error {
+	p.zone = zoneName
+	p.backup = backupName
+	return p.err
+}
+
+// mockNewZoneProvider replaces the provider of the commands with p for the
+// duration of the test.
+func mockNewZoneProvider(t *testing.T, p *mockZoneProvider) {
+	t.Setenv("HETZNER_API_KEY", "TEST_API_KEY")
+	bkpNewZoneProvider := newZoneProvider
+	newZoneProvider = func(config *hetzner.Configuration) (zoneProvider, error) {
+		p.config = config
+		return p, nil
+	}
+	t.Cleanup(func() { newZoneProvider = bkpNewZoneProvider })
+}
+
+// Test_runCommand_output tests the output formats of the commands.
+func Test_runCommand_output(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			command string
+			args    []string
+		}
+		expected struct {
+			output string
+			zone   string
+			err    string
+		}
+	}
+
+	p := &mockZoneProvider{
+		zones: []hetznercloud.ZoneSummary{
+			{ID: 1, Name: "alpha.com", TTL: 3600, Mode: "primary", Status: "ok", RecordCount: 3},
+		},
+		records: []*endpoint.Endpoint{
+			{
+				DNSName:    "www.alpha.com",
+				RecordType: "A",
+				RecordTTL:  300,
+				Targets:    endpoint.Targets{"127.0.0.1", "127.0.0.2"},
+				ProviderSpecific: endpoint.ProviderSpecific{
+					{Name: "webhook/hetzner-label-env", Value: "prod"},
+				},
+			},
+		},
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		mockNewZoneProvider(t, p)
+		p.zone = ""
+		var out bytes.Buffer
+		err := runCommand(inp.command, inp.args, nil, &out)
+		if exp.err != "" {
+			assert.EqualError(t, err, exp.err)
+			return
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, exp.output, out.String())
+		assert.Equal(t, exp.zone, p.zone)
+	}
+
+	testCases := []testCase{
+		{
+			name: "zones table",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "zones",
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				output: "ID  NAME       TTL   MODE     STATUS  RECORDS\n" +
+					"1   alpha.com  3600  primary  ok      3\n",
+			},
+		},
+		{
+			name: "zones json",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "zones",
+				args:    []string{"--output", "json"},
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				output: "[\n  {\n    \"id\": 1,\n    \"name\": \"alpha.com\",\n    \"ttl\": 3600,\n" +
+					"    \"mode\": \"primary\",\n    \"status\": \"ok\",\n    \"recordCount\": 3\n  }\n]\n",
+			},
+		},
+		{
+			name: "zones yaml",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "zones",
+				args:    []string{"-output=yaml"},
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				output: "- id: 1\n  mode: primary\n  name: alpha.com\n  recordCount: 3\n  status: ok\n  ttl: 3600\n",
+			},
+		},
+		{
+			name: "records of a zone",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "records",
+				args:    []string{"alpha.com"},
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				output: "NAME           TYPE  TTL  TARGETS              PROVIDER SPECIFIC\n" +
+					"www.alpha.com  A     300  127.0.0.1,127.0.0.2  webhook/hetzner-label-env=prod\n",
+				zone: "alpha.com",
+			},
+		},
+		{
+			name: "records yaml",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "records",
+				args:    []string{"--output", "yaml"},
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				output: "- dnsName: www.alpha.com\n" +
+					"  providerSpecific:\n" +
+					"  - name: webhook/hetzner-label-env\n" +
+					"    value: prod\n" +
+					"  recordTTL: 300\n" +
+					"  recordType: A\n" +
+					"  targets:\n" +
+					"  - 127.0.0.1\n" +
+					"  - 127.0.0.2\n",
+			},
+		},
+		{
+			name: "unsupported format",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "zones",
+				args:    []string{"--output", "xml"},
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				err: "unsupported output format xml",
+			},
+		},
+		{
+			name: "too many arguments",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "records",
+				args:    []string{"alpha.com", "beta.com"},
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				err: "usage: webhook records [--output table|json|yaml] [<zone>]",
+			},
+		},
+		{
+			name: "unknown flag",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "export",
+				args:    []string{"--output", "json", "alpha.com"},
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				err: "usage: webhook export <zone> [<file>]",
+			},
+		},
+		{
+			name: "unknown command",
+			input: struct {
+				command string
+				args    []string
+			}{
+				command: "delete",
+			},
+			expected: struct {
+				output string
+				zone   string
+				err    string
+			}{
+				err: "unknown command delete",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_runCommand_zonefile tests the export and import commands.
+func Test_runCommand_zonefile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "alpha.com.zone")
+	p := &mockZoneProvider{zonefile: "$ORIGIN alpha.com.\n"}
+	mockNewZoneProvider(t, p)
+
+	var out bytes.Buffer
+	assert.Nil(t, runCommand("export", []string{"alpha.com"}, nil, &out))
+	assert.Equal(t, "$ORIGIN alpha.com.\n", out.String())
+
+	assert.Nil(t, runCommand("export", []string{"alpha.com", path}, nil, &out))
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "$ORIGIN alpha.com.\n", string(data))
+
+	assert.Nil(t, runCommand("import", []string{"--dry-run", "beta.com", path}, nil, &out))
+	assert.Equal(t, "beta.com", p.zone)
+	assert.Equal(t, "$ORIGIN alpha.com.\n", p.imported)
+	assert.True(t, p.config.DryRun)
+
+	assert.Nil(t, runCommand("import", []string{"beta.com", path}, nil, &out))
+	assert.False(t, p.config.DryRun)
+
+	err = runCommand("import", []string{"beta.com", filepath.Join(dir, "missing.zone")}, nil, &out)
+	assert.NotNil(t, err)
+
+	p.err = errors.New("test export error")
+	assert.EqualError(t, runCommand("export", []string{"alpha.com"}, nil, &out), "test export error")
+}
+
+// Test_runCommand_restore tests the restore command.
+func Test_runCommand_restore(t *testing.T) {
+	p := &mockZoneProvider{}
+	mockNewZoneProvider(t, p)
+
+	var out bytes.Buffer
+	assert.Nil(t, runCommand("restore", []string{"alpha.com"}, nil, &out))
+	assert.Equal(t, "alpha.com", p.zone)
+	assert.Equal(t, "", p.backup)
+	assert.False(t, p.config.DryRun)
+
+	assert.Nil(t, runCommand("restore", []string{"--dry-run", "beta.com", "20260101T120000.000000000Z.zone"}, nil, &out))
+	assert.Equal(t, "beta.com", p.zone)
+	assert.Equal(t, "20260101T120000.000000000Z.zone", p.backup)
+	assert.True(t, p.config.DryRun)
+
+	usage := "usage: webhook restore [--dry-run] <zone> [<backup>]"
+	assert.EqualError(t, runCommand("restore", nil, nil, &out), usage)
+	assert.EqualError(t, runCommand("restore", []string{"a", "b", "c"}, nil, &out), usage)
+
+	p.err = errors.New("test restore error")
+	assert.EqualError(t, runCommand("restore", []string{"alpha.com"}, nil, &out), "test restore error")
+}
+
+// Test_runCommand_apply tests the apply command.
+func Test_runCommand_apply(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "changes.json")
+	changes := `{
+  "create": [{"dnsName": "www.alpha.com", "recordType": "A", "targets": ["127.0.0.1"]}],
+  "delete": [{"dnsName": "old.alpha.com", "recordType": "A", "targets": ["127.0.0.2"]}]
+}`
+	assert.Nil(t, os.WriteFile(path, []byte(changes), 0o600))
+	invalid := filepath.Join(dir, "invalid.json")
+	assert.Nil(t, os.WriteFile(invalid, []byte(`{"creates": []}`), 0o600))
+	p := &mockZoneProvider{}
+	mockNewZoneProvider(t, p)
+
+	var out bytes.Buffer
+	assert.Nil(t, runCommand("apply", []string{"--dry-run", path}, nil, &out))
+	assert.True(t, p.config.DryRun)
+	assert.Equal(t, "Processed 1 creates, 0 updates and 1 deletes.\n", out.String())
+	assert.Equal(t, "www.alpha.com", p.changes.Create[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"127.0.0.2"}, p.changes.Delete[0].Targets)
+
+	err := runCommand("apply", []string{invalid}, nil, &out)
+	assert.ErrorContains(t, err, "cannot read changes from "+invalid)
+
+	p.err = errors.New("test apply error")
+	assert.EqualError(t, runCommand("apply", []string{path}, nil, &out), "test apply error")
+}
+
+// Test_runCommand_invalidConfiguration tests that the configuration is
+// validated before running a command.
+func Test_runCommand_invalidConfiguration(t *testing.T) {
+	p := &mockZoneProvider{}
+	mockNewZoneProvider(t, p)
+	err := runCommand("zones", nil, configfile.Values{"BATCH_SIZE": "500"}, &bytes.Buffer{})
+	assert.EqualError(t, err, "BATCH_SIZE must be between 1 and 100, got 500")
+	assert.Nil(t, p.config)
+}
+
+// Test_runCommand_reconcile tests the reconcile command.
+func Test_runCommand_reconcile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "records.yaml")
+	records := "- dnsName: www.alpha.com\n  recordType: A\n  targets:\n  - 127.0.0.1\n"
+	assert.Nil(t, os.WriteFile(path, []byte(records), 0o600))
+	invalid := filepath.Join(dir, "invalid.yaml")
+	assert.Nil(t, os.WriteFile(invalid, []byte("- name: www.alpha.com\n"), 0o600))
+
+	create := endpoint.NewEndpoint("www.alpha.com", "A", "127.0.0.1")
+	conflict := endpoint.NewEndpoint("api.alpha.com", "A", "127.0.0.2")
+	drift := &hetznercloud.ReconcilePlan{Changes: &plan.Changes{Create: []*endpoint.Endpoint{create}}}
+	conflicts := &hetznercloud.ReconcilePlan{Changes: &plan.Changes{}, Conflicts: []*endpoint.Endpoint{conflict}}
+	noDrift := &hetznercloud.ReconcilePlan{Changes: &plan.Changes{}}
+
+	type testCase struct {
+		name  string
+		input struct {
+			args []string
+			plan *hetznercloud.ReconcilePlan
+		}
+		expected struct {
+			output  string
+			owner   string
+			applied bool
+			err     string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		p := &mockZoneProvider{plan: inp.plan}
+		mockNewZoneProvider(t, p)
+		var out bytes.Buffer
+		err := runCommand("reconcile", inp.args, nil, &out)
+		if exp.err != "" {
+			assert.ErrorContains(t, err, exp.err)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, exp.output, out.String())
+		assert.Equal(t, exp.owner, p.owner)
+		assert.Equal(t, exp.applied, p.changes != nil)
+		if exp.owner != "" {
+			assert.Len(t, p.desired, 1)
+			assert.Equal(t, "www.alpha.com", p.desired[0].DNSName)
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name: "plan with drift",
+			input: struct {
+				args []string
+				plan *hetznercloud.ReconcilePlan
+			}{
+				args: []string{"--plan", "--owner", "ci", path},
+				plan: drift,
+			},
+			expected: struct {
+				output  string
+				owner   string
+				applied bool
+				err     string
+			}{
+				output: "ACTION  NAME           TYPE  TTL  TARGETS\n" +
+					"create  www.alpha.com  A     0    127.0.0.1\n",
+				owner: "ci",
+				err:   "drift detected",
+			},
+		},
+		{
+			name: "plan without drift",
+			input: struct {
+				args []string
+				plan *hetznercloud.ReconcilePlan
+			}{
+				args: []string{"--plan", "--output", "json", path},
+				plan: noDrift,
+			},
+			expected: struct {
+				output  string
+				owner   string
+				applied bool
+				err     string
+			}{
+				output: "{\n  \"changes\": {}\n}\n",
+				owner:  "reconcile",
+			},
+		},
+		{
+			name: "apply",
+			input: struct {
+				args []string
+				plan *hetznercloud.ReconcilePlan
+			}{
+				args: []string{"--output", "yaml", path},
+				plan: drift,
+			},
+			expected: struct {
+				output  string
+				owner   string
+				applied bool
+				err     string
+			}{
+				output: "changes:\n  create:\n  - dnsName: www.alpha.com\n    recordType: A\n" +
+					"    targets:\n    - 127.0.0.1\n",
+				owner:   "reconcile",
+				applied: true,
+			},
+		},
+		{
+			name: "apply with conflicts",
+			input: struct {
+				args []string
+				plan *hetznercloud.ReconcilePlan
+			}{
+				args: []string{path},
+				plan: conflicts,
+			},
+			expected: struct {
+				output  string
+				owner   string
+				applied bool
+				err     string
+			}{
+				output: "ACTION    NAME           TYPE  TTL  TARGETS\n" +
+					"conflict  api.alpha.com  A     0    127.0.0.2\n",
+				owner:   "reconcile",
+				applied: true,
+				err:     "drift detected: 1 records not owned by reconcile",
+			},
+		},
+		{
+			name: "invalid file",
+			input: struct {
+				args []string
+				plan *hetznercloud.ReconcilePlan
+			}{
+				args: []string{invalid},
+			},
+			expected: struct {
+				output  string
+				owner   string
+				applied bool
+				err     string
+			}{
+				err: "cannot read records from " + invalid,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
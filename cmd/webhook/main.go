@@ -18,17 +18,24 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
+	"external-dns-hetzner-webhook/internal/configfile"
 	"external-dns-hetzner-webhook/internal/hetzner"
 	hetznercloud "external-dns-hetzner-webhook/internal/hetzner/cloud"
+	"external-dns-hetzner-webhook/internal/logging"
+	"external-dns-hetzner-webhook/internal/metrics"
 	"external-dns-hetzner-webhook/internal/server"
+	"external-dns-hetzner-webhook/internal/tracing"
 
 	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/external-dns/provider"
-	"sigs.k8s.io/external-dns/provider/webhook/api"
 )
 
 var (
@@ -43,76 +50,305 @@ var notify = func(sig chan os.Signal) {
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 }
 
+// notifyDebug requires the debug signals to be sent to the caller.
+var notifyDebug = func(sig chan os.Signal) {
+	if len(debugSignals) > 0 {
+		signal.Notify(sig, debugSignals...)
+	}
+}
+
+// configureLogging reads the logging options and configures the loggers.
+func configureLogging(values configfile.Values) error {
+	options, err := readOptions(logging.NewOptions, values)
+	if err != nil {
+		return err
+	}
+	return logging.Configure(*options)
+}
+
+// setupTracing reads the tracing options and configures the tracer provider.
+// It returns the function that flushes the pending spans.
+func setupTracing(values configfile.Values) (func(context.Context) error, error) {
+	options, err := readOptions(tracing.NewOptions, values)
+	if err != nil {
+		return nil, err
+	}
+	if options.Exporter != tracing.ExporterNone {
+		log.Infof("Tracing enabled with the %s exporter.", options.Exporter)
+	}
+	return tracing.Setup(context.Background(), *options, Version)
+}
+
+// createMetrics reads the metrics options and creates the metrics instance
+// shared by the sockets and the provider.
+func createMetrics(values configfile.Values) (*metrics.OpenMetrics, error) {
+	options, err := readOptions(metrics.NewOptions, values)
+	if err != nil {
+		return nil, err
+	}
+	return metrics.NewOpenMetrics(*options)
+}
+
+// toggleDebugOnSignal switches the debug logs on and off every time a debug
+// signal is received, until the context is cancelled.
+func toggleDebugOnSignal(ctx context.Context) {
+	sig := make(chan os.Signal, 1)
+	notifyDebug(sig)
+	defer signal.Stop(sig)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-sig:
+			if logging.ToggleDebug() {
+				log.Infof("Signal %s received: debug logs enabled.", s.String())
+			} else {
+				log.Infof("Signal %s received: configured log levels restored.", s.String())
+			}
+		}
+	}
+}
+
 // healthStatus is the interface used by loop.
 type healthStatus interface {
 	SetHealthy(bool)
 	SetReady(bool)
 }
 
-// waitForSignal waits for a SIGTERM or a SIGINT and then shuts down the server.
-func waitForSignal(status healthStatus) {
+// waitForShutdown waits for a SIGTERM or a SIGINT, for the provider to report
+// a degraded state or for a socket to fail, stops the background checks and
+// then removes the health and readiness flags, so that no check can set them
+// again. It returns the reason of the failure, or nil if a signal was
+// received.
+func waitForShutdown(status healthStatus, degraded <-chan error, failed <-chan error, stopChecks func()) error {
 	exitSignal := make(chan os.Signal, 1)
 	notify(exitSignal)
-	signal := <-exitSignal
-
-	log.Infof("Signal %s received. Shutting down the webhook.", signal.String())
+	var reason error
+	select {
+	case signal := <-exitSignal:
+		log.Infof("Signal %s received. Shutting down the webhook.", signal.String())
+	case reason = <-degraded:
+		log.Errorf("Provider degraded: %v. Shutting down the webhook.", reason)
+	case reason = <-failed:
+		log.Errorf("Socket failure: %v. Shutting down the webhook.", reason)
+	}
+	stopChecks()
 	status.SetHealthy(false)
 	status.SetReady(false)
+	return reason
+}
+
+// apiKeyWatcher is the interface of the providers that watch the API key
+// file.
+type apiKeyWatcher interface {
+	WatchAPIKey(ctx context.Context)
+}
+
+// driftWatcher is the interface of the providers that check the applied
+// records for drift.
+type driftWatcher interface {
+	WatchDrift(ctx context.Context)
 }
 
-// createProvider creates a provider.
-func createProvider(config *hetzner.Configuration) (provider.Provider, error) {
-	return hetznercloud.NewHetznerProvider(config)
+// startable is the interface of the sockets that can be started.
+type startable interface {
+	Start(startedChan chan struct{}, options server.SocketOptions) error
+}
+
+// startSocket starts a socket in background. If the socket fails, the error is
+// sent to the failed channel.
+func startSocket(s startable, startedChan chan struct{}, options server.SocketOptions, failed chan<- error) {
+	go func() {
+		if err := s.Start(startedChan, options); err != nil {
+			failed <- err
+		}
+	}()
+}
+
+// shutdown runs the shutdown steps in order, within the grace period. The
+// steps share the same deadline.
+func shutdown(gracePeriod time.Duration, steps ...func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	errs := make([]error, 0)
+	for _, step := range steps {
+		if err := step(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// exitCode returns 0 if the webhook was shut down cleanly after a signal and
+// 1 otherwise.
+func exitCode(reason error, err error) int {
+	if reason != nil || err != nil {
+		return 1
+	}
+	return 0
+}
+
+// effectiveConfig is the configuration shown by the admin API.
+type effectiveConfig struct {
+	Provider hetzner.Configuration `json:"provider"`
+	Sockets  server.SocketOptions  `json:"sockets"`
+}
+
+// createProvider creates a provider. It is mockable.
+var createProvider = func(config *hetzner.Configuration, m *metrics.OpenMetrics) (provider.Provider, error) {
+	return hetznercloud.NewHetznerProvider(config, m)
 }
 
 // main reads the server configuration and starts both the webhook and the
 // metrics socket.
 func main() {
+	fileOptions, values, content, err := loadConfigFile()
+	if err != nil {
+		log.Fatal("Cannot read configuration file:", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "--check" {
+		if err := check(os.Args[2:], values, os.Stdout); err != nil {
+			log.Fatal("Configuration check failed: ", err)
+		}
+		return
+	}
+	if err := configureLogging(values); err != nil {
+		log.Fatal("Cannot configure logging:", err)
+	}
+	if len(os.Args) > 1 {
+		if _, found := commands[os.Args[1]]; found {
+			err := runCommand(os.Args[1], os.Args[2:], values, os.Stdout)
+			if errors.Is(err, errDrift) {
+				log.Warn(err)
+				os.Exit(2)
+			} else if err != nil {
+				log.Fatalf("Command %s failed: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
+
 	log.Infof("Starting Hetzner webhook version %s (commit %s)", Version, Gitsha)
+	if fileOptions.File != "" {
+		log.Infof("Configuration file %s loaded.", fileOptions.File)
+		warnUnknownSettings(values)
+	}
+	shutdownTracing, err := setupTracing(values)
+	if err != nil {
+		log.Fatal("Cannot configure tracing:", err)
+	}
 	// Read server options
-	socketOptions, err := server.NewSocketOptions()
+	socketOptions, err := readOptions(server.NewSocketOptions, values)
 	if err != nil {
 		log.Fatal("Cannot read configuration from environment:", err.Error())
 		log.Exit(1)
 	}
+	if err := socketOptions.Validate(); err != nil {
+		log.Fatal("Invalid socket configuration: ", err)
+	}
+	m, err := createMetrics(values)
+	if err != nil {
+		log.Fatal("Cannot configure metrics:", err)
+	}
 
 	// Start health server
 	log.Infof("Starting metrics server with socket address %s", socketOptions.GetMetricsAddress())
 	serverStatus := server.Status{}
 	serverStatus.SetHealthy(true)
-	metricsSocket := server.NewMetricsSocket(&serverStatus)
-	go metricsSocket.Start(nil, *socketOptions)
+	failed := make(chan error, 2)
+	metricsSocket := server.NewMetricsSocket(&serverStatus, m)
+	startSocket(metricsSocket, nil, *socketOptions, failed)
 
 	// Read provider configuration
-	config, err := hetzner.NewConfiguration()
+	config, err := readOptions(hetzner.NewConfiguration, values)
 	if err != nil {
 		serverStatus.SetHealthy(false)
 		log.Fatal("Provider configuration unreadable - shutting down:", err)
 		log.Exit(1)
 	}
+	if err := config.Validate(); err != nil {
+		serverStatus.SetHealthy(false)
+		log.Fatal("Invalid provider configuration - shutting down: ", err)
+	}
 
 	// instantiate the Hetzner provider
-	provider, err := createProvider(config)
+	provider, err := createProvider(config, m)
 	if err != nil {
 		serverStatus.SetHealthy(false)
 		log.Fatal("Provider cannot be instantiated - shutting down:", err)
 		panic(err)
 	}
 
+	inspector, _ := provider.(server.Inspector)
+	publish := func(c hetzner.Configuration) {
+		if inspector != nil {
+			metricsSocket.SetInspector(inspector, effectiveConfig{
+				Provider: c.Redacted(),
+				Sockets:  *socketOptions,
+			})
+		}
+	}
+	publish(*config)
+
 	// Start the webhook
 	log.Infof("Starting webhook server with socket address %s", socketOptions.GetWebhookAddress())
 	startedChan := make(chan struct{})
-	go api.StartHTTPApi(
-		provider, startedChan,
-		socketOptions.GetReadTimeout(),
-		socketOptions.GetWriteTimeout(),
-		socketOptions.GetWebhookAddress(),
-	)
+	webhookSocket := server.NewWebhookSocket(provider, m)
+	startSocket(webhookSocket, startedChan, *socketOptions, failed)
 
 	// Wait for the HTTP server to start and then set the healthy and ready flags
-	<-startedChan
-	serverStatus.SetReady(true)
+	select {
+	case <-startedChan:
+		serverStatus.SetReady(true)
+	case err := <-failed:
+		serverStatus.SetHealthy(false)
+		log.Fatal("Server cannot be started - shutting down:", err)
+	}
+
+	// Start the Hetzner API health checks, the debug signal handler and the
+	// watchers of the configuration and API key files
+	checkCtx, stopChecks := context.WithCancel(context.Background())
+	go toggleDebugOnSignal(checkCtx)
+	if fileOptions.File != "" && fileOptions.ReloadInterval > 0 {
+		r := &reloader{applied: values, config: *config, metrics: m, publish: publish}
+		r.provider, _ = provider.(reconfigurable)
+		watcher := configfile.NewWatcher(fileOptions.File, content)
+		go watcher.Watch(checkCtx, fileOptions.GetReloadInterval(), r.reload)
+	}
+	if w, ok := provider.(apiKeyWatcher); ok {
+		go w.WatchAPIKey(checkCtx)
+	}
+	if w, ok := provider.(driftWatcher); ok {
+		go w.WatchDrift(checkCtx)
+	}
+	var checks sync.WaitGroup
+	if pinger, ok := provider.(server.Pinger); ok {
+		checker := server.NewHealthChecker(pinger, &serverStatus, *socketOptions, m)
+		checks.Go(func() { checker.Run(checkCtx) })
+	}
+
+	// Wait until a signal, a degraded state or a failure tells us to exit
+	var degraded <-chan error
+	if d, ok := provider.(server.Degradable); ok {
+		degraded = d.Degraded()
+	}
+	reason := waitForShutdown(&serverStatus, degraded, failed, func() {
+		stopChecks()
+		checks.Wait()
+	})
 
-	// Wait until a signal tells us to exit
-	waitForSignal(&serverStatus)
+	// Stop accepting requests, wait for the running changes, stop the metrics
+	// socket and flush the pending spans.
+	steps := []func(context.Context) error{webhookSocket.Shutdown}
+	if d, ok := provider.(server.Drainable); ok {
+		steps = append(steps, d.Drain)
+	}
+	steps = append(steps, metricsSocket.Shutdown, shutdownTracing)
+	err = shutdown(socketOptions.GetShutdownTimeout(), steps...)
+	if err != nil {
+		log.Errorf("Error while shutting down: %v", err)
+	}
+	code := exitCode(reason, err)
+	log.Infof("Webhook shut down with exit code %d.", code)
+	os.Exit(code)
 }
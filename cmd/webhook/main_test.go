@@ -18,13 +18,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
 	"syscall"
 	"testing"
 	"time"
 
+	"external-dns-hetzner-webhook/internal/configfile"
 	"external-dns-hetzner-webhook/internal/hetzner"
 	hetznercloud "external-dns-hetzner-webhook/internal/hetzner/cloud"
+	"external-dns-hetzner-webhook/internal/logging"
+	"external-dns-hetzner-webhook/internal/server"
 
 	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/external-dns/provider"
@@ -55,7 +60,7 @@ func Test_createProvider(t *testing.T) {
 	}
 
 	run := func(t *testing.T, tc testCase) {
-		actual, _ := createProvider(tc.config)
+		actual, _ := createProvider(tc.config, nil)
 		assert.IsType(t, tc.expectedType, actual)
 	}
 
@@ -74,26 +79,173 @@ func Test_createProvider(t *testing.T) {
 	}
 }
 
-// Test_waitForSignal tests waitForSignal().
-func Test_waitForSignal(t *testing.T) {
-	name := "wait for signal test"
-	actual := mockStatus{
-		ready:   true,
-		healthy: true,
+// mockSocket simulates a socket.
+type mockSocket struct {
+	err    error
+	called bool
+}
+
+// Start returns the configured error.
+func (s *mockSocket) Start(startedChan chan struct{}, options server.SocketOptions) error {
+	return s.err
+}
+
+// Shutdown records the call and returns the configured error.
+func (s *mockSocket) Shutdown(ctx context.Context) error {
+	s.called = true
+	return s.err
+}
+
+// Test_waitForShutdown tests waitForShutdown().
+func Test_waitForShutdown(t *testing.T) {
+	type testCase struct {
+		name     string
+		signal   bool
+		degraded error
+		failed   error
+		expected error
 	}
-	expected := mockStatus{}
+
 	bkpNotify := notify
-	notify = func(sig chan os.Signal) {
-		go func() {
-			time.Sleep(time.Second)
-			sig <- syscall.SIGTERM
-		}()
+	defer func() { notify = bkpNotify }()
+
+	run := func(t *testing.T, tc testCase) {
+		actual := mockStatus{
+			ready:   true,
+			healthy: true,
+		}
+		notify = func(sig chan os.Signal) {
+			if tc.signal {
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					sig <- syscall.SIGTERM
+				}()
+			}
+		}
+		degraded := make(chan error, 1)
+		if tc.degraded != nil {
+			degraded <- tc.degraded
+		}
+		failed := make(chan error, 1)
+		if tc.failed != nil {
+			failed <- tc.failed
+		}
+		stopped := false
+		stopChecks := func() {
+			// The checks are stopped before the flags are removed.
+			assert.Equal(t, mockStatus{ready: true, healthy: true}, actual)
+			stopped = true
+		}
+		reason := waitForShutdown(&actual, degraded, failed, stopChecks)
+		assert.Equal(t, tc.expected, reason)
+		assert.True(t, stopped)
+		assert.Equal(t, mockStatus{}, actual)
+	}
+
+	testCases := []testCase{
+		{
+			name:   "signal received",
+			signal: true,
+		},
+		{
+			name:     "provider degraded",
+			degraded: errors.New("failure count reached 3"),
+			expected: errors.New("failure count reached 3"),
+		},
+		{
+			name:     "socket failed",
+			failed:   errors.New("cannot listen on localhost:8888"),
+			expected: errors.New("cannot listen on localhost:8888"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_startSocket tests startSocket().
+func Test_startSocket(t *testing.T) {
+	failed := make(chan error, 1)
+	startSocket(&mockSocket{err: errors.New("test start error")}, nil, server.SocketOptions{}, failed)
+	assert.EqualError(t, <-failed, "test start error")
+}
+
+// Test_shutdown tests shutdown().
+func Test_shutdown(t *testing.T) {
+	first := &mockSocket{err: errors.New("test shutdown error")}
+	second := &mockSocket{}
+	drained := false
+	drain := func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		drained = ok
+		return nil
 	}
 
-	t.Run(name, func(t *testing.T) {
-		waitForSignal(&actual)
-		assert.Equal(t, expected, actual)
-	})
+	err := shutdown(time.Second, first.Shutdown, drain, second.Shutdown)
+
+	assert.EqualError(t, err, "test shutdown error")
+	assert.True(t, first.called)
+	assert.True(t, drained)
+	assert.True(t, second.called)
+}
+
+// Test_exitCode tests exitCode().
+func Test_exitCode(t *testing.T) {
+	assert.Equal(t, 0, exitCode(nil, nil))
+	assert.Equal(t, 1, exitCode(errors.New("failure count reached 3"), nil))
+	assert.Equal(t, 1, exitCode(nil, context.DeadlineExceeded))
+}
+
+// Test_createMetrics tests createMetrics().
+func Test_createMetrics(t *testing.T) {
+	t.Setenv("METRICS_NAMESPACE", "hetzner-webhook")
+	m, err := createMetrics(nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, m)
+	t.Setenv("METRICS_NAMESPACE", "hetzner_webhook")
+	m, err = createMetrics(nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, m)
+}
+
+// Test_configureLogging tests configureLogging().
+func Test_configureLogging(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "xml")
+	assert.NotNil(t, configureLogging(nil))
+	t.Setenv("LOG_FORMAT", "text")
+	assert.Nil(t, configureLogging(nil))
+	assert.NotNil(t, configureLogging(configfile.Values{"LOG_LEVEL": "loud"}))
+	assert.Nil(t, configureLogging(configfile.Values{"LOG_LEVEL": "info"}))
+}
+
+// Test_toggleDebugOnSignal tests toggleDebugOnSignal().
+func Test_toggleDebugOnSignal(t *testing.T) {
+	bkpNotifyDebug := notifyDebug
+	defer func() { notifyDebug = bkpNotifyDebug }()
+	sigChan := make(chan chan os.Signal, 1)
+	notifyDebug = func(sig chan os.Signal) {
+		sigChan <- sig
+	}
 
-	notify = bkpNotify
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		toggleDebugOnSignal(ctx)
+		close(done)
+	}()
+	// Any signal delivered to the channel toggles the debug logs.
+	sig := <-sigChan
+	sig <- os.Interrupt
+	assert.Eventually(t, func() bool {
+		return logging.Levels()["default"] == "debug"
+	}, time.Second, 10*time.Millisecond)
+	sig <- os.Interrupt
+	assert.Eventually(t, func() bool {
+		return logging.Levels()["default"] == "info"
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
 }
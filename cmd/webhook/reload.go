@@ -0,0 +1,210 @@
+/*
+ * Reload - configuration file loading and runtime reload.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/configfile"
+	"external-dns-hetzner-webhook/internal/hetzner"
+	"external-dns-hetzner-webhook/internal/logging"
+	"external-dns-hetzner-webhook/internal/metrics"
+	"external-dns-hetzner-webhook/internal/server"
+	"external-dns-hetzner-webhook/internal/tracing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reloadableSettings contains the provider settings that are applied without
+// restarting the webhook. All the logging settings are reloadable too.
+var reloadableSettings = []string{
+	"DOMAIN_FILTER",
+	"EXCLUDE_DOMAIN_FILTER",
+	"REGEXP_DOMAIN_FILTER",
+	"REGEXP_DOMAIN_FILTER_EXCLUSION",
+	"ZONE_CACHE_TTL",
+	"DRY_RUN",
+	"BATCH_SIZE",
+	"HETZNER_DEBUG",
+}
+
+// isReloadable returns true if a setting is applied without restarting the
+// webhook.
+func isReloadable(name string) bool {
+	for _, setting := range configfile.Names(logging.Options{}) {
+		if name == setting {
+			return true
+		}
+	}
+	for _, setting := range reloadableSettings {
+		if name == setting {
+			return true
+		}
+	}
+	return false
+}
+
+// readOptions reads a set of options from the environment and overrides them
+// with the values of the configuration file.
+func readOptions[T any](newOptions func() (*T, error), values configfile.Values) (*T, error) {
+	options, err := newOptions()
+	if err != nil {
+		return nil, err
+	}
+	if err := configfile.Apply(options, values); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// loadConfigFile reads the configuration file options and, if a file is set,
+// its values and the content they were parsed from.
+func loadConfigFile() (*configfile.Options, configfile.Values, []byte, error) {
+	options, err := configfile.NewOptions()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if options.File == "" {
+		return options, nil, nil, nil
+	}
+	content, err := os.ReadFile(options.File)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	values, err := configfile.Parse(content)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return options, values, content, nil
+}
+
+// warnUnknownSettings logs the values of the configuration file that do not
+// match any setting.
+func warnUnknownSettings(values configfile.Values) {
+	unknown := configfile.Unknown(values,
+		logging.Options{},
+		tracing.Options{},
+		metrics.Options{},
+		server.SocketOptions{},
+		hetzner.Configuration{})
+	if len(unknown) > 0 {
+		log.Warnf("Unknown settings in the configuration file: %s.", strings.Join(unknown, ", "))
+	}
+}
+
+// changedSettings returns the sorted names of the values that were added,
+// removed or modified.
+func changedSettings(previous, current configfile.Values) []string {
+	changed := make([]string, 0)
+	for name, value := range current {
+		if old, found := previous[name]; !found || old != value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range previous {
+		if _, found := current[name]; !found {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// reconfigurable is the interface of the providers that can change their
+// settings at runtime.
+type reconfigurable interface {
+	Reconfigure(config *hetzner.Configuration) error
+}
+
+// reloader applies the changes of the configuration file. The values last
+// applied are kept to report the settings that require a restart.
+type reloader struct {
+	applied  configfile.Values
+	config   hetzner.Configuration
+	provider reconfigurable
+	metrics  *metrics.OpenMetrics
+	publish  func(hetzner.Configuration)
+}
+
+// reload is called by the watcher with the new values of the configuration
+// file or with the error that prevented reading them.
+func (r *reloader) reload(values configfile.Values, err error) {
+	if err == nil {
+		err = r.apply(values)
+	}
+	if err != nil {
+		log.Errorf("Configuration file not reloaded: %v", err)
+		r.metrics.IncConfigReloadsTotal("failure", time.Now())
+		return
+	}
+	log.Info("Configuration file reloaded.")
+	r.metrics.IncConfigReloadsTotal("success", time.Now())
+}
+
+// apply validates the new values and applies the reloadable settings. Nothing
+// is applied if a value is not valid. The settings that require a restart are
+// reported once, when they change.
+func (r *reloader) apply(values configfile.Values) error {
+	loggingOptions, err := readOptions(logging.NewOptions, values)
+	if err != nil {
+		return err
+	}
+	if err := loggingOptions.Validate(); err != nil {
+		return err
+	}
+	config, err := readOptions(hetzner.NewConfiguration, values)
+	if err != nil {
+		return err
+	}
+	if r.provider != nil {
+		if err := r.provider.Reconfigure(config); err != nil {
+			return err
+		}
+	}
+	if err := logging.Configure(*loggingOptions); err != nil {
+		return err
+	}
+
+	warnUnknownSettings(values)
+	restart := make([]string, 0)
+	for _, name := range changedSettings(r.applied, values) {
+		if !isReloadable(name) {
+			restart = append(restart, name)
+		}
+	}
+	if len(restart) > 0 {
+		log.Warnf("Settings changed that require a restart: %s.", strings.Join(restart, ", "))
+	}
+
+	r.config.DomainFilter = config.DomainFilter
+	r.config.ExcludeDomains = config.ExcludeDomains
+	r.config.RegexDomainFilter = config.RegexDomainFilter
+	r.config.RegexDomainExclusion = config.RegexDomainExclusion
+	r.config.ZoneCacheTTL = config.ZoneCacheTTL
+	r.config.DryRun = config.DryRun
+	r.config.BatchSize = config.BatchSize
+	r.config.Debug = config.Debug
+	r.applied = values
+	if r.publish != nil {
+		r.publish(r.config)
+	}
+	return nil
+}
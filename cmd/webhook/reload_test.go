@@ -0,0 +1,266 @@
+/*
+ * Reload - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"external-dns-hetzner-webhook/internal/configfile"
+	"external-dns-hetzner-webhook/internal/hetzner"
+	"external-dns-hetzner-webhook/internal/logging"
+	"external-dns-hetzner-webhook/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockReconfigurable records the configurations passed to Reconfigure.
+type mockReconfigurable struct {
+	configs []*hetzner.Configuration
+	err     error
+}
+
+// Reconfigure records the configuration or returns the mock error.
+func (p *mockReconfigurable) Reconfigure(config *hetzner.Configuration) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.configs = append(p.configs, config)
+	return nil
+}
+
+// reloadsTotal returns the value of config_reloads_total for a result.
+func reloadsTotal(t *testing.T, m *metrics.OpenMetrics, result string) float64 {
+	families, err := m.GetRegistry().Gather()
+	assert.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() != "config_reloads_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "result" && label.GetValue() == result {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func Test_isReloadable(t *testing.T) {
+	assert.True(t, isReloadable("DOMAIN_FILTER"))
+	assert.True(t, isReloadable("LOG_LEVEL_API"))
+	assert.True(t, isReloadable("LOG_FORMAT"))
+	assert.False(t, isReloadable("BULK_MODE"))
+	assert.False(t, isReloadable("WEBHOOK_PORT"))
+}
+
+func Test_readOptions(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_FORMAT", "json")
+	options, err := readOptions(logging.NewOptions, configfile.Values{"LOG_LEVEL": "debug"})
+	assert.Nil(t, err)
+	assert.Equal(t, "debug", options.Level)
+	assert.Equal(t, "json", options.Format)
+
+	_, err = readOptions(logging.NewOptions, configfile.Values{"HETZNER_DEBUG": "maybe"})
+	assert.NotNil(t, err)
+}
+
+func Test_loadConfigFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	options, values, content, err := loadConfigFile()
+	assert.Nil(t, err)
+	assert.Equal(t, "", options.File)
+	assert.Nil(t, values)
+	assert.Nil(t, content)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: true\n"), 0600))
+	t.Setenv("CONFIG_FILE", path)
+	options, values, content, err = loadConfigFile()
+	assert.Nil(t, err)
+	assert.Equal(t, path, options.File)
+	assert.Equal(t, configfile.Values{"DRY_RUN": "true"}, values)
+	assert.Equal(t, []byte("DRY_RUN: true\n"), content)
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	_, _, _, err = loadConfigFile()
+	assert.NotNil(t, err)
+}
+
+func Test_changedSettings(t *testing.T) {
+	previous := configfile.Values{"A": "1", "B": "2", "C": "3"}
+	current := configfile.Values{"A": "1", "B": "5", "D": "4"}
+	assert.Equal(t, []string{"B", "C", "D"}, changedSettings(previous, current))
+	assert.Equal(t, []string{}, changedSettings(previous, previous))
+}
+
+func Test_reloader_reload(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			values configfile.Values
+			err    error
+			reject error
+		}
+		expected struct {
+			result    string
+			published bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		t.Setenv("HETZNER_API_KEY", "TEST_API_KEY")
+		t.Cleanup(func() {
+			assert.Nil(t, logging.Configure(logging.Options{Format: "text"}))
+		})
+		inp := tc.input
+		exp := tc.expected
+		m, err := metrics.NewOpenMetrics(metrics.DefaultOptions())
+		assert.Nil(t, err)
+		provider := &mockReconfigurable{err: inp.reject}
+		var published *hetzner.Configuration
+		r := &reloader{
+			applied:  configfile.Values{"BULK_MODE": "false"},
+			config:   hetzner.Configuration{APIKey: "TEST_API_KEY", BatchSize: 100},
+			provider: provider,
+			metrics:  m,
+			publish: func(c hetzner.Configuration) {
+				published = &c
+			},
+		}
+		r.reload(inp.values, inp.err)
+		assert.Equal(t, float64(1), reloadsTotal(t, m, exp.result))
+		if !exp.published {
+			assert.Nil(t, published)
+			assert.Empty(t, provider.configs)
+			assert.Equal(t, configfile.Values{"BULK_MODE": "false"}, r.applied)
+			return
+		}
+		assert.Len(t, provider.configs, 1)
+		// The applied values become the baseline of the next reload.
+		assert.Equal(t, inp.values, r.applied)
+		assert.Equal(t, hetzner.Configuration{
+			APIKey:       "TEST_API_KEY",
+			BatchSize:    50,
+			DryRun:       true,
+			DomainFilter: []string{"alpha.com"},
+		}, *published)
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			input: struct {
+				values configfile.Values
+				err    error
+				reject error
+			}{
+				values: configfile.Values{
+					"BATCH_SIZE":    "50",
+					"DRY_RUN":       "true",
+					"DOMAIN_FILTER": "alpha.com",
+					"LOG_LEVEL":     "warn",
+					"BULK_MODE":     "true",
+				},
+			},
+			expected: struct {
+				result    string
+				published bool
+			}{
+				result:    "success",
+				published: true,
+			},
+		},
+		{
+			name: "unreadable file",
+			input: struct {
+				values configfile.Values
+				err    error
+				reject error
+			}{
+				err: errors.New("cannot parse configuration file"),
+			},
+			expected: struct {
+				result    string
+				published bool
+			}{
+				result: "failure",
+			},
+		},
+		{
+			name: "invalid log level",
+			input: struct {
+				values configfile.Values
+				err    error
+				reject error
+			}{
+				values: configfile.Values{"BATCH_SIZE": "50", "LOG_LEVEL": "loud"},
+			},
+			expected: struct {
+				result    string
+				published bool
+			}{
+				result: "failure",
+			},
+		},
+		{
+			name: "invalid value",
+			input: struct {
+				values configfile.Values
+				err    error
+				reject error
+			}{
+				values: configfile.Values{"BATCH_SIZE": "many"},
+			},
+			expected: struct {
+				result    string
+				published bool
+			}{
+				result: "failure",
+			},
+		},
+		{
+			name: "rejected by the provider",
+			input: struct {
+				values configfile.Values
+				err    error
+				reject error
+			}{
+				values: configfile.Values{"BATCH_SIZE": "500"},
+				reject: errors.New("BATCH_SIZE must be between 1 and 100"),
+			},
+			expected: struct {
+				result    string
+				published bool
+			}{
+				result: "failure",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
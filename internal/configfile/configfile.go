@@ -0,0 +1,261 @@
+/*
+ * Configuration file - loading and overlay of the settings.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package configfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// secretKeys contains the settings that cannot be read from the configuration
+// file.
+var secretKeys = map[string]bool{
+	"HETZNER_API_KEY":  true,
+	"HETZNER_API_KEYS": true,
+}
+
+// Values contains the settings read from the configuration file, indexed by
+// the name of the environment variable they replace.
+type Values map[string]string
+
+// Load reads and parses a configuration file.
+func Load(path string) (Values, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses the content of a YAML or JSON configuration file. The keys are
+// the names of the environment variables and are case insensitive. Lists are
+// converted to comma separated values.
+func Parse(data []byte) (Values, error) {
+	raw := make(map[string]any)
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("cannot parse configuration file: %w", err)
+	}
+	values := make(Values, len(raw))
+	for key, value := range raw {
+		name := strings.ToUpper(key)
+		if secretKeys[name] {
+			return nil, fmt.Errorf("%s cannot be set in the configuration file", name)
+		}
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("duplicate key %s", name)
+		}
+		str, err := toString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		values[name] = str
+	}
+	return values, nil
+}
+
+// toString converts a parsed value to the format used by the environment
+// variables.
+func toString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.([]any); ok {
+				return "", errors.New("nested lists are not supported")
+			}
+			str, err := toString(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// Apply overwrites the fields of target, which must be a pointer to a struct,
+// with the values whose name matches the env tag of the field.
+func Apply(target any, values Values) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%T is not a pointer to a struct", target)
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		if err := setValue(v.Field(i), value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setValue sets a field from its string representation.
+func setValue(field reflect.Value, value string) error {
+	if field.Kind() != reflect.Slice {
+		return setScalar(field, value)
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setScalar(slice.Index(i), item); err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// setScalar sets a non-slice field from its string representation.
+func setScalar(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Unknown returns the sorted names of the values that do not match any env
+// tag of the targets.
+func Unknown(values Values, targets ...any) []string {
+	known := make(map[string]bool)
+	for _, target := range targets {
+		for _, name := range Names(target) {
+			known[name] = true
+		}
+	}
+	unknown := make([]string, 0)
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// Names returns the env tags of a struct or of a pointer to a struct.
+func Names(target any) []string {
+	t := reflect.TypeOf(target)
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := t.Field(i).Tag.Lookup("env"); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Changed returns the env tags of the fields that differ between two values
+// of the same struct type.
+func Changed(previous, current any) []string {
+	p := reflect.Indirect(reflect.ValueOf(previous))
+	c := reflect.Indirect(reflect.ValueOf(current))
+	if p.Kind() != reflect.Struct || p.Type() != c.Type() {
+		return nil
+	}
+	changed := make([]string, 0)
+	for i := 0; i < p.NumField(); i++ {
+		name, ok := p.Type().Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(p.Field(i).Interface(), c.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
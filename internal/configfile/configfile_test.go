@@ -0,0 +1,347 @@
+/*
+ * Configuration file - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConfig struct {
+	Name     string        `env:"NAME" default:""`
+	Enabled  bool          `env:"ENABLED" default:"false"`
+	Count    int           `env:"COUNT" default:"0"`
+	Port     uint16        `env:"PORT" default:"0"`
+	Ratio    float64       `env:"RATIO" default:"0"`
+	Timeout  time.Duration `env:"TIMEOUT" default:"0"`
+	Domains  []string      `env:"DOMAINS" default:""`
+	Buckets  []float64     `env:"BUCKETS" default:""`
+	internal string
+}
+
+func Test_Parse(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected struct {
+			values Values
+			err    bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		values, err := Parse([]byte(tc.input))
+		assert.Equal(t, tc.expected.err, err != nil)
+		assert.Equal(t, tc.expected.values, values)
+	}
+
+	testCases := []testCase{
+		{
+			name: "yaml",
+			input: "DRY_RUN: true\nbatch_size: 50\nTRACING_SAMPLE_RATIO: 0.5\n" +
+				"DOMAIN_FILTER:\n  - example.com\n  - example.org\nREGEXP_DOMAIN_FILTER: \"\"\n" +
+				"LOG_LEVEL: ~\n",
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				values: Values{
+					"DRY_RUN":              "true",
+					"BATCH_SIZE":           "50",
+					"TRACING_SAMPLE_RATIO": "0.5",
+					"DOMAIN_FILTER":        "example.com,example.org",
+					"REGEXP_DOMAIN_FILTER": "",
+					"LOG_LEVEL":            "",
+				},
+			},
+		},
+		{
+			name:  "json",
+			input: `{"ZONE_CACHE_TTL": 300, "EXCLUDE_DOMAIN_FILTER": ["a.example.com"]}`,
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				values: Values{
+					"ZONE_CACHE_TTL":        "300",
+					"EXCLUDE_DOMAIN_FILTER": "a.example.com",
+				},
+			},
+		},
+		{
+			name:  "empty",
+			input: "",
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				values: Values{},
+			},
+		},
+		{
+			name:  "api key",
+			input: "hetzner_api_key: secret\n",
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				err: true,
+			},
+		},
+		{
+			name:  "project api keys",
+			input: "hetzner_api_keys:\n  - staging=secret\n",
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				err: true,
+			},
+		},
+		{
+			name:  "duplicate key",
+			input: "DRY_RUN: true\ndry_run: false\n",
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				err: true,
+			},
+		},
+		{
+			name:  "map value",
+			input: "DOMAIN_FILTER:\n  name: example.com\n",
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				err: true,
+			},
+		},
+		{
+			name:  "nested list",
+			input: "DOMAIN_FILTER:\n  - [example.com]\n",
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				err: true,
+			},
+		},
+		{
+			name:  "not a map",
+			input: "- DRY_RUN\n",
+			expected: struct {
+				values Values
+				err    bool
+			}{
+				err: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: true\n"), 0600))
+	values, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, Values{"DRY_RUN": "true"}, values)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.NotNil(t, err)
+}
+
+func Test_Apply(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			target any
+			values Values
+		}
+		expected struct {
+			target any
+			err    bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		err := Apply(tc.input.target, tc.input.values)
+		assert.Equal(t, tc.expected.err, err != nil)
+		assert.Equal(t, tc.expected.target, tc.input.target)
+	}
+
+	testCases := []testCase{
+		{
+			name: "all types",
+			input: struct {
+				target any
+				values Values
+			}{
+				target: &testConfig{Name: "env", Count: 3, Domains: []string{"env.com"}},
+				values: Values{
+					"ENABLED": "true",
+					"COUNT":   "-5",
+					"PORT":    "8080",
+					"RATIO":   "0.25",
+					"TIMEOUT": "1m30s",
+					"DOMAINS": "a.com, b.com",
+					"BUCKETS": "0.5,1",
+					"UNKNOWN": "ignored",
+				},
+			},
+			expected: struct {
+				target any
+				err    bool
+			}{
+				target: &testConfig{
+					Name:    "env",
+					Enabled: true,
+					Count:   -5,
+					Port:    8080,
+					Ratio:   0.25,
+					Timeout: 90 * time.Second,
+					Domains: []string{"a.com", "b.com"},
+					Buckets: []float64{0.5, 1},
+				},
+			},
+		},
+		{
+			name: "empty list clears the value",
+			input: struct {
+				target any
+				values Values
+			}{
+				target: &testConfig{Domains: []string{"env.com"}},
+				values: Values{"DOMAINS": ""},
+			},
+			expected: struct {
+				target any
+				err    bool
+			}{
+				target: &testConfig{},
+			},
+		},
+		{
+			name: "invalid bool",
+			input: struct {
+				target any
+				values Values
+			}{
+				target: &testConfig{},
+				values: Values{"ENABLED": "maybe"},
+			},
+			expected: struct {
+				target any
+				err    bool
+			}{
+				target: &testConfig{},
+				err:    true,
+			},
+		},
+		{
+			name: "uint overflow",
+			input: struct {
+				target any
+				values Values
+			}{
+				target: &testConfig{},
+				values: Values{"PORT": "70000"},
+			},
+			expected: struct {
+				target any
+				err    bool
+			}{
+				target: &testConfig{},
+				err:    true,
+			},
+		},
+		{
+			name: "invalid list item",
+			input: struct {
+				target any
+				values Values
+			}{
+				target: &testConfig{},
+				values: Values{"BUCKETS": "1,two"},
+			},
+			expected: struct {
+				target any
+				err    bool
+			}{
+				target: &testConfig{},
+				err:    true,
+			},
+		},
+		{
+			name: "not a pointer",
+			input: struct {
+				target any
+				values Values
+			}{
+				target: testConfig{},
+				values: Values{},
+			},
+			expected: struct {
+				target any
+				err    bool
+			}{
+				target: testConfig{},
+				err:    true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_Unknown(t *testing.T) {
+	values := Values{"NAME": "x", "TYPO": "y", "ANOTHER": "z"}
+	assert.Equal(t, []string{"ANOTHER", "TYPO"}, Unknown(values, &testConfig{}))
+	assert.Equal(t, []string{}, Unknown(Values{"NAME": "x"}, testConfig{}))
+}
+
+func Test_Names(t *testing.T) {
+	expected := []string{"NAME", "ENABLED", "COUNT", "PORT", "RATIO", "TIMEOUT", "DOMAINS", "BUCKETS"}
+	assert.Equal(t, expected, Names(&testConfig{}))
+	assert.Equal(t, expected, Names(testConfig{}))
+	assert.Nil(t, Names(nil))
+	assert.Nil(t, Names("string"))
+}
+
+func Test_Changed(t *testing.T) {
+	previous := testConfig{Name: "a", Domains: []string{"a.com"}, internal: "x"}
+	current := testConfig{Name: "b", Domains: []string{"a.com"}, Count: 1, internal: "y"}
+	assert.Equal(t, []string{"NAME", "COUNT"}, Changed(previous, &current))
+	assert.Equal(t, []string{}, Changed(previous, previous))
+	assert.Nil(t, Changed(previous, "string"))
+}
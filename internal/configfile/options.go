@@ -0,0 +1,48 @@
+/*
+ * Configuration file - options.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package configfile
+
+import (
+	"time"
+
+	"github.com/codingconcepts/env"
+)
+
+// Options contains the configuration file settings passed as environment
+// variables.
+type Options struct {
+	// Path of the YAML or JSON configuration file. Empty disables the file.
+	File string `env:"CONFIG_FILE" default:""`
+	// Seconds between the checks for changes of the configuration file. A
+	// negative or 0 value disables the reload.
+	ReloadInterval int `env:"CONFIG_RELOAD_INTERVAL" default:"10"`
+}
+
+// NewOptions reads the configuration file options from the environment.
+func NewOptions() (*Options, error) {
+	options := &Options{}
+	if err := env.Set(options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// GetReloadInterval returns the reload interval as a time.Duration.
+func (o Options) GetReloadInterval() time.Duration {
+	return time.Duration(int64(o.ReloadInterval) * int64(time.Second))
+}
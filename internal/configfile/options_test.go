@@ -0,0 +1,41 @@
+/*
+ * Configuration file options - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package configfile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewOptions(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "/etc/webhook/config.yaml")
+	options, err := NewOptions()
+	assert.Nil(t, err)
+	expected := &Options{
+		File:           "/etc/webhook/config.yaml",
+		ReloadInterval: 10,
+	}
+	assert.Equal(t, expected, options)
+}
+
+func Test_Options_GetReloadInterval(t *testing.T) {
+	options := Options{ReloadInterval: 30}
+	assert.Equal(t, 30*time.Second, options.GetReloadInterval())
+}
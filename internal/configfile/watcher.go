@@ -0,0 +1,80 @@
+/*
+ * Configuration file - watcher.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package configfile
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+// Watcher detects the changes of the configuration file by comparing the hash
+// of its content. Polling is used instead of file system notifications because
+// ConfigMap volumes are updated by swapping symbolic links.
+type Watcher struct {
+	path   string
+	hash   [sha256.Size]byte
+	failed bool
+}
+
+// NewWatcher creates a watcher for the file at path. The content the current
+// values were parsed from is taken as the starting point, so that a change
+// made after it was read is detected.
+func NewWatcher(path string, content []byte) *Watcher {
+	return &Watcher{path: path, hash: sha256.Sum256(content)}
+}
+
+// check reads the file and returns the new values if the content changed. A
+// read error is reported only once until the file can be read again.
+func (w *Watcher) check() (Values, bool, error) {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		if w.failed {
+			return nil, false, nil
+		}
+		w.failed = true
+		return nil, true, err
+	}
+	w.failed = false
+	hash := sha256.Sum256(data)
+	if hash == w.hash {
+		return nil, false, nil
+	}
+	w.hash = hash
+	values, err := Parse(data)
+	return values, true, err
+}
+
+// Watch checks the file every interval until the context is done, calling
+// reload with the new values or with the error every time a change is
+// detected.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, reload func(Values, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if values, changed, err := w.check(); changed {
+				reload(values, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Configuration file watcher - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package configfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Watcher_check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: true\n"), 0600))
+	w := NewWatcher(path, []byte("DRY_RUN: true\n"))
+
+	// Unchanged content.
+	values, changed, err := w.check()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Nil(t, values)
+
+	// New content.
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: false\n"), 0600))
+	values, changed, err = w.check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, Values{"DRY_RUN": "false"}, values)
+
+	// Invalid content is reported once.
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: [\n"), 0600))
+	_, changed, err = w.check()
+	assert.NotNil(t, err)
+	assert.True(t, changed)
+	_, changed, err = w.check()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	// Missing file is reported once.
+	assert.Nil(t, os.Remove(path))
+	_, changed, err = w.check()
+	assert.NotNil(t, err)
+	assert.True(t, changed)
+	_, changed, err = w.check()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	// File restored.
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: true\n"), 0600))
+	values, changed, err = w.check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, Values{"DRY_RUN": "true"}, values)
+}
+
+func Test_Watcher_changedBeforeStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	// The file changed after the initial content was read.
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: false\n"), 0600))
+	w := NewWatcher(path, []byte("DRY_RUN: true\n"))
+	values, changed, err := w.check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, Values{"DRY_RUN": "false"}, values)
+}
+
+func Test_Watcher_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: true\n"), 0600))
+	w := NewWatcher(path, []byte("DRY_RUN: true\n"))
+	ctx, cancel := context.WithCancel(context.Background())
+	reloaded := make(chan Values, 1)
+	done := make(chan struct{})
+	go func() {
+		w.Watch(ctx, 10*time.Millisecond, func(values Values, err error) {
+			assert.Nil(t, err)
+			reloaded <- values
+		})
+		close(done)
+	}()
+
+	assert.Nil(t, os.WriteFile(path, []byte("DRY_RUN: false\n"), 0600))
+	select {
+	case values := <-reloaded:
+		assert.Equal(t, Values{"DRY_RUN": "false"}, values)
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload not called")
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,331 @@
+/*
+ * Handlers - endpoints of the fake Hetzner Cloud DNS API.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+// zoneListResponse is the response of the zone list.
+type zoneListResponse struct {
+	schema.ZoneListResponse
+	schema.MetaResponse
+}
+
+// rrsetListResponse is the response of the RRSet list.
+type rrsetListResponse struct {
+	schema.ZoneRRSetListResponse
+	schema.MetaResponse
+}
+
+// actionListResponse is the response of the action list.
+type actionListResponse struct {
+	schema.ActionListResponse
+	schema.MetaResponse
+}
+
+// routes returns the handler of the API endpoints. The handlers are called
+// with the lock held.
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /zones", s.listZones)
+	mux.HandleFunc("GET /zones/{zone}", s.withZone(s.getZone))
+	mux.HandleFunc("GET /zones/{zone}/zonefile", s.withZone(s.exportZonefile))
+	mux.HandleFunc("POST /zones/{zone}/actions/import_zonefile", s.withZone(s.importZonefile))
+	mux.HandleFunc("GET /zones/{zone}/rrsets", s.withZone(s.listRRSets))
+	mux.HandleFunc("POST /zones/{zone}/rrsets", s.withZone(s.createRRSet))
+	mux.HandleFunc("GET /zones/{zone}/rrsets/{name}/{type}", s.withRRSet(s.getRRSet))
+	mux.HandleFunc("PUT /zones/{zone}/rrsets/{name}/{type}", s.withRRSet(s.updateRRSet))
+	mux.HandleFunc("DELETE /zones/{zone}/rrsets/{name}/{type}", s.withRRSet(s.deleteRRSet))
+	mux.HandleFunc("POST /zones/{zone}/rrsets/{name}/{type}/actions/change_ttl", s.withRRSet(s.changeTTL))
+	mux.HandleFunc("POST /zones/{zone}/rrsets/{name}/{type}/actions/set_records", s.withRRSet(s.setRecords))
+	mux.HandleFunc("GET /actions", s.listActions)
+	mux.HandleFunc("GET /actions/{id}", s.getAction)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s %s not found", r.Method, r.URL.Path))
+	})
+	return mux
+}
+
+// withZone looks up the zone of the request.
+func (s *Server) withZone(h func(http.ResponseWriter, *http.Request, *zone)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		z := s.findZone(r.PathValue("zone"))
+		if z == nil {
+			writeError(w, http.StatusNotFound, "not_found", "zone not found")
+			return
+		}
+		h(w, r, z)
+	}
+}
+
+// withRRSet looks up the zone and the RRSet of the request.
+func (s *Server) withRRSet(h func(http.ResponseWriter, *http.Request, *zone, *schema.ZoneRRSet)) http.HandlerFunc {
+	return s.withZone(func(w http.ResponseWriter, r *http.Request, z *zone) {
+		rrset, ok := z.rrsets[rrsetKey{r.PathValue("name"), r.PathValue("type")}]
+		if !ok {
+			writeError(w, http.StatusNotFound, "not_found", "rrset not found")
+			return
+		}
+		h(w, r, z, rrset)
+	})
+}
+
+// readBody decodes the JSON body of the request. It writes an error response
+// and returns false if the body is not valid.
+func readBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// paginate returns the bounds of the requested page of a list with n entries
+// and the pagination metadata. It writes an error response and returns false
+// if the parameters are not valid.
+func paginate(w http.ResponseWriter, q url.Values, n int) (int, int, schema.MetaResponse, bool) {
+	page, perPage := 1, defaultPerPage
+	var err error
+	if v := q.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			writeError(w, http.StatusBadRequest, "invalid_input", "invalid page")
+			return 0, 0, schema.MetaResponse{}, false
+		}
+	}
+	if v := q.Get("per_page"); v != "" {
+		if perPage, err = strconv.Atoi(v); err != nil || perPage < 1 {
+			writeError(w, http.StatusBadRequest, "invalid_input", "invalid per_page")
+			return 0, 0, schema.MetaResponse{}, false
+		}
+	}
+	perPage = min(perPage, maxPerPage)
+	lastPage := max(1, (n+perPage-1)/perPage)
+	meta := schema.MetaPagination{
+		Page:         page,
+		PerPage:      perPage,
+		LastPage:     lastPage,
+		TotalEntries: n,
+	}
+	if page > 1 {
+		meta.PreviousPage = page - 1
+	}
+	if page < lastPage {
+		meta.NextPage = page + 1
+	}
+	start := min(n, (page-1)*perPage)
+	end := min(n, start+perPage)
+	return start, end, schema.MetaResponse{Meta: schema.Meta{Pagination: &meta}}, true
+}
+
+// listZones handles GET /zones.
+func (s *Server) listZones(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	zones := make([]schema.Zone, 0, len(s.zones))
+	for _, z := range s.sortedZones() {
+		if name := q.Get("name"); name == "" || name == z.schema.Name {
+			zones = append(zones, z.toSchema())
+		}
+	}
+	start, end, meta, ok := paginate(w, q, len(zones))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, zoneListResponse{
+		ZoneListResponse: schema.ZoneListResponse{Zones: zones[start:end]},
+		MetaResponse:     meta,
+	})
+}
+
+// getZone handles GET /zones/{zone}.
+func (s *Server) getZone(w http.ResponseWriter, _ *http.Request, z *zone) {
+	writeJSON(w, http.StatusOK, schema.ZoneGetResponse{Zone: z.toSchema()})
+}
+
+// exportZonefile handles GET /zones/{zone}/zonefile.
+func (s *Server) exportZonefile(w http.ResponseWriter, _ *http.Request, z *zone) {
+	writeJSON(w, http.StatusOK, schema.ZoneExportZonefileResponse{Zonefile: exportZonefile(z)})
+}
+
+// importZonefile handles POST /zones/{zone}/actions/import_zonefile. The
+// RRSets of the zone are replaced, keeping the labels of the RRSets that are
+// still present.
+func (s *Server) importZonefile(w http.ResponseWriter, r *http.Request, z *zone) {
+	var req schema.ZoneImportZonefileRequest
+	if !readBody(w, r, &req) {
+		return
+	}
+	rrsets, err := importZonefile(z, req.Zonefile)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_input", err.Error())
+		return
+	}
+	for key, rrset := range rrsets {
+		if old, ok := z.rrsets[key]; ok {
+			rrset.Labels = old.Labels
+		}
+	}
+	z.rrsets = rrsets
+	writeJSON(w, http.StatusCreated, schema.ActionGetResponse{Action: s.newAction("import_zonefile", z.schema.ID)})
+}
+
+// listRRSets handles GET /zones/{zone}/rrsets.
+func (s *Server) listRRSets(w http.ResponseWriter, r *http.Request, z *zone) {
+	q := r.URL.Query()
+	types := q["type"]
+	rrsets := make([]schema.ZoneRRSet, 0, len(z.rrsets))
+	for _, rrset := range z.sortedRRSets() {
+		if name := q.Get("name"); name != "" && name != rrset.Name {
+			continue
+		}
+		if len(types) > 0 && !slices.Contains(types, rrset.Type) {
+			continue
+		}
+		rrsets = append(rrsets, *rrset)
+	}
+	start, end, meta, ok := paginate(w, q, len(rrsets))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, rrsetListResponse{
+		ZoneRRSetListResponse: schema.ZoneRRSetListResponse{RRSets: rrsets[start:end]},
+		MetaResponse:          meta,
+	})
+}
+
+// createRRSet handles POST /zones/{zone}/rrsets.
+func (s *Server) createRRSet(w http.ResponseWriter, r *http.Request, z *zone) {
+	var req schema.ZoneRRSetCreateRequest
+	if !readBody(w, r, &req) {
+		return
+	}
+	if req.Name == "" || req.Type == "" || len(req.Records) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_input", "name, type and records are required")
+		return
+	}
+	key := rrsetKey{req.Name, req.Type}
+	if _, ok := z.rrsets[key]; ok {
+		writeError(w, http.StatusConflict, "uniqueness_error", "rrset already exists")
+		return
+	}
+	var labels map[string]string
+	if req.Labels != nil {
+		labels = *req.Labels
+	}
+	values := make([]string, 0, len(req.Records))
+	for _, record := range req.Records {
+		values = append(values, record.Value)
+	}
+	rrset := newRRSet(z.schema.ID, req.Name, req.Type, req.TTL, labels, values)
+	z.rrsets[key] = rrset
+	writeJSON(w, http.StatusCreated, schema.ZoneRRSetCreateResponse{
+		RRSet:  *rrset,
+		Action: s.newAction("create_rrset", z.schema.ID),
+	})
+}
+
+// getRRSet handles GET /zones/{zone}/rrsets/{name}/{type}.
+func (s *Server) getRRSet(w http.ResponseWriter, _ *http.Request, _ *zone, rrset *schema.ZoneRRSet) {
+	writeJSON(w, http.StatusOK, schema.ZoneRRSetGetResponse{RRSet: *rrset})
+}
+
+// updateRRSet handles PUT /zones/{zone}/rrsets/{name}/{type}, which changes
+// the labels.
+func (s *Server) updateRRSet(w http.ResponseWriter, r *http.Request, _ *zone, rrset *schema.ZoneRRSet) {
+	var req schema.ZoneRRSetUpdateRequest
+	if !readBody(w, r, &req) {
+		return
+	}
+	if req.Labels != nil {
+		rrset.Labels = maps.Clone(*req.Labels)
+	}
+	writeJSON(w, http.StatusOK, schema.ZoneRRSetUpdateResponse{RRSet: *rrset})
+}
+
+// deleteRRSet handles DELETE /zones/{zone}/rrsets/{name}/{type}.
+func (s *Server) deleteRRSet(w http.ResponseWriter, _ *http.Request, z *zone, rrset *schema.ZoneRRSet) {
+	delete(z.rrsets, rrsetKey{rrset.Name, rrset.Type})
+	writeJSON(w, http.StatusCreated, schema.ActionGetResponse{Action: s.newAction("delete_rrset", z.schema.ID)})
+}
+
+// changeTTL handles POST /zones/{zone}/rrsets/{name}/{type}/actions/change_ttl.
+func (s *Server) changeTTL(w http.ResponseWriter, r *http.Request, z *zone, rrset *schema.ZoneRRSet) {
+	var req schema.ZoneRRSetChangeTTLRequest
+	if !readBody(w, r, &req) {
+		return
+	}
+	rrset.TTL = req.TTL
+	writeJSON(w, http.StatusCreated, schema.ActionGetResponse{Action: s.newAction("change_rrset_ttl", z.schema.ID)})
+}
+
+// setRecords handles POST /zones/{zone}/rrsets/{name}/{type}/actions/set_records.
+func (s *Server) setRecords(w http.ResponseWriter, r *http.Request, z *zone, rrset *schema.ZoneRRSet) {
+	var req schema.ZoneRRSetSetRecordsRequest
+	if !readBody(w, r, &req) {
+		return
+	}
+	if len(req.Records) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_input", "records are required")
+		return
+	}
+	rrset.Records = req.Records
+	writeJSON(w, http.StatusCreated, schema.ActionGetResponse{Action: s.newAction("set_rrset_records", z.schema.ID)})
+}
+
+// listActions handles GET /actions, filtered by the id parameters.
+func (s *Server) listActions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	actions := make([]schema.Action, 0)
+	for _, v := range q["id"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_input", "invalid id")
+			return
+		}
+		if action, ok := s.actions[id]; ok {
+			actions = append(actions, action)
+		}
+	}
+	start, end, meta, ok := paginate(w, q, len(actions))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, actionListResponse{
+		ActionListResponse: schema.ActionListResponse{Actions: actions[start:end]},
+		MetaResponse:       meta,
+	})
+}
+
+// getAction handles GET /actions/{id}.
+func (s *Server) getAction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	action, ok := s.actions[id]
+	if err != nil || !ok {
+		writeError(w, http.StatusNotFound, "not_found", "action not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, schema.ActionGetResponse{Action: action})
+}
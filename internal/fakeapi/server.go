@@ -0,0 +1,430 @@
+/*
+ * Server - in-process fake of the Hetzner Cloud DNS API for tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeapi
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+const (
+	// DefaultRateLimit is the number of API calls available in one hour.
+	DefaultRateLimit = 3600
+	// Default and maximum page sizes of the list calls.
+	defaultPerPage = 25
+	maxPerPage     = 100
+	// Values of the default SOA and NS records of a new zone.
+	defaultSOA = "hydrogen.ns.hetzner.com. dns.hetzner.com. 2026010100 86400 10800 3600000 3600"
+)
+
+// defaultNameservers are the authoritative nameservers of every zone.
+var defaultNameservers = []string{
+	"hydrogen.ns.hetzner.com.",
+	"oxygen.ns.hetzner.com.",
+	"helium.ns.hetzner.de.",
+}
+
+// RRSet is an RRSet of a fake zone. A TTL of 0 uses the TTL of the zone.
+type RRSet struct {
+	Name    string
+	Type    string
+	TTL     int
+	Labels  map[string]string
+	Records []string
+}
+
+// Error is an error returned instead of handling the matching requests.
+type Error struct {
+	// HTTP method of the requests, empty for any method
+	Method string
+	// Pattern of the request path, as in path.Match, empty for any path
+	Path string
+	// HTTP status code
+	Status int
+	// Error code, like "not_found" or "invalid_input"
+	Code string
+	// Error message
+	Message string
+	// Number of requests that fail, 0 for all of them
+	Times int
+}
+
+// matches returns true if the error applies to the request.
+func (e Error) matches(r *http.Request) bool {
+	if e.Method != "" && e.Method != r.Method {
+		return false
+	}
+	if e.Path == "" {
+		return true
+	}
+	ok, _ := path.Match(e.Path, r.URL.Path)
+	return ok
+}
+
+// Request is a request received by the fake API.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+}
+
+// rrsetKey identifies an RRSet in a zone.
+type rrsetKey struct {
+	name   string
+	rrType string
+}
+
+// zone is a fake zone with its RRSets.
+type zone struct {
+	schema schema.Zone
+	rrsets map[rrsetKey]*schema.ZoneRRSet
+}
+
+// toSchema returns the zone as sent by the API.
+func (z *zone) toSchema() schema.Zone {
+	sz := z.schema
+	sz.RecordCount = 0
+	for _, rrset := range z.rrsets {
+		sz.RecordCount += len(rrset.Records)
+	}
+	return sz
+}
+
+// sortedRRSets returns the RRSets sorted by name and type, with the SOA
+// record first.
+func (z *zone) sortedRRSets() []*schema.ZoneRRSet {
+	rrsets := slices.Collect(maps.Values(z.rrsets))
+	slices.SortFunc(rrsets, func(a, b *schema.ZoneRRSet) int {
+		if (a.Type == "SOA") != (b.Type == "SOA") {
+			if a.Type == "SOA" {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Type, b.Type))
+	})
+	return rrsets
+}
+
+// Server is an in-process fake of the zone and RRSet endpoints of the Hetzner
+// Cloud API, including the zonefile export and import. Every action completes
+// immediately. The requests are handled one at a time.
+type Server struct {
+	// Base URL of the API, to be used with hcloud.WithEndpoint
+	URL       string
+	srv       *httptest.Server
+	mux       *http.ServeMux
+	token     string
+	m         sync.Mutex
+	zones     map[int64]*zone
+	actions   map[int64]schema.Action
+	nextID    int64
+	errs      []*Error
+	requests  []Request
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// NewServer starts a fake API that only accepts the given token. An empty
+// token accepts every request.
+func NewServer(token string) *Server {
+	s := &Server{
+		token:     token,
+		zones:     make(map[int64]*zone),
+		actions:   make(map[int64]schema.Action),
+		limit:     DefaultRateLimit,
+		remaining: DefaultRateLimit,
+		reset:     time.Now().Add(time.Hour),
+	}
+	s.mux = s.routes()
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// id returns a new identifier for a zone or an action.
+func (s *Server) id() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// AddZone adds a zone with the default SOA and NS records and returns its
+// ID. If the zone already exists, its ID is returned.
+func (s *Server) AddZone(name string, ttl int) int64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if z := s.findZone(name); z != nil {
+		return z.schema.ID
+	}
+	z := &zone{
+		schema: schema.Zone{
+			ID:      s.id(),
+			Name:    name,
+			Created: time.Now().UTC(),
+			TTL:     ttl,
+			Mode:    "primary",
+			Status:  "ok",
+			Labels:  map[string]string{},
+			AuthoritativeNameservers: schema.ZoneAuthoritativeNameservers{
+				Assigned: defaultNameservers,
+			},
+		},
+		rrsets: make(map[rrsetKey]*schema.ZoneRRSet),
+	}
+	z.rrsets[rrsetKey{"@", "SOA"}] = newRRSet(z.schema.ID, "@", "SOA", nil, nil, []string{defaultSOA})
+	z.rrsets[rrsetKey{"@", "NS"}] = newRRSet(z.schema.ID, "@", "NS", nil, nil, defaultNameservers)
+	s.zones[z.schema.ID] = z
+	return z.schema.ID
+}
+
+// SetRRSet creates or replaces an RRSet in a zone, as if it was changed in
+// the Hetzner Console.
+func (s *Server) SetRRSet(zoneName string, rrset RRSet) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	z := s.findZone(zoneName)
+	if z == nil {
+		return fmt.Errorf("zone %s not found", zoneName)
+	}
+	var ttl *int
+	if rrset.TTL != 0 {
+		ttl = &rrset.TTL
+	}
+	z.rrsets[rrsetKey{rrset.Name, rrset.Type}] = newRRSet(z.schema.ID, rrset.Name, rrset.Type, ttl, rrset.Labels, rrset.Records)
+	return nil
+}
+
+// DeleteRRSet deletes an RRSet from a zone, as if it was deleted in the
+// Hetzner Console.
+func (s *Server) DeleteRRSet(zoneName, name, rrType string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	z := s.findZone(zoneName)
+	if z == nil {
+		return fmt.Errorf("zone %s not found", zoneName)
+	}
+	key := rrsetKey{name, rrType}
+	if _, ok := z.rrsets[key]; !ok {
+		return fmt.Errorf("RRSet %s of type %s not found in zone %s", name, rrType, zoneName)
+	}
+	delete(z.rrsets, key)
+	return nil
+}
+
+// RRSets returns the RRSets of a zone sorted by name and type, with the SOA
+// record first.
+func (s *Server) RRSets(zoneName string) ([]RRSet, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	z := s.findZone(zoneName)
+	if z == nil {
+		return nil, fmt.Errorf("zone %s not found", zoneName)
+	}
+	rrsets := make([]RRSet, 0, len(z.rrsets))
+	for _, sr := range z.sortedRRSets() {
+		rrset := RRSet{
+			Name:    sr.Name,
+			Type:    sr.Type,
+			Labels:  maps.Clone(sr.Labels),
+			Records: make([]string, 0, len(sr.Records)),
+		}
+		if sr.TTL != nil {
+			rrset.TTL = *sr.TTL
+		}
+		for _, r := range sr.Records {
+			rrset.Records = append(rrset.Records, r.Value)
+		}
+		rrsets = append(rrsets, rrset)
+	}
+	return rrsets, nil
+}
+
+// InjectError makes the matching requests fail with the error.
+func (s *Server) InjectError(e Error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.errs = append(s.errs, &e)
+}
+
+// ClearErrors removes the injected errors.
+func (s *Server) ClearErrors() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.errs = nil
+}
+
+// SetFirstID sets the identifier of the next zone or action, so that several
+// servers can act as projects of the same account without clashing IDs.
+func (s *Server) SetFirstID(id int64) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.nextID = id - 1
+}
+
+// SetRateLimit sets the rate limit and the number of calls remaining before
+// the requests are rejected.
+func (s *Server) SetRateLimit(limit, remaining int) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.limit = limit
+	s.remaining = remaining
+	s.reset = time.Now().Add(time.Hour)
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// findZone returns a zone by ID or name, or nil if not found.
+func (s *Server) findZone(idOrName string) *zone {
+	if id, err := strconv.ParseInt(idOrName, 10, 64); err == nil {
+		return s.zones[id]
+	}
+	for _, z := range s.zones {
+		if z.schema.Name == idOrName {
+			return z
+		}
+	}
+	return nil
+}
+
+// sortedZones returns the zones sorted by name.
+func (s *Server) sortedZones() []*zone {
+	zones := slices.Collect(maps.Values(s.zones))
+	slices.SortFunc(zones, func(a, b *zone) int {
+		return cmp.Compare(a.schema.Name, b.schema.Name)
+	})
+	return zones
+}
+
+// newAction records a completed action on a zone.
+func (s *Server) newAction(command string, zoneID int64) schema.Action {
+	now := time.Now().UTC()
+	action := schema.Action{
+		ID:        s.id(),
+		Status:    "success",
+		Command:   command,
+		Progress:  100,
+		Started:   now,
+		Finished:  &now,
+		Resources: []schema.ActionResourceReference{{ID: zoneID, Type: "zone"}},
+	}
+	s.actions[action.ID] = action
+	return action
+}
+
+// newRRSet returns a new RRSet of a zone.
+func newRRSet(zoneID int64, name, rrType string, ttl *int, labels map[string]string, values []string) *schema.ZoneRRSet {
+	records := make([]schema.ZoneRRSetRecord, 0, len(values))
+	for _, v := range values {
+		records = append(records, schema.ZoneRRSetRecord{Value: v})
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return &schema.ZoneRRSet{
+		ID:      name + "/" + rrType,
+		Name:    name,
+		Type:    rrType,
+		TTL:     ttl,
+		Labels:  maps.Clone(labels),
+		Records: records,
+		Zone:    zoneID,
+	}
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, schema.ErrorResponse{
+		Error: schema.Error{Code: code, Message: message},
+	})
+}
+
+// takeError returns the first injected error matching the request, if any.
+func (s *Server) takeError(r *http.Request) *Error {
+	for i, e := range s.errs {
+		if !e.matches(r) {
+			continue
+		}
+		if e.Times > 0 {
+			e.Times--
+			if e.Times == 0 {
+				s.errs = slices.Delete(s.errs, i, i+1)
+			}
+		}
+		return e
+	}
+	return nil
+}
+
+// ServeHTTP checks the token, the rate limit and the injected errors before
+// handling the request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query()})
+
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unable to authenticate")
+		return
+	}
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(s.limit))
+	h.Set("RateLimit-Reset", strconv.FormatInt(s.reset.Unix(), 10))
+	if s.remaining <= 0 {
+		h.Set("RateLimit-Remaining", "0")
+		writeError(w, http.StatusTooManyRequests, "rate_limit_exceeded", "limit of requests per hour reached")
+		return
+	}
+	s.remaining--
+	h.Set("RateLimit-Remaining", strconv.Itoa(s.remaining))
+
+	if e := s.takeError(r); e != nil {
+		writeError(w, e.Status, e.Code, e.Message)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,288 @@
+/*
+ * Server - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "test-token"
+
+// newTestClient returns a fake API and a client connected to it.
+func newTestClient(t *testing.T) (*Server, *hcloud.Client) {
+	s := NewServer(testToken)
+	t.Cleanup(s.Close)
+	client := hcloud.NewClient(
+		hcloud.WithEndpoint(s.URL),
+		hcloud.WithToken(testToken),
+	)
+	return s, client
+}
+
+// Test_Server_AddZone tests AddZone().
+func Test_Server_AddZone(t *testing.T) {
+	s, client := newTestClient(t)
+	id := s.AddZone("alpha.com", 3600)
+	assert.Equal(t, id, s.AddZone("alpha.com", 7200))
+
+	z, _, err := client.Zone.Get(context.Background(), "alpha.com")
+	assert.Nil(t, err)
+	assert.Equal(t, id, z.ID)
+	s.SetFirstID(100)
+	assert.Equal(t, int64(100), s.AddZone("beta.com", 3600))
+	assert.Equal(t, 3600, z.TTL)
+	assert.Equal(t, 4, z.RecordCount)
+
+	rrsets, err := s.RRSets("alpha.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []RRSet{
+		{Name: "@", Type: "SOA", Labels: map[string]string{}, Records: []string{defaultSOA}},
+		{Name: "@", Type: "NS", Labels: map[string]string{}, Records: defaultNameservers},
+	}, rrsets)
+
+	_, err = s.RRSets("gamma.com")
+	assert.EqualError(t, err, "zone gamma.com not found")
+}
+
+// Test_Server_RRSets tests the RRSet endpoints.
+func Test_Server_RRSets(t *testing.T) {
+	s, client := newTestClient(t)
+	ctx := context.Background()
+	s.AddZone("alpha.com", 3600)
+	z := &hcloud.Zone{Name: "alpha.com"}
+	ttl := 600
+
+	res, _, err := client.Zone.CreateRRSet(ctx, z, hcloud.ZoneRRSetCreateOpts{
+		Name:    "www",
+		Type:    hcloud.ZoneRRSetTypeA,
+		TTL:     &ttl,
+		Labels:  map[string]string{"env": "test"},
+		Records: []hcloud.ZoneRRSetRecord{{Value: "127.0.0.1"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "www/A", res.RRSet.ID)
+	assert.Nil(t, client.Action.WaitFor(ctx, res.Action))
+
+	_, _, err = client.Zone.CreateRRSet(ctx, z, hcloud.ZoneRRSetCreateOpts{
+		Name:    "www",
+		Type:    hcloud.ZoneRRSetTypeA,
+		Records: []hcloud.ZoneRRSetRecord{{Value: "127.0.0.2"}},
+	})
+	assert.True(t, hcloud.IsError(err, hcloud.ErrorCodeUniquenessError))
+
+	rrset, _, err := client.Zone.GetRRSetByNameAndType(ctx, z, "www", hcloud.ZoneRRSetTypeA)
+	assert.Nil(t, err)
+	action, _, err := client.Zone.SetRRSetRecords(ctx, rrset, hcloud.ZoneRRSetSetRecordsOpts{
+		Records: []hcloud.ZoneRRSetRecord{{Value: "127.0.0.2"}, {Value: "127.0.0.3"}},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, client.Action.WaitFor(ctx, action))
+	newTTL := 300
+	_, _, err = client.Zone.ChangeRRSetTTL(ctx, rrset, hcloud.ZoneRRSetChangeTTLOpts{TTL: &newTTL})
+	assert.Nil(t, err)
+	_, _, err = client.Zone.UpdateRRSet(ctx, rrset, hcloud.ZoneRRSetUpdateOpts{
+		Labels: map[string]string{"env": "prod"},
+	})
+	assert.Nil(t, err)
+
+	rrsets, err := s.RRSets("alpha.com")
+	assert.Nil(t, err)
+	assert.Equal(t, RRSet{
+		Name:    "www",
+		Type:    "A",
+		TTL:     300,
+		Labels:  map[string]string{"env": "prod"},
+		Records: []string{"127.0.0.2", "127.0.0.3"},
+	}, rrsets[2])
+
+	_, _, err = client.Zone.DeleteRRSet(ctx, rrset)
+	assert.Nil(t, err)
+	rrsets, _ = s.RRSets("alpha.com")
+	assert.Len(t, rrsets, 2)
+
+	rrset, _, err = client.Zone.GetRRSetByNameAndType(ctx, z, "www", hcloud.ZoneRRSetTypeA)
+	assert.Nil(t, err)
+	assert.Nil(t, rrset)
+	assert.EqualError(t, s.DeleteRRSet("alpha.com", "www", "A"),
+		"RRSet www of type A not found in zone alpha.com")
+}
+
+// Test_Server_pagination tests that the lists are paginated.
+func Test_Server_pagination(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			rrsets  int
+			perPage int
+		}
+		expected struct {
+			calls int
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		s, client := newTestClient(t)
+		s.AddZone("alpha.com", 3600)
+		for i := range inp.rrsets {
+			err := s.SetRRSet("alpha.com", RRSet{
+				Name:    "host" + string(rune('a'+i%26)) + string(rune('a'+i/26)),
+				Type:    "A",
+				Records: []string{"127.0.0.1"},
+			})
+			assert.Nil(t, err)
+		}
+		rrsets, err := client.Zone.AllRRSetsWithOpts(context.Background(), &hcloud.Zone{Name: "alpha.com"},
+			hcloud.ZoneRRSetListOpts{ListOpts: hcloud.ListOpts{PerPage: inp.perPage}})
+		assert.Nil(t, err)
+		assert.Len(t, rrsets, inp.rrsets+2)
+		assert.Equal(t, "SOA", string(rrsets[0].Type))
+		assert.Len(t, s.Requests(), exp.calls)
+	}
+
+	testCases := []testCase{
+		{
+			name: "single page",
+			input: struct {
+				rrsets  int
+				perPage int
+			}{rrsets: 3, perPage: 10},
+			expected: struct{ calls int }{calls: 1},
+		},
+		{
+			name: "several pages",
+			input: struct {
+				rrsets  int
+				perPage int
+			}{rrsets: 28, perPage: 10},
+			expected: struct{ calls int }{calls: 3},
+		},
+		{
+			name: "page size above maximum",
+			input: struct {
+				rrsets  int
+				perPage int
+			}{rrsets: 150, perPage: 500},
+			expected: struct{ calls int }{calls: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_Server_auth tests that the requests need the token.
+func Test_Server_auth(t *testing.T) {
+	s, _ := newTestClient(t)
+	client := hcloud.NewClient(hcloud.WithEndpoint(s.URL), hcloud.WithToken("wrong"))
+	_, err := client.Zone.All(context.Background())
+	assert.True(t, hcloud.IsError(err, hcloud.ErrorCodeUnauthorized))
+
+	open := NewServer("")
+	defer open.Close()
+	client = hcloud.NewClient(hcloud.WithEndpoint(open.URL), hcloud.WithToken("any"))
+	zones, err := client.Zone.All(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, zones)
+}
+
+// Test_Server_rateLimit tests the rate limit headers and errors.
+func Test_Server_rateLimit(t *testing.T) {
+	s, client := newTestClient(t)
+	ctx := context.Background()
+	s.SetRateLimit(10, 2)
+
+	_, resp, err := client.Zone.List(ctx, hcloud.ZoneListOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, "10", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, resp.Header.Get("RateLimit-Reset"))
+
+	// The client retries rate limit errors, so the requests are sent raw.
+	req, err := http.NewRequest(http.MethodGet, s.URL+"/zones", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	for _, status := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		_ = r.Body.Close()
+		assert.Equal(t, status, r.StatusCode)
+		assert.Equal(t, "0", r.Header.Get("RateLimit-Remaining"))
+	}
+}
+
+// Test_Server_InjectError tests the injected errors.
+func Test_Server_InjectError(t *testing.T) {
+	s, client := newTestClient(t)
+	ctx := context.Background()
+	s.AddZone("alpha.com", 3600)
+	z := &hcloud.Zone{Name: "alpha.com"}
+
+	s.InjectError(Error{
+		Method:  http.MethodGet,
+		Path:    "/zones/*/rrsets",
+		Status:  http.StatusUnprocessableEntity,
+		Code:    "invalid_input",
+		Message: "test error",
+		Times:   1,
+	})
+	_, err := client.Zone.AllRRSets(ctx, z)
+	assert.True(t, hcloud.IsError(err, hcloud.ErrorCodeInvalidInput))
+	assert.ErrorContains(t, err, "test error")
+	_, err = client.Zone.AllRRSets(ctx, z)
+	assert.Nil(t, err)
+
+	s.InjectError(Error{Method: http.MethodPost, Status: http.StatusForbidden, Code: "forbidden"})
+	_, err = client.Zone.AllRRSets(ctx, z)
+	assert.Nil(t, err)
+	for range 2 {
+		_, _, err = client.Zone.CreateRRSet(ctx, z, hcloud.ZoneRRSetCreateOpts{
+			Name:    "www",
+			Type:    hcloud.ZoneRRSetTypeA,
+			Records: []hcloud.ZoneRRSetRecord{{Value: "127.0.0.1"}},
+		})
+		assert.True(t, hcloud.IsError(err, hcloud.ErrorCodeForbidden))
+	}
+	s.ClearErrors()
+	_, _, err = client.Zone.CreateRRSet(ctx, z, hcloud.ZoneRRSetCreateOpts{
+		Name:    "www",
+		Type:    hcloud.ZoneRRSetTypeA,
+		Records: []hcloud.ZoneRRSetRecord{{Value: "127.0.0.1"}},
+	})
+	assert.Nil(t, err)
+}
+
+// Test_Server_notFound tests the requests for unknown resources.
+func Test_Server_notFound(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx := context.Background()
+	z, _, err := client.Zone.Get(ctx, "unknown.com")
+	assert.Nil(t, err)
+	assert.Nil(t, z)
+	_, err = client.Zone.AllRRSets(ctx, &hcloud.Zone{Name: "unknown.com"})
+	assert.True(t, hcloud.IsError(err, hcloud.ErrorCodeNotFound))
+}
@@ -0,0 +1,90 @@
+/*
+ * Zonefile - zonefile export and import of the fake Hetzner Cloud DNS API.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"codeberg.org/miekg/dns"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+// exportZonefile returns the zonefile of a zone. The RRSets without a TTL use
+// the TTL of the zone.
+func exportZonefile(z *zone) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ORIGIN\t%s.\n", z.schema.Name)
+	fmt.Fprintf(&b, "$TTL\t%d\n", z.schema.TTL)
+	for _, rrset := range z.sortedRRSets() {
+		for _, record := range rrset.Records {
+			if rrset.TTL != nil {
+				fmt.Fprintf(&b, "%s\t%d\tIN\t%s\t%s\n", rrset.Name, *rrset.TTL, rrset.Type, record.Value)
+			} else {
+				fmt.Fprintf(&b, "%s\tIN\t%s\t%s\n", rrset.Name, rrset.Type, record.Value)
+			}
+		}
+	}
+	return b.String()
+}
+
+// relativeName returns the name of a record relative to the origin, or "@"
+// for the origin itself.
+func relativeName(name, origin string) string {
+	if name == origin {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+origin)
+}
+
+// importZonefile parses a zonefile and returns the RRSets of the zone. The
+// records with the TTL of the zone get no TTL. Exactly one SOA record is
+// required.
+func importZonefile(z *zone, zf string) (map[rrsetKey]*schema.ZoneRRSet, error) {
+	origin := z.schema.Name + "."
+	zp := dns.NewZoneParser(strings.NewReader(zf), origin, z.schema.Name+".zone")
+	rrsets := make(map[rrsetKey]*schema.ZoneRRSet)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		// The text form is: name, TTL, class, type and value.
+		fields := strings.SplitN(rr.String(), "\t", 5)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("invalid record %q", rr.String())
+		}
+		name := relativeName(rr.Header().Name, origin)
+		rrType := dns.TypeToString[dns.RRToType(rr)]
+		key := rrsetKey{name, rrType}
+		rrset, found := rrsets[key]
+		if !found {
+			var ttl *int
+			if recTTL := int(rr.Header().TTL); recTTL != z.schema.TTL {
+				ttl = &recTTL
+			}
+			rrset = newRRSet(z.schema.ID, name, rrType, ttl, nil, nil)
+			rrsets[key] = rrset
+		}
+		rrset.Records = append(rrset.Records, schema.ZoneRRSetRecord{Value: fields[4]})
+	}
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("invalid zonefile: %w", err)
+	}
+	if soa, ok := rrsets[rrsetKey{"@", "SOA"}]; !ok || len(soa.Records) != 1 {
+		return nil, errors.New("invalid zonefile: exactly one SOA record is required")
+	}
+	return rrsets, nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Zonefile - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_exportZonefile tests the zonefile export.
+func Test_exportZonefile(t *testing.T) {
+	s, client := newTestClient(t)
+	s.AddZone("alpha.com", 3600)
+	assert.Nil(t, s.SetRRSet("alpha.com", RRSet{Name: "www", Type: "A", TTL: 300, Records: []string{"127.0.0.1", "127.0.0.2"}}))
+	assert.Nil(t, s.SetRRSet("alpha.com", RRSet{Name: "@", Type: "TXT", Records: []string{`"v=spf1 -all"`}}))
+
+	res, _, err := client.Zone.ExportZonefile(context.Background(), &hcloud.Zone{Name: "alpha.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, "$ORIGIN\talpha.com.\n"+
+		"$TTL\t3600\n"+
+		"@\tIN\tSOA\t"+defaultSOA+"\n"+
+		"@\tIN\tNS\thydrogen.ns.hetzner.com.\n"+
+		"@\tIN\tNS\toxygen.ns.hetzner.com.\n"+
+		"@\tIN\tNS\thelium.ns.hetzner.de.\n"+
+		"@\tIN\tTXT\t\"v=spf1 -all\"\n"+
+		"www\t300\tIN\tA\t127.0.0.1\n"+
+		"www\t300\tIN\tA\t127.0.0.2\n", res.Zonefile)
+}
+
+// Test_importZonefile tests the zonefile import.
+func Test_importZonefile(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected struct {
+			rrsets []RRSet
+			err    string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		s, client := newTestClient(t)
+		ctx := context.Background()
+		s.AddZone("alpha.com", 3600)
+		assert.Nil(t, s.SetRRSet("alpha.com", RRSet{
+			Name:    "www",
+			Type:    "A",
+			Labels:  map[string]string{"env": "test"},
+			Records: []string{"127.0.0.9"},
+		}))
+		action, _, err := client.Zone.ImportZonefile(ctx, &hcloud.Zone{Name: "alpha.com"},
+			hcloud.ZoneImportZonefileOpts{Zonefile: tc.input})
+		if exp.err != "" {
+			assert.ErrorContains(t, err, exp.err)
+			return
+		}
+		assert.Nil(t, err)
+		assert.Nil(t, client.Action.WaitFor(ctx, action))
+		rrsets, err := s.RRSets("alpha.com")
+		assert.Nil(t, err)
+		assert.Equal(t, exp.rrsets, rrsets)
+	}
+
+	testCases := []testCase{
+		{
+			name: "valid zonefile",
+			input: "$ORIGIN alpha.com.\n" +
+				"$TTL 3600\n" +
+				"@ IN SOA " + defaultSOA + "\n" +
+				"www 300 IN A 127.0.0.1\n" +
+				"www.alpha.com. 300 IN A 127.0.0.2\n" +
+				"ftp IN CNAME www\n",
+			expected: struct {
+				rrsets []RRSet
+				err    string
+			}{
+				rrsets: []RRSet{
+					{Name: "@", Type: "SOA", Labels: map[string]string{}, Records: []string{defaultSOA}},
+					{Name: "ftp", Type: "CNAME", Labels: map[string]string{}, Records: []string{"www.alpha.com."}},
+					{Name: "www", Type: "A", TTL: 300, Labels: map[string]string{"env": "test"}, Records: []string{"127.0.0.1", "127.0.0.2"}},
+				},
+			},
+		},
+		{
+			name:  "missing SOA",
+			input: "$ORIGIN alpha.com.\n$TTL 3600\nwww IN A 127.0.0.1\n",
+			expected: struct {
+				rrsets []RRSet
+				err    string
+			}{
+				err: "invalid zonefile: exactly one SOA record is required",
+			},
+		},
+		{
+			name:  "syntax error",
+			input: "$ORIGIN alpha.com.\n$TTL 3600\nwww IN A not-an-address\n",
+			expected: struct {
+				rrsets []RRSet
+				err    string
+			}{
+				err: "invalid zonefile",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_zonefile_roundTrip tests that an exported zonefile can be imported
+// without changes.
+func Test_zonefile_roundTrip(t *testing.T) {
+	s, client := newTestClient(t)
+	ctx := context.Background()
+	z := &hcloud.Zone{Name: "alpha.com"}
+	s.AddZone("alpha.com", 3600)
+	assert.Nil(t, s.SetRRSet("alpha.com", RRSet{Name: "www", Type: "A", TTL: 300, Records: []string{"127.0.0.1"}}))
+	assert.Nil(t, s.SetRRSet("alpha.com", RRSet{Name: "@", Type: "MX", Records: []string{"10 mail.alpha.com."}}))
+	expected, _ := s.RRSets("alpha.com")
+
+	res, _, err := client.Zone.ExportZonefile(ctx, z)
+	assert.Nil(t, err)
+	_, _, err = client.Zone.ImportZonefile(ctx, z, hcloud.ZoneImportZonefileOpts{Zonefile: res.Zonefile})
+	assert.Nil(t, err)
+	actual, _ := s.RRSets("alpha.com")
+	assert.Equal(t, expected, actual)
+}
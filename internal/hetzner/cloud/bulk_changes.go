@@ -19,10 +19,14 @@ package hetznercloud
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"external-dns-hetzner-webhook/internal/metrics"
+	"external-dns-hetzner-webhook/internal/tracing"
 	"external-dns-hetzner-webhook/internal/zonefile"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
@@ -38,25 +42,42 @@ type zoneChanges struct {
 	deletes []*hetznerChangeDelete
 }
 
+// zonefileBackups stores the zonefiles before they are replaced.
+type zonefileBackups interface {
+	Save(zone string, zf string) (string, error)
+}
+
 // bulkChanges contains all changes to apply to DNS using the bulk system. This
 // method exports the BIND zone file, applies the changes and re-uploads it,
-// therefore using always exactly two calls per zone.
+// therefore using two calls per zone, plus one more export for detecting
+// concurrent modifications of the zone. Since labels cannot be
+// stored in a zone file, the label changes are applied afterwards with one call
+// for each RRSet whose labels actually changed. If backups are configured, the
+// exported zonefile is saved before being replaced.
 type bulkChanges struct {
-	dnsClient apiClient
-	dryRun    bool
-	slash     string
-	zones     map[int64]*hcloud.Zone
-	changes   map[int64]*zoneChanges
+	dnsClient  apiClient
+	dryRun     bool
+	slash      string
+	defaults   defaultLabels
+	maxRetries int
+	backups    zonefileBackups
+	metrics    *metrics.OpenMetrics
+	zones      map[int64]*hcloud.Zone
+	changes    map[int64]*zoneChanges
 }
 
 // NewBulkChanges creates a new bulkChanges object.
-func NewBulkChanges(dnsClient apiClient, dryRun bool, slash string) *bulkChanges {
+func NewBulkChanges(dnsClient apiClient, dryRun bool, slash string, defaults defaultLabels, maxRetries int, backups zonefileBackups, m *metrics.OpenMetrics) *bulkChanges {
 	return &bulkChanges{
-		dnsClient: dnsClient,
-		dryRun:    dryRun,
-		slash:     slash,
-		zones:     make(map[int64]*hcloud.Zone, 0),
-		changes:   make(map[int64]*zoneChanges, 0),
+		dnsClient:  dnsClient,
+		dryRun:     dryRun,
+		slash:      slash,
+		defaults:   defaults,
+		maxRetries: maxRetries,
+		backups:    backups,
+		metrics:    m,
+		zones:      make(map[int64]*hcloud.Zone, 0),
+		changes:    make(map[int64]*zoneChanges, 0),
 	}
 }
 
@@ -71,6 +92,11 @@ func (c bulkChanges) GetSlash() (string, bool) {
 	return c.slash, true
 }
 
+// GetDefaultLabels returns the default labels applied to every RRSet.
+func (c bulkChanges) GetDefaultLabels() defaultLabels {
+	return c.defaults
+}
+
 // getZoneChanges returns or creates the appropriate zoneChanges object for the
 // zone.
 func (c *bulkChanges) getZoneChanges(zone *hcloud.Zone) *zoneChanges {
@@ -143,7 +169,7 @@ func decodeRecords(rrs []hcloud.ZoneRRSetRecord) []string {
 
 // createRecord adds a new recordset.
 func createRecord(z *zonefile.Zonefile, c *hetznerChangeCreate) {
-	log.WithFields(c.GetLogFields()).Debug("Planning record creation")
+	changesLog.WithFields(c.GetLogFields()).Debug("Planning record creation")
 	opts := c.opts
 	recType := string(opts.Type)
 	ttl := z.GetTTL()
@@ -154,7 +180,7 @@ func createRecord(z *zonefile.Zonefile, c *hetznerChangeCreate) {
 	recs := decodeRecords(opts.Records)
 	if err := z.AddRecord(recType, name, ttl, recs); err != nil {
 		zn, _ := strings.CutSuffix(z.GetOrigin(), ".")
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"zoneName":   zn,
 			"dnsName":    opts.Name,
 			"recordType": recType,
@@ -164,7 +190,7 @@ func createRecord(z *zonefile.Zonefile, c *hetznerChangeCreate) {
 
 // updateRecord updates a recordset.
 func updateRecord(z *zonefile.Zonefile, u *hetznerChangeUpdate) {
-	log.WithFields(u.GetLogFields()).Debug("Planning record update")
+	changesLog.WithFields(u.GetLogFields()).Debug("Planning record update")
 	rset := u.rrset
 	rOpts := u.recordsOpts
 	ttlOpts := u.ttlOpts
@@ -185,7 +211,7 @@ func updateRecord(z *zonefile.Zonefile, u *hetznerChangeUpdate) {
 
 	if err := z.UpdateRecord(recType, name, ttl, recs); err != nil {
 		zn, _ := strings.CutSuffix(z.GetOrigin(), ".")
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"zoneName":   zn,
 			"dnsName":    rset.Name,
 			"recordType": recType,
@@ -195,13 +221,13 @@ func updateRecord(z *zonefile.Zonefile, u *hetznerChangeUpdate) {
 
 // deleteRecord removes a recordset.
 func deleteRecord(z *zonefile.Zonefile, d *hetznerChangeDelete) {
-	log.WithFields(d.GetLogFields()).Debug("Planning record deletion")
+	changesLog.WithFields(d.GetLogFields()).Debug("Planning record deletion")
 	rset := d.rrset
 	recType := string(rset.Type)
 	name := rset.Name
 	if err := z.DeleteRecord(recType, name); err != nil {
 		zn, _ := strings.CutSuffix(z.GetOrigin(), ".")
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"zoneName":   zn,
 			"dnsName":    rset.Name,
 			"recordType": recType,
@@ -263,48 +289,208 @@ func (c bulkChanges) runZoneChanges(zone *hcloud.Zone, zf string) (string, error
 	return nzf, nil
 }
 
-// applyZoneChanges applies changes to a zone.
-func (c bulkChanges) applyZoneChanges(ctx context.Context, zone *hcloud.Zone) {
-	log.Debugf("Downloading zonefile from [%s]", zone.Name)
+// exportZonefile downloads the zonefile for a zone.
+func (c bulkChanges) exportZonefile(ctx context.Context, zone *hcloud.Zone) (string, error) {
+	changesLog.Debugf("Downloading zonefile from [%s]", zone.Name)
 	zfr, _, err := c.dnsClient.ExportZonefile(ctx, zone)
 	if err != nil {
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"zoneName": zone.Name,
 		}).Errorf("Error while downloading zonefile: %v", err)
-		return
+		return "", fmt.Errorf("cannot download zonefile for zone %s: %w", zone.Name, err)
 	}
-	nzf, err := c.runZoneChanges(zone, zfr.Zonefile)
+	return zfr.Zonefile, nil
+}
+
+// prepareZonefile applies the changes to the exported zonefile and returns
+// both the exported zonefile and the one to be imported. Before returning, the zonefile is exported
+// again and compared with the one the changes were applied to: if they differ,
+// the zone was modified concurrently and the changes are applied again to the
+// new version, up to maxRetries times. A negative maxRetries disables the
+// check.
+func (c bulkChanges) prepareZonefile(ctx context.Context, zone *hcloud.Zone) (string, string, error) {
+	zf, err := c.exportZonefile(ctx, zone)
 	if err != nil {
-		log.WithFields(log.Fields{
+		return "", "", err
+	}
+	for retries := 0; ; retries++ {
+		nzf, err := c.runZoneChanges(zone, zf)
+		if err != nil {
+			changesLog.WithFields(log.Fields{
+				"zoneName": zone.Name,
+			}).Errorf("Error while managing the zonefile: %v", err)
+			return "", "", fmt.Errorf("cannot apply changes to zonefile for zone %s: %w", zone.Name, err)
+		}
+		if c.maxRetries < 0 {
+			return zf, nzf, nil
+		}
+		current, err := c.exportZonefile(ctx, zone)
+		if err != nil {
+			return "", "", err
+		}
+		if zonefile.Fingerprint(current) == zonefile.Fingerprint(zf) {
+			return zf, nzf, nil
+		}
+		c.metrics.IncBulkConflictsTotal(zone.Name)
+		if retries >= c.maxRetries {
+			changesLog.WithFields(log.Fields{
+				"zoneName": zone.Name,
+			}).Errorf("Zone modified concurrently %d times, giving up.", retries+1)
+			return "", "", fmt.Errorf("zone %s was modified concurrently while applying changes (%d retries)", zone.Name, retries)
+		}
+		changesLog.WithFields(log.Fields{
 			"zoneName": zone.Name,
-		}).Errorf("Error while managing the zonefile: %v", err)
-		return
+		}).Warn("Zone modified concurrently, applying the changes again.")
+		zf = current
+	}
+}
+
+// backupZonefile saves the zonefile that is going to be replaced, if the
+// backups are enabled. The import must not proceed if the backup fails.
+func (c bulkChanges) backupZonefile(zone *hcloud.Zone, zf string) error {
+	if c.backups == nil {
+		return nil
+	}
+	name, err := c.backups.Save(zone.Name, zf)
+	if err != nil {
+		changesLog.WithFields(log.Fields{
+			"zoneName": zone.Name,
+		}).Errorf("Error while saving the zonefile backup: %v", err)
+		return fmt.Errorf("cannot save zonefile backup for zone %s: %w", zone.Name, err)
+	}
+	changesLog.Infof("Saved zonefile backup [%s] for zone [%s].", name, zone.Name)
+	return nil
+}
+
+// applyZoneChanges applies changes to a zone. In dry run mode the new
+// zonefile is prepared, but neither saved nor uploaded.
+func (c bulkChanges) applyZoneChanges(ctx context.Context, zone *hcloud.Zone) error {
+	zf, nzf, err := c.prepareZonefile(ctx, zone)
+	if err != nil {
+		return err
+	}
+	zc := c.changes[zone.ID]
+	if c.dryRun {
+		changesLog.Infof("Dry run: zonefile for zone [%s] not uploaded, with %d creations, %d updates and %d deletions.",
+			zone.Name, len(zc.creates), len(zc.updates), len(zc.deletes))
+		changesLog.Debugf("Zonefile not uploaded to [%s]:\n%s", zone.Name, nzf)
+		c.applyZoneLabels(ctx, zone, nil)
+		return nil
+	}
+	if err := c.backupZonefile(zone, zf); err != nil {
+		return err
 	}
 	opts := hcloud.ZoneImportZonefileOpts{
 		Zonefile: nzf,
 	}
-	log.Debugf("Uploading zonefile to [%s]", zone.Name)
-	_, _, err = c.dnsClient.ImportZonefile(ctx, zone, opts)
+	changesLog.Debugf("Uploading zonefile to [%s]", zone.Name)
+	action, _, err := c.dnsClient.ImportZonefile(ctx, zone, opts)
 	if err != nil {
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"zoneName": zone.Name,
 		}).Errorf("Error while uploading the zonefile: %v", err)
-		return
+		return fmt.Errorf("cannot upload zonefile for zone %s: %w", zone.Name, err)
 	}
-	zc := c.changes[zone.ID]
-	log.Infof("Uploaded zonefile for zone [%s] with %d creations, %d updates and %d deletions.",
+	changesLog.Infof("Uploaded zonefile for zone [%s] with %d creations, %d updates and %d deletions.",
 		zone.Name, len(zc.creates), len(zc.updates), len(zc.deletes))
+	countChanges(c.metrics, zone.Name, opCreate, len(zc.creates))
+	countChanges(c.metrics, zone.Name, opUpdate, len(zc.updates))
+	countChanges(c.metrics, zone.Name, opDelete, len(zc.deletes))
+	c.applyZoneLabels(ctx, zone, action)
+	return nil
+}
+
+// getLabelChanges returns the label updates that must be applied after the
+// zonefile import. Only the RRSets whose labels changed are returned.
+func (c bulkChanges) getLabelChanges(zone *hcloud.Zone) []*hetznerChangeUpdate {
+	zc, ok := c.changes[zone.ID]
+	if !ok {
+		return nil
+	}
+	labelChanges := make([]*hetznerChangeUpdate, 0)
+	for _, cc := range zc.creates {
+		if len(cc.opts.Labels) == 0 {
+			continue
+		}
+		// The API addresses an RRSet by zone, name and type, so no lookup is
+		// needed for the RRSets created by the import.
+		rrset := &hcloud.ZoneRRSet{
+			Zone: zone,
+			Name: cc.opts.Name,
+			Type: cc.opts.Type,
+		}
+		labelChanges = append(labelChanges, &hetznerChangeUpdate{
+			rrset:      rrset,
+			updateOpts: &hcloud.ZoneRRSetUpdateOpts{Labels: cc.opts.Labels},
+		})
+	}
+	for _, cu := range zc.updates {
+		if cu.updateOpts == nil {
+			continue
+		}
+		labelChanges = append(labelChanges, &hetznerChangeUpdate{
+			rrset:      cu.rrset,
+			updateOpts: cu.updateOpts,
+		})
+	}
+	return labelChanges
+}
+
+// applyZoneLabels updates the labels that could not be imported with the
+// zonefile. The import action must be completed before the labels are
+// updated, otherwise the new RRSets might not be available yet. In dry run
+// mode the label changes are only logged.
+func (c bulkChanges) applyZoneLabels(ctx context.Context, zone *hcloud.Zone, action *hcloud.Action) {
+	labelChanges := c.getLabelChanges(zone)
+	if len(labelChanges) == 0 {
+		return
+	}
+	for _, lc := range labelChanges {
+		changesLog.Infof("Updating labels for Name [%s], Type [%s] in zone [%s]: %s",
+			lc.rrset.Name, lc.rrset.Type, zone.Name, formatLabels(lc.updateOpts.Labels))
+	}
+	if c.dryRun {
+		return
+	}
+	if action != nil {
+		if err := c.dnsClient.WaitForAction(ctx, action); err != nil {
+			changesLog.WithFields(log.Fields{
+				"zoneName": zone.Name,
+			}).Errorf("Error while waiting for the zonefile import, labels not updated: %v", err)
+			return
+		}
+	}
+	updated := 0
+	for _, lc := range labelChanges {
+		if _, _, err := c.dnsClient.UpdateRRSetLabels(ctx, lc.rrset, *lc.updateOpts); err != nil {
+			changesLog.WithFields(lc.GetLogFields()).Errorf("Error while updating the labels: %v", err)
+			continue
+		}
+		updated++
+	}
+	changesLog.Infof("Updated labels for %d RRSets in zone [%s].", updated, zone.Name)
 }
 
 // ApplyChanges applies the planned changes.
 func (c bulkChanges) ApplyChanges(ctx context.Context) error {
 	// No changes = nothing to do.
 	if c.empty() {
-		log.Debug("No changes to be applied found.")
+		changesLog.Debug("No changes to be applied found.")
 		return nil
 	}
+	errs := make([]error, 0)
 	for _, z := range c.zones {
-		c.applyZoneChanges(ctx, z)
+		zc := c.changes[z.ID]
+		zctx, span := tracing.Start(ctx, "changes.bulk.zone",
+			tracing.AttrZone.String(z.Name),
+			tracing.AttrCreates.Int(len(zc.creates)),
+			tracing.AttrUpdates.Int(len(zc.updates)),
+			tracing.AttrDeletes.Int(len(zc.deletes)))
+		err := c.applyZoneChanges(zctx, z)
+		tracing.End(span, err)
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
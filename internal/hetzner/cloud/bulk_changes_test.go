@@ -26,6 +26,8 @@ import (
 	"testing"
 	"time"
 
+	"external-dns-hetzner-webhook/internal/fakeapi"
+	"external-dns-hetzner-webhook/internal/hetzner"
 	"external-dns-hetzner-webhook/internal/zonefile"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
@@ -2227,3 +2229,408 @@ func Test_bulkChanges_ApplyChanges(t *testing.T) {
 		})
 	}
 }
+
+// Test_bulkChanges_getLabelChanges tests bulkChanges.getLabelChanges().
+func Test_bulkChanges_getLabelChanges(t *testing.T) {
+	zone := &hcloud.Zone{ID: 1, Name: "alpha.com"}
+	rrset := &hcloud.ZoneRRSet{
+		Zone: zone,
+		ID:   "www/A",
+		Name: "www",
+		Type: hcloud.ZoneRRSetTypeA,
+	}
+	obj := bulkChanges{
+		zones: map[int64]*hcloud.Zone{1: zone},
+		changes: map[int64]*zoneChanges{
+			1: {
+				creates: []*hetznerChangeCreate{
+					{
+						zone: zone,
+						opts: hcloud.ZoneRRSetCreateOpts{
+							Name:   "ftp",
+							Type:   hcloud.ZoneRRSetTypeA,
+							Labels: map[string]string{"env": "test"},
+						},
+					},
+					{
+						zone: zone,
+						opts: hcloud.ZoneRRSetCreateOpts{
+							Name: "mail",
+							Type: hcloud.ZoneRRSetTypeA,
+						},
+					},
+				},
+				updates: []*hetznerChangeUpdate{
+					{
+						rrset:   rrset,
+						ttlOpts: &hcloud.ZoneRRSetChangeTTLOpts{TTL: &ttl3600},
+					},
+					{
+						rrset:      rrset,
+						updateOpts: &hcloud.ZoneRRSetUpdateOpts{Labels: map[string]string{"env": "prod"}},
+					},
+				},
+			},
+		},
+	}
+	expected := []*hetznerChangeUpdate{
+		{
+			rrset: &hcloud.ZoneRRSet{
+				Zone: zone,
+				Name: "ftp",
+				Type: hcloud.ZoneRRSetTypeA,
+			},
+			updateOpts: &hcloud.ZoneRRSetUpdateOpts{Labels: map[string]string{"env": "test"}},
+		},
+		{
+			rrset:      rrset,
+			updateOpts: &hcloud.ZoneRRSetUpdateOpts{Labels: map[string]string{"env": "prod"}},
+		},
+	}
+
+	t.Run("only changed labels", func(t *testing.T) {
+		assert.Equal(t, expected, obj.getLabelChanges(zone))
+	})
+	t.Run("zone without changes", func(t *testing.T) {
+		assert.Nil(t, obj.getLabelChanges(&hcloud.Zone{ID: 2, Name: "beta.com"}))
+	})
+}
+
+// Test_bulkChanges_applyZoneLabels tests bulkChanges.applyZoneLabels().
+func Test_bulkChanges_applyZoneLabels(t *testing.T) {
+	type testCase struct {
+		name      string
+		inpClient mockClient
+		dryRun    bool
+		labels    map[string]string
+		expState  mockClientState
+		expCalls  int
+	}
+
+	zone := &hcloud.Zone{ID: 1, Name: "alpha.com"}
+
+	run := func(t *testing.T, tc testCase) {
+		client := tc.inpClient
+		obj := bulkChanges{
+			dnsClient: &client,
+			dryRun:    tc.dryRun,
+			zones:     map[int64]*hcloud.Zone{1: zone},
+			changes: map[int64]*zoneChanges{
+				1: {
+					creates: []*hetznerChangeCreate{
+						{
+							zone: zone,
+							opts: hcloud.ZoneRRSetCreateOpts{
+								Name:   "ftp",
+								Type:   hcloud.ZoneRRSetTypeA,
+								Labels: tc.labels,
+							},
+						},
+					},
+				},
+			},
+		}
+		obj.applyZoneLabels(context.Background(), zone, &hcloud.Action{ID: 1})
+		assert.Equal(t, tc.expState, client.state)
+		assert.Len(t, client.args.UpdateRRSetLabels, tc.expCalls)
+	}
+
+	testCases := []testCase{
+		{
+			name:     "no label changes",
+			expState: mockClientState{},
+		},
+		{
+			name:   "labels updated",
+			labels: map[string]string{"env": "test"},
+			expState: mockClientState{
+				WaitForActionCalled:     true,
+				UpdateRRSetLabelsCalled: true,
+			},
+			expCalls: 1,
+		},
+		{
+			name:     "dry run",
+			dryRun:   true,
+			labels:   map[string]string{"env": "test"},
+			expState: mockClientState{},
+		},
+		{
+			name: "import action failed",
+			inpClient: mockClient{
+				waitForAction: errors.New("import failed"),
+			},
+			labels: map[string]string{"env": "test"},
+			expState: mockClientState{
+				WaitForActionCalled: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_bulkChanges_applyZoneLabels_api tests that the labels of the RRSets
+// created by the zonefile import are updated through the API.
+func Test_bulkChanges_applyZoneLabels_api(t *testing.T) {
+	fake := fakeapi.NewServer("TEST_API_KEY")
+	defer fake.Close()
+	fake.AddZone("alpha.com", 3600)
+	client, err := NewHetznerCloud(hetzner.Project{APIKey: "TEST_API_KEY", Endpoint: fake.URL}, nil)
+	assert.Nil(t, err)
+	zones, _, err := client.GetZones(context.Background(), hcloud.ZoneListOpts{})
+	assert.Nil(t, err)
+
+	obj := NewBulkChanges(client, false, "--slash--", nil, 0, nil, nil)
+	obj.AddChangeCreate(zones[0], hcloud.ZoneRRSetCreateOpts{
+		Name:    "www",
+		Type:    hcloud.ZoneRRSetTypeA,
+		Labels:  map[string]string{"env": "test"},
+		Records: []hcloud.ZoneRRSetRecord{{Value: "127.0.0.1"}},
+	})
+	assert.Nil(t, obj.ApplyChanges(context.Background()))
+
+	rrsets, err := fake.RRSets("alpha.com")
+	assert.Nil(t, err)
+	idx := slices.IndexFunc(rrsets, func(r fakeapi.RRSet) bool { return r.Name == "www" && r.Type == "A" })
+	if assert.GreaterOrEqual(t, idx, 0) {
+		assert.Equal(t, map[string]string{"env": "test"}, rrsets[idx].Labels)
+	}
+}
+
+// Test_bulkChanges_prepareZonefile tests bulkChanges.prepareZonefile().
+func Test_bulkChanges_prepareZonefile(t *testing.T) {
+	type testCase struct {
+		name       string
+		exports    []exportZonefileResponse
+		maxRetries int
+		expected   struct {
+			records []string
+			exports int
+			err     error
+		}
+	}
+
+	zone := &hcloud.Zone{ID: 1, Name: "fastipletonis.eu", TTL: 3600}
+	modifiedZoneFile := inputZoneFile + "mail\t3600\tIN\tA\t116.202.181.4\n"
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		client := &mockClient{exportZonefileSeq: tc.exports}
+		obj := NewBulkChanges(client, false, "--slash--", nil, tc.maxRetries, nil, nil)
+		obj.AddChangeCreate(zone, hcloud.ZoneRRSetCreateOpts{
+			Name:    "ftp",
+			Type:    hcloud.ZoneRRSetTypeA,
+			TTL:     &ttl3600,
+			Records: []hcloud.ZoneRRSetRecord{{Value: "116.202.181.1"}},
+		})
+		_, zf, err := obj.prepareZonefile(context.Background(), zone)
+		assert.Equal(t, exp.exports, client.exportZonefileNum)
+		if !assertError(t, exp.err, err) {
+			for _, rec := range exp.records {
+				assert.Contains(t, zf, rec)
+			}
+		}
+	}
+
+	export := func(zf string) exportZonefileResponse {
+		return exportZonefileResponse{
+			result: hcloud.ZoneExportZonefileResult{Zonefile: zf},
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name:       "no concurrent modification",
+			exports:    []exportZonefileResponse{export(inputZoneFile), export(inputZoneFile)},
+			maxRetries: 2,
+			expected: struct {
+				records []string
+				exports int
+				err     error
+			}{
+				records: []string{"ftp.fastipletonis.eu.\t3600\tIN\tA\t116.202.181.1"},
+				exports: 2,
+			},
+		},
+		{
+			name: "concurrent modification merged",
+			exports: []exportZonefileResponse{
+				export(inputZoneFile),
+				export(modifiedZoneFile),
+				export(modifiedZoneFile),
+			},
+			maxRetries: 2,
+			expected: struct {
+				records []string
+				exports int
+				err     error
+			}{
+				records: []string{
+					"ftp.fastipletonis.eu.\t3600\tIN\tA\t116.202.181.1",
+					"mail.fastipletonis.eu.\t3600\tIN\tA\t116.202.181.4",
+				},
+				exports: 3,
+			},
+		},
+		{
+			name: "too many concurrent modifications",
+			exports: []exportZonefileResponse{
+				export(inputZoneFile),
+				export(modifiedZoneFile),
+			},
+			maxRetries: 0,
+			expected: struct {
+				records []string
+				exports int
+				err     error
+			}{
+				exports: 2,
+				err:     errors.New("zone fastipletonis.eu was modified concurrently while applying changes (0 retries)"),
+			},
+		},
+		{
+			name:       "check disabled",
+			exports:    []exportZonefileResponse{export(inputZoneFile)},
+			maxRetries: -1,
+			expected: struct {
+				records []string
+				exports int
+				err     error
+			}{
+				records: []string{"ftp.fastipletonis.eu.\t3600\tIN\tA\t116.202.181.1"},
+				exports: 1,
+			},
+		},
+		{
+			name: "second export error",
+			exports: []exportZonefileResponse{
+				export(inputZoneFile),
+				{err: errors.New("export error")},
+			},
+			maxRetries: 2,
+			expected: struct {
+				records []string
+				exports int
+				err     error
+			}{
+				exports: 2,
+				err:     errors.New("cannot download zonefile for zone fastipletonis.eu: export error"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// mockBackups simulates the zonefile backups.
+type mockBackups struct {
+	err   error
+	saved map[string]string
+}
+
+// Save records the zonefile saved for the zone.
+func (m *mockBackups) Save(zone string, zf string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if m.saved == nil {
+		m.saved = make(map[string]string)
+	}
+	m.saved[zone] = zf
+	return "backup.zone", nil
+}
+
+// Test_bulkChanges_backupZonefile tests bulkChanges.backupZonefile().
+func Test_bulkChanges_backupZonefile(t *testing.T) {
+	type testCase struct {
+		name     string
+		backups  *mockBackups
+		expected struct {
+			saved map[string]string
+			err   error
+		}
+	}
+
+	zone := &hcloud.Zone{ID: 1, Name: "fastipletonis.eu", TTL: 3600}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		obj := NewBulkChanges(&mockClient{}, false, "--slash--", nil, 0, nil, nil)
+		if tc.backups != nil {
+			obj.backups = tc.backups
+		}
+		err := obj.backupZonefile(zone, inputZoneFile)
+		if !assertError(t, exp.err, err) && tc.backups != nil {
+			assert.Equal(t, exp.saved, tc.backups.saved)
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name: "backups disabled",
+		},
+		{
+			name:    "backup saved",
+			backups: &mockBackups{},
+			expected: struct {
+				saved map[string]string
+				err   error
+			}{
+				saved: map[string]string{"fastipletonis.eu": inputZoneFile},
+			},
+		},
+		{
+			name:    "backup error",
+			backups: &mockBackups{err: errors.New("test backup error")},
+			expected: struct {
+				saved map[string]string
+				err   error
+			}{
+				err: errors.New("cannot save zonefile backup for zone fastipletonis.eu: test backup error"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_bulkChanges_applyZoneChanges_backup tests that the zonefile is not
+// imported when the backup fails.
+func Test_bulkChanges_applyZoneChanges_backup(t *testing.T) {
+	zone := &hcloud.Zone{ID: 1, Name: "fastipletonis.eu", TTL: 3600}
+	client := &mockClient{
+		exportZonefile: exportZonefileResponse{
+			result: hcloud.ZoneExportZonefileResult{Zonefile: inputZoneFile},
+		},
+	}
+	backups := &mockBackups{err: errors.New("test backup error")}
+	obj := NewBulkChanges(client, false, "--slash--", nil, 0, backups, nil)
+	obj.AddChangeCreate(zone, hcloud.ZoneRRSetCreateOpts{
+		Name:    "ftp",
+		Type:    hcloud.ZoneRRSetTypeA,
+		TTL:     &ttl3600,
+		Records: []hcloud.ZoneRRSetRecord{{Value: "116.202.181.1"}},
+	})
+	err := obj.applyZoneChanges(context.Background(), zone)
+	assert.EqualError(t, err, "cannot save zonefile backup for zone fastipletonis.eu: test backup error")
+	assert.False(t, client.state.ImportZonefileCalled)
+
+	backups.err = nil
+	err = obj.applyZoneChanges(context.Background(), zone)
+	assert.Nil(t, err)
+	assert.True(t, client.state.ImportZonefileCalled)
+	assert.Equal(t, inputZoneFile, backups.saved["fastipletonis.eu"])
+}
@@ -53,7 +53,7 @@ func adjustCNAMETarget(domain string, target string) string {
 func adjustMXTarget(domain string, target string) string {
 	parts := strings.SplitN(target, " ", 2)
 	if len(parts) != 2 {
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"target": target,
 		}).Warn("MX target has invalid format (expected 'priority hostname')")
 		return target
@@ -63,7 +63,7 @@ func adjustMXTarget(domain string, target string) string {
 
 	// Validate priority is numeric
 	if _, err := strconv.Atoi(priority); err != nil {
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"target":   target,
 			"priority": priority,
 		}).Warn("MX priority is not a valid integer")
@@ -104,7 +104,7 @@ func processCreateActionsByZone(zone *hcloud.Zone, rrsets []*hcloud.ZoneRRSet, e
 	for _, ep := range endpoints {
 		// If there is an existing record we refuse to act.
 		if matchingRRSet, _ := getMatchingDomainRRSet(rrsets, zoneName, ep); matchingRRSet != nil {
-			log.WithFields(log.Fields{
+			changesLog.WithFields(log.Fields{
 				"zoneName":   zoneName,
 				"dnsName":    ep.DNSName,
 				"recordType": ep.RecordType,
@@ -112,26 +112,28 @@ func processCreateActionsByZone(zone *hcloud.Zone, rrsets []*hcloud.ZoneRRSet, e
 		} else {
 			var err error
 			var labels map[string]string
+			name := makeEndpointName(zoneName, ep.DNSName)
+			defaults := changes.GetDefaultLabels().render(zoneName, ep.RecordType, name)
 			slash, labelsSupported := changes.GetSlash()
 			if labels, err = getHetznerLabels(slash, ep); err != nil {
-				log.WithFields(log.Fields{
+				changesLog.WithFields(log.Fields{
 					"zoneName":   zoneName,
 					"dnsName":    ep.DNSName,
 					"recordType": ep.RecordType,
 				}).Warnf("Labels will be ignored due to a parsing error: %s", err.Error())
 			} else if !labelsSupported && len(labels) > 0 {
-				log.WithFields(log.Fields{
+				changesLog.WithFields(log.Fields{
 					"zoneName":   zoneName,
 					"dnsName":    ep.DNSName,
 					"recordType": ep.RecordType,
-				}).Warn("Labels are ignored in BULK_MODE.")
+				}).Warn("Labels are not supported by the current changes runner and will be ignored.")
 			}
 			opts := hcloud.ZoneRRSetCreateOpts{
-				Name:    makeEndpointName(zoneName, ep.DNSName),
+				Name:    name,
 				Type:    hcloud.ZoneRRSetType(ep.RecordType),
 				TTL:     getEndpointTTL(ep),
 				Records: extractRRSetRecords(zoneName, ep),
-				Labels:  labels,
+				Labels:  mergeLabels(defaults, labels),
 			}
 			changes.AddChangeCreate(zone, opts)
 		}
@@ -147,9 +149,9 @@ func processCreateActions(
 ) {
 	// Process endpoints that need to be created.
 	for zoneID, endpoints := range createsByZoneID {
-		zone := zoneIDNameMapper[zoneID]
+		zone := zoneIDNameMapper[zoneID].zone
 		if len(endpoints) == 0 {
-			log.WithFields(log.Fields{
+			changesLog.WithFields(log.Fields{
 				"zoneName": zone.Name,
 			}).Debug("Skipping domain, no creates found.")
 			continue
@@ -245,23 +247,25 @@ func processUpdateEndpoint(mRRSet *hcloud.ZoneRRSet, ep *endpoint.Endpoint, chan
 	}
 
 	slash, labelsSupported := changes.GetSlash()
-	// Check if we need to update the labels
+	// Check if we need to update the labels. The default labels are merged
+	// with the endpoint ones, so that they are kept in line.
+	defaults := changes.GetDefaultLabels().render(zoneName, string(mRRSet.Type), mRRSet.Name)
 	labels, err := getHetznerLabels(slash, ep)
 
 	if err != nil {
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"zoneName":   zoneName,
 			"dnsName":    ep.DNSName,
 			"recordType": ep.RecordType,
 		}).Warnf("Labels will be ignored for a parsing error: %s", err.Error())
 	} else if !labelsSupported && len(labels) > 0 {
-		log.WithFields(log.Fields{
+		changesLog.WithFields(log.Fields{
 			"zoneName":   zoneName,
 			"dnsName":    ep.DNSName,
 			"recordType": ep.RecordType,
-		}).Warn("Labels are ignored in BULK_MODE.")
-	} else if !equalStringMaps(labels, mRRSet.Labels) {
-		log.Debugf("Updating labels to %s", formatLabels(labels))
+		}).Warn("Labels are not supported by the current changes runner and will be ignored.")
+	} else if labels = mergeLabels(defaults, labels); !equalStringMaps(labels, mRRSet.Labels) {
+		changesLog.Debugf("Updating labels to %s", formatLabels(labels))
 		updateOpts = &hcloud.ZoneRRSetUpdateOpts{
 			Labels: labels,
 		}
@@ -276,7 +280,7 @@ func processUpdateActionsByZone(zone *hcloud.Zone, rrsets []*hcloud.ZoneRRSet, e
 	for _, ep := range endpoints {
 		mRRSet, found := getMatchingDomainRRSet(rrsets, zoneName, ep)
 		if !found {
-			log.WithFields(log.Fields{
+			changesLog.WithFields(log.Fields{
 				"zoneName":   zoneName,
 				"dnsName":    ep.DNSName,
 				"recordType": ep.RecordType,
@@ -298,10 +302,10 @@ func processUpdateActions(
 ) {
 	// Generate creates and updates based on existing
 	for zoneID, endpoints := range updatesByZoneID {
-		zone := zoneIDNameMapper[zoneID]
+		zone := zoneIDNameMapper[zoneID].zone
 		zoneName := zone.Name
 		if len(endpoints) == 0 {
-			log.WithFields(log.Fields{
+			changesLog.WithFields(log.Fields{
 				"zoneName": zoneName,
 			}).Debug("Skipping Zone, no updates found.")
 			continue
@@ -318,7 +322,7 @@ func processDeleteActionsByZone(zone *hcloud.Zone, rrsets []*hcloud.ZoneRRSet, e
 	for _, ep := range endpoints {
 		mRRSet, found := getMatchingDomainRRSet(rrsets, zoneName, ep)
 		if !found {
-			log.WithFields(log.Fields{
+			changesLog.WithFields(log.Fields{
 				"zoneName":   zoneName,
 				"dnsName":    ep.DNSName,
 				"recordType": ep.RecordType,
@@ -339,10 +343,10 @@ func processDeleteActions(
 	changes changesRunner,
 ) {
 	for zoneID, endpoints := range deletesByZoneID {
-		zone := zoneIDNameMapper[zoneID]
+		zone := zoneIDNameMapper[zoneID].zone
 		zoneName := zone.Name
 		if len(endpoints) == 0 {
-			log.WithFields(log.Fields{
+			changesLog.WithFields(log.Fields{
 				"zoneName": zoneName,
 			}).Debug("Skipping Zone, no deletes found.")
 			continue
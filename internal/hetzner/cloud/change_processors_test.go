@@ -528,14 +528,14 @@ func Test_processCreateActions(t *testing.T) {
 				createsByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -568,14 +568,14 @@ func Test_processCreateActions(t *testing.T) {
 				createsByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -610,14 +610,14 @@ func Test_processCreateActions(t *testing.T) {
 				createsByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -659,14 +659,14 @@ func Test_processCreateActions(t *testing.T) {
 				createsByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -1483,6 +1483,66 @@ func Test_processUpdateEndpoint(t *testing.T) {
 	}
 }
 
+// Test_defaultLabelsProcessing tests that the default labels are merged with
+// the endpoint labels on creates and updates.
+func Test_defaultLabelsProcessing(t *testing.T) {
+	defaults, err := newDefaultLabels([]string{"cluster=c1", "zone={{.Zone}}"})
+	assert.Nil(t, err)
+	zone := &hcloud.Zone{
+		ID:   1,
+		Name: "alpha.com",
+	}
+
+	t.Run("labels added on create", func(t *testing.T) {
+		changes := &hetznerChanges{defaults: defaults}
+		endpoints := []*endpoint.Endpoint{
+			{
+				DNSName:    "www.alpha.com",
+				Targets:    endpoint.Targets{"1.1.1.1"},
+				RecordType: "A",
+				ProviderSpecific: endpoint.ProviderSpecific{
+					{Name: "webhook/hetzner-label-cluster", Value: "c2"},
+				},
+			},
+		}
+		processCreateActionsByZone(zone, nil, endpoints, changes)
+		assert.Len(t, changes.creates, 1)
+		assert.Equal(t, map[string]string{
+			"cluster": "c2",
+			"zone":    "alpha.com",
+		}, changes.creates[0].opts.Labels)
+	})
+
+	t.Run("labels kept in line on update", func(t *testing.T) {
+		changes := &hetznerChanges{defaults: defaults}
+		rrset := &hcloud.ZoneRRSet{
+			Zone:    zone,
+			ID:      "id_1",
+			Type:    "A",
+			Name:    "www",
+			TTL:     &testTTL,
+			Records: []hcloud.ZoneRRSetRecord{{Value: "1.1.1.1"}},
+			Labels:  map[string]string{"cluster": "c1"},
+		}
+		ep := &endpoint.Endpoint{
+			DNSName:    "www.alpha.com",
+			Targets:    endpoint.Targets{"1.1.1.1"},
+			RecordType: "A",
+			RecordTTL:  endpoint.TTL(testTTL),
+		}
+		processUpdateEndpoint(rrset, ep, changes)
+		assert.Len(t, changes.updates, 1)
+		assert.Nil(t, changes.updates[0].ttlOpts)
+		assert.Nil(t, changes.updates[0].recordsOpts)
+		assert.Equal(t, &hcloud.ZoneRRSetUpdateOpts{
+			Labels: map[string]string{
+				"cluster": "c1",
+				"zone":    "alpha.com",
+			},
+		}, changes.updates[0].updateOpts)
+	})
+}
+
 // Test_processUpdateActionsByZone tests processUpdateActionsByZone().
 func Test_processUpdateActionsByZone(t *testing.T) {
 	type testCase struct {
@@ -1679,14 +1739,14 @@ func Test_processUpdateActions(t *testing.T) {
 				updatesByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -1737,14 +1797,14 @@ func Test_processUpdateActions(t *testing.T) {
 				updatesByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -1798,14 +1858,14 @@ func Test_processUpdateActions(t *testing.T) {
 				updatesByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -2010,14 +2070,14 @@ func Test_processDeleteActions(t *testing.T) {
 				deletesByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: {
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: {
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -2095,14 +2155,14 @@ func Test_processDeleteActions(t *testing.T) {
 				deletesByZoneID  map[int64][]*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: {
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: {
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				rrSetsByZoneID: map[int64][]*hcloud.ZoneRRSet{
 					1: {
@@ -20,15 +20,33 @@ package hetznercloud
 import (
 	"context"
 
+	"external-dns-hetzner-webhook/internal/metrics"
+	"external-dns-hetzner-webhook/internal/tracing"
+
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
-	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// Operation constants used for the change metrics.
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+// countChanges adds the changes applied to a zone to the change metrics.
+func countChanges(m *metrics.OpenMetrics, zone, operation string, num int) {
+	if num > 0 {
+		m.AddRecordChangesTotal(zone, operation, num)
+	}
+}
+
 // hetznerChange contains all changes to apply to DNS.
 type hetznerChanges struct {
 	dnsClient apiClient
 	dryRun    bool
 	slash     string
+	defaults  defaultLabels
+	metrics   *metrics.OpenMetrics
 
 	creates []*hetznerChangeCreate
 	updates []*hetznerChangeUpdate
@@ -36,11 +54,13 @@ type hetznerChanges struct {
 }
 
 // NewHetznerChanges creates a new hetznerChanges object.
-func NewHetznerChanges(dnsClient apiClient, dryRun bool, slash string) *hetznerChanges {
+func NewHetznerChanges(dnsClient apiClient, dryRun bool, slash string, defaults defaultLabels, m *metrics.OpenMetrics) *hetznerChanges {
 	return &hetznerChanges{
 		dnsClient: dnsClient,
 		dryRun:    dryRun,
 		slash:     slash,
+		defaults:  defaults,
+		metrics:   m,
 	}
 }
 
@@ -55,6 +75,11 @@ func (c hetznerChanges) GetSlash() (string, bool) {
 	return c.slash, true
 }
 
+// GetDefaultLabels returns the default labels applied to every RRSet.
+func (c hetznerChanges) GetDefaultLabels() defaultLabels {
+	return c.defaults
+}
+
 // AddChangeCreate adds a new creation entry to the current object.
 func (c *hetznerChanges) AddChangeCreate(zone *hcloud.Zone, opts hcloud.ZoneRRSetCreateOpts) {
 	changeCreate := &hetznerChangeCreate{
@@ -87,14 +112,15 @@ func (c *hetznerChanges) AddChangeDelete(rrset *hcloud.ZoneRRSet) {
 func (c hetznerChanges) applyDeletes(ctx context.Context) error {
 	client := c.dnsClient
 	for _, e := range c.deletes {
-		log.WithFields(e.GetLogFields()).Debug("Deleting domain record")
-		log.Infof("Deleting record [%s] of type [%s] from zone [%s]", e.rrset.Name, e.rrset.Type, e.rrset.Zone.Name)
+		changesLog.WithFields(e.GetLogFields()).Debug("Deleting domain record")
+		changesLog.Infof("Deleting record [%s] of type [%s] from zone [%s]", e.rrset.Name, e.rrset.Type, e.rrset.Zone.Name)
 		if c.dryRun {
 			continue
 		}
 		if _, _, err := client.DeleteRRSet(ctx, e.rrset); err != nil {
 			return err
 		}
+		countChanges(c.metrics, e.rrset.Zone.Name, opDelete, 1)
 	}
 	return nil
 }
@@ -109,8 +135,8 @@ func (c hetznerChanges) applyCreates(ctx context.Context) error {
 			ttl := zone.TTL
 			opts.TTL = &ttl
 		}
-		log.WithFields(e.GetLogFields()).Debug("Creating domain record")
-		log.Infof("Creating record [%s] of type [%s] with records(%s) in zone [%s]",
+		changesLog.WithFields(e.GetLogFields()).Debug("Creating domain record")
+		changesLog.Infof("Creating record [%s] of type [%s] with records(%s) in zone [%s]",
 			opts.Name, opts.Type, getRRSetRecordsString(opts.Records), zone.Name)
 		if c.dryRun {
 			continue
@@ -118,6 +144,7 @@ func (c hetznerChanges) applyCreates(ctx context.Context) error {
 		if _, _, err := client.CreateRRSet(ctx, zone, opts); err != nil {
 			return err
 		}
+		countChanges(c.metrics, zone.Name, opCreate, 1)
 	}
 	return nil
 }
@@ -130,9 +157,9 @@ func (c hetznerChanges) applyUpdates(ctx context.Context) error {
 		recordOpts := e.recordsOpts
 		ttlOpts := e.ttlOpts
 		updateOpts := e.updateOpts
-		log.WithFields(e.GetLogFields()).Debug("Updating domain record")
+		changesLog.WithFields(e.GetLogFields()).Debug("Updating domain record")
 		if recordOpts != nil {
-			log.Infof("Updating recordset for ID [%s], Name [%s], Type [%s] in zone [%s]: %s",
+			changesLog.Infof("Updating recordset for ID [%s], Name [%s], Type [%s] in zone [%s]: %s",
 				rrset.ID, rrset.Name, rrset.Type, rrset.Zone.Name, getRRSetRecordsString(recordOpts.Records))
 			if c.dryRun {
 				continue
@@ -146,7 +173,7 @@ func (c hetznerChanges) applyUpdates(ctx context.Context) error {
 				ttl := rrset.Zone.TTL
 				ttlOpts.TTL = &ttl
 			}
-			log.Infof("Updating TTL for ID [%s], Name [%s], Type [%s] in zone [%s]: %d",
+			changesLog.Infof("Updating TTL for ID [%s], Name [%s], Type [%s] in zone [%s]: %d",
 				rrset.ID, rrset.Name, rrset.Type, rrset.Zone.Name, *ttlOpts.TTL)
 			if c.dryRun {
 				continue
@@ -157,7 +184,7 @@ func (c hetznerChanges) applyUpdates(ctx context.Context) error {
 		}
 		if updateOpts != nil {
 			logLabels := formatLabels(updateOpts.Labels)
-			log.Infof("Updating labels for ID [%s], Name [%s], Type [%s] in zone [%s]: %s",
+			changesLog.Infof("Updating labels for ID [%s], Name [%s], Type [%s] in zone [%s]: %s",
 				rrset.ID, rrset.Name, rrset.Type, rrset.Zone.Name, logLabels)
 			if c.dryRun {
 				continue
@@ -166,17 +193,25 @@ func (c hetznerChanges) applyUpdates(ctx context.Context) error {
 				return err
 			}
 		}
+		if !c.dryRun {
+			countChanges(c.metrics, rrset.Zone.Name, opUpdate, 1)
+		}
 	}
 	return nil
 }
 
 // ApplyChanges applies the planned changes using dnsClient.
-func (c hetznerChanges) ApplyChanges(ctx context.Context) error {
+func (c hetznerChanges) ApplyChanges(ctx context.Context) (err error) {
 	// No changes = nothing to do.
 	if c.empty() {
-		log.Debug("No changes to be applied found.")
+		changesLog.Debug("No changes to be applied found.")
 		return nil
 	}
+	ctx, span := tracing.Start(ctx, "changes.single",
+		tracing.AttrCreates.Int(len(c.creates)),
+		tracing.AttrUpdates.Int(len(c.updates)),
+		tracing.AttrDeletes.Int(len(c.deletes)))
+	defer func() { tracing.End(span, err) }()
 	// Process records to be deleted.
 	if err := c.applyDeletes(ctx); err != nil {
 		return err
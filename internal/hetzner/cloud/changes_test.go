@@ -1196,3 +1196,18 @@ func Test_hetznerChanges_ApplyChanges(t *testing.T) {
 		})
 	}
 }
+
+// Test_countChanges tests countChanges().
+func Test_countChanges(t *testing.T) {
+	m := newTestMetrics(t)
+	labels := map[string]string{"zone": "alpha.com", "operation": opCreate}
+
+	countChanges(m, "alpha.com", opCreate, 0)
+	assert.Equal(t, float64(-1), metricValue(t, m, "record_changes_total", labels))
+
+	countChanges(m, "alpha.com", opCreate, 2)
+	assert.Equal(t, float64(2), metricValue(t, m, "record_changes_total", labels))
+
+	// No metrics are collected without an instance.
+	countChanges(nil, "alpha.com", opCreate, 2)
+}
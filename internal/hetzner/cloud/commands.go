@@ -0,0 +1,128 @@
+/*
+ * Commands - zone operations for the command line.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// ZoneSummary describes a zone listed from the command line.
+type ZoneSummary struct {
+	// Zone ID
+	ID int64 `json:"id"`
+	// Zone name
+	Name string `json:"name"`
+	// Default TTL of the zone
+	TTL int `json:"ttl"`
+	// Zone mode: primary or secondary
+	Mode string `json:"mode"`
+	// Zone status
+	Status string `json:"status"`
+	// Number of records in the zone
+	RecordCount int `json:"recordCount"`
+}
+
+// ListZones returns the zones that match the domain filter, sorted by name.
+func (p *HetznerProvider) ListZones(ctx context.Context) ([]ZoneSummary, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]ZoneSummary, 0, len(zones))
+	for _, zone := range zones {
+		summaries = append(summaries, ZoneSummary{
+			ID:          zone.ID,
+			Name:        zone.Name,
+			TTL:         zone.TTL,
+			Mode:        string(zone.Mode),
+			Status:      string(zone.Status),
+			RecordCount: zone.RecordCount,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries, nil
+}
+
+// findManagedZone returns the zone with the given name, if it matches the
+// domain filter.
+func (p *HetznerProvider) findManagedZone(ctx context.Context, zoneName string) (*hcloud.Zone, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range zones {
+		if zone.Name == zoneName {
+			return zone, nil
+		}
+	}
+	return nil, fmt.Errorf("zone %s not found or not matching the domain filter", zoneName)
+}
+
+// ZoneRecords returns the records of a zone as a slice of endpoints, as
+// returned to ExternalDNS.
+func (p *HetznerProvider) ZoneRecords(ctx context.Context, zoneName string) ([]*endpoint.Endpoint, error) {
+	zone, err := p.findManagedZone(ctx, zoneName)
+	if err != nil {
+		return nil, err
+	}
+	return p.zoneEndpoints(ctx, zone)
+}
+
+// ExportZonefile returns the zonefile of a zone.
+func (p *HetznerProvider) ExportZonefile(ctx context.Context, zoneName string) (string, error) {
+	zone, err := p.findManagedZone(ctx, zoneName)
+	if err != nil {
+		return "", err
+	}
+	result, _, err := p.client.ExportZonefile(ctx, zone)
+	if err != nil {
+		return "", fmt.Errorf("cannot export zonefile for zone %s: %w", zoneName, err)
+	}
+	return result.Zonefile, nil
+}
+
+// ImportZonefile replaces the records of a zone with the ones of the zonefile
+// and waits for the import to complete. Nothing is imported in dry run mode.
+func (p *HetznerProvider) ImportZonefile(ctx context.Context, zoneName, zf string) error {
+	zone, err := p.findManagedZone(ctx, zoneName)
+	if err != nil {
+		return err
+	}
+	if p.dryRun {
+		zonefileLog.Infof("Dry run: zonefile for zone [%s] not imported.", zoneName)
+		return nil
+	}
+	action, _, err := p.client.ImportZonefile(ctx, zone, hcloud.ZoneImportZonefileOpts{Zonefile: zf})
+	if err != nil {
+		return fmt.Errorf("cannot upload zonefile for zone %s: %w", zoneName, err)
+	}
+	if action != nil {
+		if err := p.client.WaitForAction(ctx, action); err != nil {
+			return fmt.Errorf("error while importing zonefile for zone %s: %w", zoneName, err)
+		}
+	}
+	zonefileLog.Infof("Imported zonefile for zone [%s].", zoneName)
+	return nil
+}
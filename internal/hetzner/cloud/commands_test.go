@@ -0,0 +1,271 @@
+/*
+ * Commands - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// singlePageResponse returns a response with a single page of entries.
+func singlePageResponse(entries int) *hcloud.Response {
+	return &hcloud.Response{
+		Response: &http.Response{StatusCode: http.StatusOK},
+		Meta: hcloud.Meta{
+			Pagination: &hcloud.Pagination{
+				Page:         1,
+				PerPage:      100,
+				LastPage:     1,
+				TotalEntries: entries,
+			},
+		},
+	}
+}
+
+// newCommandsProvider returns a provider managing only alpha.com, with
+// beta.com excluded by the domain filter.
+func newCommandsProvider(client *mockClient) *HetznerProvider {
+	alpha := &hcloud.Zone{ID: 1, Name: "alpha.com", TTL: 3600, Mode: hcloud.ZoneModePrimary, Status: hcloud.ZoneStatusOk, RecordCount: 3}
+	beta := &hcloud.Zone{ID: 2, Name: "beta.com", TTL: 7200, Mode: hcloud.ZoneModePrimary, Status: hcloud.ZoneStatusOk, RecordCount: 2}
+	client.getZones = zonesResponse{
+		zones: []*hcloud.Zone{beta, alpha},
+		resp:  singlePageResponse(2),
+	}
+	return &HetznerProvider{
+		client:       client,
+		batchSize:    100,
+		domainFilter: endpoint.NewDomainFilter([]string{"alpha.com"}),
+	}
+}
+
+// Test_ListZones tests ListZones().
+func Test_ListZones(t *testing.T) {
+	p := newCommandsProvider(&mockClient{})
+	zones, err := p.ListZones(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []ZoneSummary{
+		{ID: 1, Name: "alpha.com", TTL: 3600, Mode: "primary", Status: "ok", RecordCount: 3},
+	}, zones)
+
+	p = newCommandsProvider(&mockClient{})
+	p.client.(*mockClient).getZones.err = errors.New("test zones error")
+	_, err = p.ListZones(context.Background())
+	assert.EqualError(t, err, "test zones error")
+}
+
+// Test_ZoneRecords tests ZoneRecords().
+func Test_ZoneRecords(t *testing.T) {
+	alpha := &hcloud.Zone{ID: 1, Name: "alpha.com"}
+	client := &mockClient{
+		getRRSets: rrSetsResponse{
+			rrsets: []*hcloud.ZoneRRSet{
+				{
+					Zone:    alpha,
+					Name:    "www",
+					Type:    hcloud.ZoneRRSetTypeA,
+					TTL:     &testTTL,
+					Records: []hcloud.ZoneRRSetRecord{{Value: "127.0.0.1"}},
+				},
+				{
+					Zone:    alpha,
+					Name:    "@",
+					Type:    hcloud.ZoneRRSetTypeSOA,
+					Records: []hcloud.ZoneRRSetRecord{{Value: "soa"}},
+				},
+			},
+			resp: singlePageResponse(2),
+		},
+	}
+	p := newCommandsProvider(client)
+	endpoints, err := p.ZoneRecords(context.Background(), "alpha.com")
+	assert.Nil(t, err)
+	assert.Len(t, endpoints, 1)
+	assert.Equal(t, "www.alpha.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"127.0.0.1"}, endpoints[0].Targets)
+
+	_, err = p.ZoneRecords(context.Background(), "beta.com")
+	assert.EqualError(t, err, "zone beta.com not found or not matching the domain filter")
+}
+
+// Test_ExportZonefile tests ExportZonefile().
+func Test_ExportZonefile(t *testing.T) {
+	client := &mockClient{
+		exportZonefile: exportZonefileResponse{
+			result: hcloud.ZoneExportZonefileResult{Zonefile: "$ORIGIN alpha.com.\n"},
+		},
+	}
+	p := newCommandsProvider(client)
+	zf, err := p.ExportZonefile(context.Background(), "alpha.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "$ORIGIN alpha.com.\n", zf)
+
+	client.exportZonefile.err = errors.New("test export error")
+	_, err = p.ExportZonefile(context.Background(), "alpha.com")
+	assert.EqualError(t, err, "cannot export zonefile for zone alpha.com: test export error")
+
+	_, err = p.ExportZonefile(context.Background(), "beta.com")
+	assert.EqualError(t, err, "zone beta.com not found or not matching the domain filter")
+}
+
+// Test_ImportZonefile tests ImportZonefile().
+func Test_ImportZonefile(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			zone   string
+			dryRun bool
+			client *mockClient
+		}
+		expected struct {
+			state mockClientState
+			err   error
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		p := newCommandsProvider(inp.client)
+		p.dryRun = inp.dryRun
+		err := p.ImportZonefile(context.Background(), inp.zone, "$ORIGIN alpha.com.\n")
+		assertError(t, exp.err, err)
+		assert.Equal(t, exp.state, inp.client.state)
+	}
+
+	testCases := []testCase{
+		{
+			name: "imported",
+			input: struct {
+				zone   string
+				dryRun bool
+				client *mockClient
+			}{
+				zone: "alpha.com",
+				client: &mockClient{
+					importZonefile: actionResponse{action: &hcloud.Action{ID: 1}},
+				},
+			},
+			expected: struct {
+				state mockClientState
+				err   error
+			}{
+				state: mockClientState{
+					GetZonesCalled:       true,
+					ImportZonefileCalled: true,
+					WaitForActionCalled:  true,
+				},
+			},
+		},
+		{
+			name: "dry run",
+			input: struct {
+				zone   string
+				dryRun bool
+				client *mockClient
+			}{
+				zone:   "alpha.com",
+				dryRun: true,
+				client: &mockClient{},
+			},
+			expected: struct {
+				state mockClientState
+				err   error
+			}{
+				state: mockClientState{GetZonesCalled: true},
+			},
+		},
+		{
+			name: "zone not managed",
+			input: struct {
+				zone   string
+				dryRun bool
+				client *mockClient
+			}{
+				zone:   "beta.com",
+				client: &mockClient{},
+			},
+			expected: struct {
+				state mockClientState
+				err   error
+			}{
+				state: mockClientState{GetZonesCalled: true},
+				err:   errors.New("zone beta.com not found or not matching the domain filter"),
+			},
+		},
+		{
+			name: "import error",
+			input: struct {
+				zone   string
+				dryRun bool
+				client *mockClient
+			}{
+				zone: "alpha.com",
+				client: &mockClient{
+					importZonefile: actionResponse{err: errors.New("test import error")},
+				},
+			},
+			expected: struct {
+				state mockClientState
+				err   error
+			}{
+				state: mockClientState{
+					GetZonesCalled:       true,
+					ImportZonefileCalled: true,
+				},
+				err: errors.New("cannot upload zonefile for zone alpha.com: test import error"),
+			},
+		},
+		{
+			name: "wait error",
+			input: struct {
+				zone   string
+				dryRun bool
+				client *mockClient
+			}{
+				zone: "alpha.com",
+				client: &mockClient{
+					importZonefile: actionResponse{action: &hcloud.Action{ID: 1}},
+					waitForAction:  errors.New("test wait error"),
+				},
+			},
+			expected: struct {
+				state mockClientState
+				err   error
+			}{
+				state: mockClientState{
+					GetZonesCalled:       true,
+					ImportZonefileCalled: true,
+					WaitForActionCalled:  true,
+				},
+				err: errors.New("error while importing zonefile for zone alpha.com: test wait error"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
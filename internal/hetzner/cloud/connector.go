@@ -43,6 +43,8 @@ type apiClient interface {
 	ExportZonefile(ctx context.Context, zone *hcloud.Zone) (hcloud.ZoneExportZonefileResult, *hcloud.Response, error)
 	// ImportZoneFile imports a zonefile
 	ImportZonefile(ctx context.Context, zone *hcloud.Zone, opts hcloud.ZoneImportZonefileOpts) (*hcloud.Action, *hcloud.Response, error)
+	// WaitForAction waits until an action is completed.
+	WaitForAction(ctx context.Context, action *hcloud.Action) error
 }
 
 // isEndPage returns true if this is the last page response.
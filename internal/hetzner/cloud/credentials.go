@@ -0,0 +1,187 @@
+/*
+ * Credentials - API key loading and rotation.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// apiKeyCheckInterval is the interval between the checks of the API key file.
+const apiKeyCheckInterval = 10 * time.Second
+
+// tokenClient is a client with the fingerprint of its API key.
+type tokenClient struct {
+	client *hcloud.Client
+	token  string
+}
+
+// newTokenClient creates a client for an API key. An empty endpoint selects
+// the default one.
+func newTokenClient(apiKey, endpoint string) *tokenClient {
+	opts := []hcloud.ClientOption{hcloud.WithToken(apiKey)}
+	if endpoint != "" {
+		opts = append(opts, hcloud.WithEndpoint(endpoint))
+	}
+	return &tokenClient{
+		client: hcloud.NewClient(opts...),
+		token:  tokenFingerprint(apiKey),
+	}
+}
+
+// credentials holds the current client. When the API key is read from a file,
+// the client is replaced atomically every time the key changes.
+type credentials struct {
+	current  atomic.Pointer[tokenClient]
+	file     string
+	endpoint string
+	m        sync.Mutex
+	apiKey   string
+}
+
+// readAPIKeyFile reads an API key from a file, ignoring the surrounding
+// spaces.
+func readAPIKeyFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	apiKey := strings.TrimSpace(string(data))
+	if apiKey == "" {
+		return "", fmt.Errorf("empty API key in %s", file)
+	}
+	return apiKey, nil
+}
+
+// newCredentials creates the credentials from an API key or from the file
+// containing it. Only one of them must be provided. An empty endpoint selects
+// the default one.
+func newCredentials(apiKey, file, endpoint string) (*credentials, error) {
+	if apiKey != "" && file != "" {
+		return nil, errors.New("both API key and API key file provided")
+	}
+	if file != "" {
+		var err error
+		if apiKey, err = readAPIKeyFile(file); err != nil {
+			return nil, fmt.Errorf("cannot read API key file: %w", err)
+		}
+	}
+	if apiKey == "" {
+		return nil, errors.New("nil API key provided")
+	}
+	c := &credentials{file: file, endpoint: endpoint, apiKey: apiKey}
+	c.current.Store(newTokenClient(apiKey, endpoint))
+	return c, nil
+}
+
+// get returns the current client, or nil if the credentials are not set.
+func (c *credentials) get() *tokenClient {
+	if c == nil {
+		return nil
+	}
+	return c.current.Load()
+}
+
+// token returns the fingerprint of the current API key, or an empty string if
+// the credentials are not set.
+func (c *credentials) token() string {
+	if tc := c.get(); tc != nil {
+		return tc.token
+	}
+	return ""
+}
+
+// reload reads the API key file again and replaces the client if the key
+// changed. It returns true if the client was replaced.
+func (c *credentials) reload() (bool, error) {
+	if c == nil || c.file == "" {
+		return false, nil
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	apiKey, err := readAPIKeyFile(c.file)
+	if err != nil {
+		return false, err
+	}
+	if apiKey == c.apiKey {
+		return false, nil
+	}
+	tc := newTokenClient(apiKey, c.endpoint)
+	c.current.Store(tc)
+	c.apiKey = apiKey
+	apiLog.Infof("API key changed in %s, now using token %s.", c.file, tc.token)
+	return true, nil
+}
+
+// isAuthFailure returns true if an API call failed because the API key was
+// rejected.
+func isAuthFailure(r *hcloud.Response, err error) bool {
+	if err == nil {
+		return false
+	}
+	if r != nil && r.Response != nil && r.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	return hcloud.IsError(err, hcloud.ErrorCodeUnauthorized)
+}
+
+// checkAuth reads the API key file as soon as a call is rejected, without
+// waiting for the next periodic check, so that a rotated key is picked up
+// quickly.
+func (c *credentials) checkAuth(r *hcloud.Response, err error) {
+	if c == nil || c.file == "" || !isAuthFailure(r, err) {
+		return
+	}
+	apiLog.Warn("API key rejected, reading the API key file again.")
+	if _, err := c.reload(); err != nil {
+		apiLog.Errorf("Cannot read the API key file: %v", err)
+	}
+}
+
+// watch checks the API key file every interval until the context is done.
+func (c *credentials) watch(ctx context.Context, interval time.Duration) {
+	if c == nil || c.file == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := c.reload()
+			// A read error is reported only once until the file can be read
+			// again.
+			if err != nil && !failed {
+				apiLog.Errorf("Cannot read the API key file: %v", err)
+			}
+			failed = err != nil
+		}
+	}
+}
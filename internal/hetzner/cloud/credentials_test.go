@@ -0,0 +1,296 @@
+/*
+ * Credentials - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeAPIKeyFile writes an API key file in a temporary directory.
+func writeAPIKeyFile(t *testing.T, dir, apiKey string) string {
+	path := filepath.Join(dir, "token")
+	assert.Nil(t, os.WriteFile(path, []byte(apiKey), 0600))
+	return path
+}
+
+// Test_newCredentials tests newCredentials().
+func Test_newCredentials(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := writeAPIKeyFile(t, dir, "FILE_API_KEY\n")
+	emptyFile := filepath.Join(dir, "empty")
+	assert.Nil(t, os.WriteFile(emptyFile, []byte(" \n"), 0600))
+
+	type testCase struct {
+		name  string
+		input struct {
+			apiKey string
+			file   string
+		}
+		expected struct {
+			token string
+			err   bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		c, err := newCredentials(inp.apiKey, inp.file, "")
+		assert.Equal(t, exp.err, err != nil)
+		if err == nil {
+			assert.NotNil(t, c.get().client)
+			assert.Equal(t, exp.token, c.token())
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name: "api key",
+			input: struct {
+				apiKey string
+				file   string
+			}{apiKey: "TEST_API_KEY"},
+			expected: struct {
+				token string
+				err   bool
+			}{token: tokenFingerprint("TEST_API_KEY")},
+		},
+		{
+			name: "api key file",
+			input: struct {
+				apiKey string
+				file   string
+			}{file: keyFile},
+			expected: struct {
+				token string
+				err   bool
+			}{token: tokenFingerprint("FILE_API_KEY")},
+		},
+		{
+			name: "both provided",
+			input: struct {
+				apiKey string
+				file   string
+			}{apiKey: "TEST_API_KEY", file: keyFile},
+			expected: struct {
+				token string
+				err   bool
+			}{err: true},
+		},
+		{
+			name: "none provided",
+			expected: struct {
+				token string
+				err   bool
+			}{err: true},
+		},
+		{
+			name: "missing file",
+			input: struct {
+				apiKey string
+				file   string
+			}{file: filepath.Join(dir, "missing")},
+			expected: struct {
+				token string
+				err   bool
+			}{err: true},
+		},
+		{
+			name: "empty file",
+			input: struct {
+				apiKey string
+				file   string
+			}{file: emptyFile},
+			expected: struct {
+				token string
+				err   bool
+			}{err: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_credentials_nil tests the credentials methods on a nil instance.
+func Test_credentials_nil(t *testing.T) {
+	var c *credentials
+	assert.Nil(t, c.get())
+	assert.Equal(t, "", c.token())
+	changed, err := c.reload()
+	assert.False(t, changed)
+	assert.Nil(t, err)
+	assert.NotPanics(t, func() {
+		c.checkAuth(nil, errors.New("unauthorized"))
+		c.watch(context.Background(), time.Millisecond)
+	})
+}
+
+// Test_credentials_reload tests credentials.reload().
+func Test_credentials_reload(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := writeAPIKeyFile(t, dir, "OLD_API_KEY")
+	c, err := newCredentials("", keyFile, "")
+	assert.Nil(t, err)
+	old := c.get()
+
+	// Unchanged key.
+	changed, err := c.reload()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Same(t, old, c.get())
+
+	// Rotated key.
+	writeAPIKeyFile(t, dir, "NEW_API_KEY")
+	changed, err = c.reload()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.NotSame(t, old, c.get())
+	assert.Equal(t, tokenFingerprint("NEW_API_KEY"), c.token())
+
+	// Unreadable file keeps the current client.
+	assert.Nil(t, os.Remove(keyFile))
+	current := c.get()
+	changed, err = c.reload()
+	assert.NotNil(t, err)
+	assert.False(t, changed)
+	assert.Same(t, current, c.get())
+
+	// Credentials without file are never reloaded.
+	c, err = newCredentials("TEST_API_KEY", "", "")
+	assert.Nil(t, err)
+	changed, err = c.reload()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+}
+
+// Test_isAuthFailure tests isAuthFailure().
+func Test_isAuthFailure(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			r   *hcloud.Response
+			err error
+		}
+		expected bool
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		assert.Equal(t, tc.expected, isAuthFailure(tc.input.r, tc.input.err))
+	}
+
+	testCases := []testCase{
+		{
+			name: "no error",
+			input: struct {
+				r   *hcloud.Response
+				err error
+			}{
+				r: &hcloud.Response{Response: &http.Response{StatusCode: http.StatusOK}},
+			},
+			expected: false,
+		},
+		{
+			name: "unauthorized status",
+			input: struct {
+				r   *hcloud.Response
+				err error
+			}{
+				r:   &hcloud.Response{Response: &http.Response{StatusCode: http.StatusUnauthorized}},
+				err: errors.New("unauthorized"),
+			},
+			expected: true,
+		},
+		{
+			name: "unauthorized error code",
+			input: struct {
+				r   *hcloud.Response
+				err error
+			}{
+				err: hcloud.Error{Code: hcloud.ErrorCodeUnauthorized},
+			},
+			expected: true,
+		},
+		{
+			name: "other error",
+			input: struct {
+				r   *hcloud.Response
+				err error
+			}{
+				r:   &hcloud.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+				err: hcloud.Error{Code: hcloud.ErrorCodeNotFound},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_credentials_checkAuth tests credentials.checkAuth().
+func Test_credentials_checkAuth(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := writeAPIKeyFile(t, dir, "OLD_API_KEY")
+	c, err := newCredentials("", keyFile, "")
+	assert.Nil(t, err)
+	writeAPIKeyFile(t, dir, "NEW_API_KEY")
+
+	// Other errors do not trigger the reload.
+	c.checkAuth(nil, hcloud.Error{Code: hcloud.ErrorCodeNotFound})
+	assert.Equal(t, tokenFingerprint("OLD_API_KEY"), c.token())
+
+	c.checkAuth(nil, hcloud.Error{Code: hcloud.ErrorCodeUnauthorized})
+	assert.Equal(t, tokenFingerprint("NEW_API_KEY"), c.token())
+}
+
+// Test_credentials_watch tests credentials.watch().
+func Test_credentials_watch(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := writeAPIKeyFile(t, dir, "OLD_API_KEY")
+	c, err := newCredentials("", keyFile, "")
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	writeAPIKeyFile(t, dir, "NEW_API_KEY")
+	assert.Eventually(t, func() bool {
+		return c.token() == tokenFingerprint("NEW_API_KEY")
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
@@ -0,0 +1,288 @@
+/*
+ * Drift - detects the changes made outside the webhook to the applied RRSets.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/hetzner"
+	"external-dns-hetzner-webhook/internal/metrics"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Kinds of drift.
+const (
+	// The applied RRSet was deleted
+	driftMissing = "missing"
+	// The applied RRSet was changed
+	driftModified = "modified"
+)
+
+// appliedEndpoint is an endpoint applied by the webhook, with its zone.
+type appliedEndpoint struct {
+	zone     *hcloud.Zone
+	endpoint *endpoint.Endpoint
+}
+
+// drift is a difference between an applied endpoint and the current RRSet.
+type drift struct {
+	kind     string
+	zone     string
+	expected *endpoint.Endpoint
+	// nil if the RRSet is missing
+	actual *endpoint.Endpoint
+}
+
+// logFields returns the log fields describing the drift.
+func (d drift) logFields() log.Fields {
+	fields := log.Fields{
+		"zone":            d.zone,
+		"DNSName":         d.expected.DNSName,
+		"RecordType":      d.expected.RecordType,
+		"kind":            d.kind,
+		"expectedTargets": d.expected.Targets.String(),
+		"expectedTTL":     int(d.expected.RecordTTL),
+	}
+	if d.actual != nil {
+		fields["actualTargets"] = d.actual.Targets.String()
+		fields["actualTTL"] = int(d.actual.RecordTTL)
+	}
+	return fields
+}
+
+// driftTracker remembers the endpoints applied by the webhook and the drift
+// found by the last check. It is shared by ApplyChanges and the check loop.
+type driftTracker struct {
+	interval    time.Duration
+	autoCorrect bool
+	m           sync.Mutex
+	applied     map[endpointKey]appliedEndpoint
+	drifts      map[endpointKey]drift
+	// Zones in the drift metrics, only used by the check loop
+	zones map[string]bool
+}
+
+// newDriftTracker creates a new driftTracker. It returns nil if the interval
+// is not positive, which disables the drift detection.
+func newDriftTracker(interval time.Duration, autoCorrect bool) *driftTracker {
+	if interval <= 0 {
+		return nil
+	}
+	return &driftTracker{
+		interval:    interval,
+		autoCorrect: autoCorrect,
+		applied:     make(map[endpointKey]appliedEndpoint),
+		drifts:      make(map[endpointKey]drift),
+		zones:       make(map[string]bool),
+	}
+}
+
+// record stores the state applied by the changes. The deleted endpoints are
+// forgotten.
+func (dt *driftTracker) record(zoneIDNameMapper zoneIDName, changes *plan.Changes) {
+	if dt == nil {
+		return
+	}
+	dt.m.Lock()
+	defer dt.m.Unlock()
+	for _, ep := range slices.Concat(changes.UpdateOld, changes.Delete) {
+		key := newEndpointKey(ep)
+		delete(dt.applied, key)
+		delete(dt.drifts, key)
+	}
+	for _, ep := range slices.Concat(changes.Create, changes.UpdateNew) {
+		_, zone := zoneIDNameMapper.FindZone(ep.DNSName)
+		if zone == nil {
+			continue
+		}
+		key := newEndpointKey(ep)
+		dt.applied[key] = appliedEndpoint{zone: zone, endpoint: ep.DeepCopy()}
+		delete(dt.drifts, key)
+	}
+}
+
+// snapshot returns a copy of the applied endpoints.
+func (dt *driftTracker) snapshot() map[endpointKey]appliedEndpoint {
+	dt.m.Lock()
+	defer dt.m.Unlock()
+	return maps.Clone(dt.applied)
+}
+
+// update replaces the drift found by a check on the snapshot and logs the
+// differences with the previous check. The endpoints applied again during the
+// check are ignored. It returns the stored drift.
+func (dt *driftTracker) update(snapshot map[endpointKey]appliedEndpoint, found map[endpointKey]drift) []drift {
+	dt.m.Lock()
+	defer dt.m.Unlock()
+	drifts := make(map[endpointKey]drift, len(found))
+	for key, d := range found {
+		if dt.applied[key].endpoint != snapshot[key].endpoint {
+			continue
+		}
+		drifts[key] = d
+		if prev, ok := dt.drifts[key]; !ok || prev.kind != d.kind {
+			providerLog.WithFields(d.logFields()).Warnf("Drift detected: RRSet %s of type %s is %s.",
+				d.expected.DNSName, d.expected.RecordType, d.kind)
+		}
+	}
+	for key, d := range dt.drifts {
+		if _, ok := drifts[key]; !ok {
+			providerLog.Infof("Drift of RRSet %s of type %s resolved.", d.expected.DNSName, d.expected.RecordType)
+		}
+	}
+	dt.drifts = drifts
+	return slices.Collect(maps.Values(drifts))
+}
+
+// correct returns the changes with the corrections of the detected drift
+// added, if the automatic correction is enabled. The RRSets already changed by
+// the given changes are left alone.
+func (dt *driftTracker) correct(changes *plan.Changes, m *metrics.OpenMetrics) *plan.Changes {
+	if dt == nil || !dt.autoCorrect {
+		return changes
+	}
+	dt.m.Lock()
+	defer dt.m.Unlock()
+	if len(dt.drifts) == 0 {
+		return changes
+	}
+	changed := make(map[endpointKey]bool)
+	for _, ep := range slices.Concat(changes.Create, changes.UpdateOld, changes.UpdateNew, changes.Delete) {
+		changed[newEndpointKey(ep)] = true
+	}
+	corrected := &plan.Changes{
+		Create:    slices.Clone(changes.Create),
+		UpdateOld: slices.Clone(changes.UpdateOld),
+		UpdateNew: slices.Clone(changes.UpdateNew),
+		Delete:    slices.Clone(changes.Delete),
+	}
+	keys := slices.SortedFunc(maps.Keys(dt.drifts), func(a, b endpointKey) int {
+		return cmp.Or(cmp.Compare(a.name, b.name), cmp.Compare(a.recordType, b.recordType))
+	})
+	for _, key := range keys {
+		if changed[key] {
+			continue
+		}
+		d := dt.drifts[key]
+		switch d.kind {
+		case driftMissing:
+			corrected.Create = append(corrected.Create, d.expected.DeepCopy())
+		case driftModified:
+			corrected.UpdateOld = append(corrected.UpdateOld, d.actual.DeepCopy())
+			corrected.UpdateNew = append(corrected.UpdateNew, d.expected.DeepCopy())
+		}
+		providerLog.WithFields(d.logFields()).Infof("Correcting drift of RRSet %s of type %s.",
+			d.expected.DNSName, d.expected.RecordType)
+		m.IncDriftCorrectionsTotal(d.zone)
+	}
+	return corrected
+}
+
+// WatchDrift checks the applied RRSets for drift at the configured interval
+// until the context is done. It returns immediately if the drift detection is
+// disabled.
+func (p *HetznerProvider) WatchDrift(ctx context.Context) {
+	if p.drift == nil {
+		return
+	}
+	providerLog.Infof("Checking the applied RRSets for drift every %s.", p.drift.interval)
+	ticker := time.NewTicker(p.drift.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := p.checkDrift(ctx); err != nil {
+			providerLog.Errorf("Drift check failed: %s", err.Error())
+			p.metrics.IncDriftChecksTotal("failure", time.Now())
+		} else {
+			p.metrics.IncDriftChecksTotal("success", time.Now())
+		}
+	}
+}
+
+// checkDrift compares the applied endpoints with the current RRSets of their
+// zones and updates the drift metrics. Only the fields that never change after
+// the creation of the provider are used, because the check runs concurrently
+// with the synchronization.
+func (p *HetznerProvider) checkDrift(ctx context.Context) error {
+	dt := p.drift
+	snapshot := dt.snapshot()
+	zones := make(map[int64]*hcloud.Zone)
+	keysByZoneID := make(map[int64][]endpointKey)
+	for key, ae := range snapshot {
+		zones[ae.zone.ID] = ae.zone
+		keysByZoneID[ae.zone.ID] = append(keysByZoneID[ae.zone.ID], key)
+	}
+
+	found := make(map[endpointKey]drift)
+	for zoneID, keys := range keysByZoneID {
+		zone := zones[zoneID]
+		rrsets, err := fetchRecords(ctx, zone, p.client, hetzner.MaxBatchSize)
+		if err != nil {
+			return fmt.Errorf("cannot read zone %s: %w", zone.Name, err)
+		}
+		endpoints, _, _ := createZoneEndpoints(p.slashEscSeq, zone, rrsets)
+		current := make(map[endpointKey]*endpoint.Endpoint, len(endpoints))
+		for _, ep := range endpoints {
+			current[newEndpointKey(ep)] = ep
+		}
+		for _, key := range keys {
+			expected := snapshot[key].endpoint
+			actual, ok := current[key]
+			if !ok {
+				found[key] = drift{kind: driftMissing, zone: zone.Name, expected: expected}
+			} else if !sameEndpoint(expected, actual) {
+				found[key] = drift{kind: driftModified, zone: zone.Name, expected: expected, actual: actual}
+			}
+		}
+	}
+
+	counts := make(map[string]map[string]int)
+	for _, zone := range zones {
+		counts[zone.Name] = map[string]int{driftMissing: 0, driftModified: 0}
+	}
+	drifts := dt.update(snapshot, found)
+	for _, d := range drifts {
+		counts[d.zone][d.kind]++
+	}
+	for zoneName := range dt.zones {
+		if _, ok := counts[zoneName]; !ok {
+			p.metrics.SetDriftedRRSets(zoneName, nil)
+			delete(dt.zones, zoneName)
+		}
+	}
+	for zoneName, c := range counts {
+		p.metrics.SetDriftedRRSets(zoneName, c)
+		dt.zones[zoneName] = true
+	}
+	providerLog.Debugf("Checked %d applied RRSets for drift, %d drifted.", len(snapshot), len(drifts))
+	return nil
+}
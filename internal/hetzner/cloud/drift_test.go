@@ -0,0 +1,250 @@
+/*
+ * Drift - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// driftZone is the zone used in the drift tests.
+var driftZone = &hcloud.Zone{ID: 1, Name: "alpha.com"}
+
+// newDriftEndpoint returns an A endpoint in alpha.com.
+func newDriftEndpoint(name string, targets ...string) *endpoint.Endpoint {
+	return endpoint.NewEndpointWithTTL(name, "A", endpoint.TTL(testTTL), targets...)
+}
+
+// newDriftRRSet returns an A RRSet in alpha.com.
+func newDriftRRSet(name string, targets ...string) *hcloud.ZoneRRSet {
+	records := make([]hcloud.ZoneRRSetRecord, 0, len(targets))
+	for _, t := range targets {
+		records = append(records, hcloud.ZoneRRSetRecord{Value: t})
+	}
+	return &hcloud.ZoneRRSet{
+		Zone:    driftZone,
+		Name:    name,
+		Type:    hcloud.ZoneRRSetTypeA,
+		TTL:     &testTTL,
+		Records: records,
+	}
+}
+
+// driftKinds returns the kinds of the tracked drift by name.
+func driftKinds(dt *driftTracker) map[string]string {
+	kinds := make(map[string]string)
+	for key, d := range dt.drifts {
+		kinds[key.name] = d.kind
+	}
+	return kinds
+}
+
+// Test_newDriftTracker tests newDriftTracker().
+func Test_newDriftTracker(t *testing.T) {
+	assert.Nil(t, newDriftTracker(0, true))
+	assert.Nil(t, newDriftTracker(-time.Second, false))
+	dt := newDriftTracker(time.Minute, true)
+	assert.NotNil(t, dt)
+	assert.Equal(t, time.Minute, dt.interval)
+	assert.True(t, dt.autoCorrect)
+}
+
+// Test_driftTracker_record tests driftTracker.record().
+func Test_driftTracker_record(t *testing.T) {
+	mapper := zoneIDName{}
+	mapper.Add(driftZone, "")
+	dt := newDriftTracker(time.Minute, false)
+	dt.record(mapper, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newDriftEndpoint("www.alpha.com", "127.0.0.1"),
+			newDriftEndpoint("ftp.alpha.com", "127.0.0.2"),
+			newDriftEndpoint("www.unknown.com", "127.0.0.3"),
+		},
+	})
+	assert.Len(t, dt.applied, 2)
+	assert.Equal(t, driftZone, dt.applied[endpointKey{"www.alpha.com", "A"}].zone)
+
+	dt.drifts[endpointKey{"www.alpha.com", "A"}] = drift{kind: driftMissing}
+	dt.record(mapper, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{newDriftEndpoint("www.alpha.com", "127.0.0.1")},
+		UpdateNew: []*endpoint.Endpoint{newDriftEndpoint("www.alpha.com", "127.0.0.4")},
+		Delete:    []*endpoint.Endpoint{newDriftEndpoint("ftp.alpha.com", "127.0.0.2")},
+	})
+	assert.Len(t, dt.applied, 1)
+	assert.Equal(t, endpoint.Targets{"127.0.0.4"}, dt.applied[endpointKey{"www.alpha.com", "A"}].endpoint.Targets)
+	assert.Empty(t, dt.drifts)
+
+	// A nil tracker ignores the changes.
+	var nilTracker *driftTracker
+	assert.NotPanics(t, func() {
+		nilTracker.record(mapper, &plan.Changes{})
+	})
+}
+
+// Test_checkDrift tests HetznerProvider.checkDrift().
+func Test_checkDrift(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			applied []*endpoint.Endpoint
+			rrsets  []*hcloud.ZoneRRSet
+			err     error
+		}
+		expected struct {
+			drifts map[string]string
+			err    string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		mapper := zoneIDName{}
+		mapper.Add(driftZone, "")
+		p := &HetznerProvider{
+			client: &mockClient{
+				getRRSets: rrSetsResponse{
+					rrsets: inp.rrsets,
+					resp:   singlePageResponse(len(inp.rrsets)),
+					err:    inp.err,
+				},
+			},
+			drift: newDriftTracker(time.Minute, false),
+		}
+		p.drift.record(mapper, &plan.Changes{Create: inp.applied})
+		err := p.checkDrift(context.Background())
+		if exp.err != "" {
+			assert.EqualError(t, err, exp.err)
+			return
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, exp.drifts, driftKinds(p.drift))
+	}
+
+	testCases := []testCase{
+		{
+			name: "no drift",
+			input: struct {
+				applied []*endpoint.Endpoint
+				rrsets  []*hcloud.ZoneRRSet
+				err     error
+			}{
+				applied: []*endpoint.Endpoint{newDriftEndpoint("www.alpha.com", "127.0.0.1", "127.0.0.2")},
+				rrsets:  []*hcloud.ZoneRRSet{newDriftRRSet("www", "127.0.0.2", "127.0.0.1")},
+			},
+			expected: struct {
+				drifts map[string]string
+				err    string
+			}{
+				drifts: map[string]string{},
+			},
+		},
+		{
+			name: "missing and modified",
+			input: struct {
+				applied []*endpoint.Endpoint
+				rrsets  []*hcloud.ZoneRRSet
+				err     error
+			}{
+				applied: []*endpoint.Endpoint{
+					newDriftEndpoint("www.alpha.com", "127.0.0.1"),
+					newDriftEndpoint("ftp.alpha.com", "127.0.0.2"),
+					newDriftEndpoint("mail.alpha.com", "127.0.0.3"),
+				},
+				rrsets: []*hcloud.ZoneRRSet{
+					newDriftRRSet("www", "127.0.0.9"),
+					newDriftRRSet("mail", "127.0.0.3"),
+				},
+			},
+			expected: struct {
+				drifts map[string]string
+				err    string
+			}{
+				drifts: map[string]string{
+					"www.alpha.com": driftModified,
+					"ftp.alpha.com": driftMissing,
+				},
+			},
+		},
+		{
+			name: "API error",
+			input: struct {
+				applied []*endpoint.Endpoint
+				rrsets  []*hcloud.ZoneRRSet
+				err     error
+			}{
+				applied: []*endpoint.Endpoint{newDriftEndpoint("www.alpha.com", "127.0.0.1")},
+				err:     errors.New("test rrsets error"),
+			},
+			expected: struct {
+				drifts map[string]string
+				err    string
+			}{
+				err: "cannot read zone alpha.com: test rrsets error",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_driftTracker_correct tests driftTracker.correct().
+func Test_driftTracker_correct(t *testing.T) {
+	missing := newDriftEndpoint("ftp.alpha.com", "127.0.0.2")
+	modified := newDriftEndpoint("www.alpha.com", "127.0.0.1")
+	actual := newDriftEndpoint("www.alpha.com", "127.0.0.9")
+	changed := newDriftEndpoint("mail.alpha.com", "127.0.0.3")
+	newTracker := func(autoCorrect bool) *driftTracker {
+		dt := newDriftTracker(time.Minute, autoCorrect)
+		dt.drifts = map[endpointKey]drift{
+			newEndpointKey(missing):  {kind: driftMissing, zone: "alpha.com", expected: missing},
+			newEndpointKey(modified): {kind: driftModified, zone: "alpha.com", expected: modified, actual: actual},
+			newEndpointKey(changed):  {kind: driftMissing, zone: "alpha.com", expected: changed},
+		}
+		return dt
+	}
+	changes := &plan.Changes{
+		Delete: []*endpoint.Endpoint{changed},
+	}
+
+	// Without automatic correction the changes are returned unchanged.
+	assert.Same(t, changes, newTracker(false).correct(changes, nil))
+	var nilTracker *driftTracker
+	assert.Same(t, changes, nilTracker.correct(changes, nil))
+
+	corrected := newTracker(true).correct(changes, nil)
+	assert.Equal(t, &plan.Changes{
+		Create:    []*endpoint.Endpoint{missing},
+		UpdateOld: []*endpoint.Endpoint{actual},
+		UpdateNew: []*endpoint.Endpoint{modified},
+		Delete:    []*endpoint.Endpoint{changed},
+	}, corrected)
+	// The original changes are not modified.
+	assert.Empty(t, changes.Create)
+}
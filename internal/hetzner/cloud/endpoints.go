@@ -110,7 +110,7 @@ func extractEndpointTargets(rrset *hcloud.ZoneRRSet) []string {
 				host := fromHetznerHostname(rrset.Zone.Name, parts[1])
 				target = priority + " " + host
 			} else {
-				log.WithFields(log.Fields{
+				providerLog.WithFields(log.Fields{
 					"zone":   rrset.Zone.Name,
 					"target": target,
 				}).Warn("MX record from Hetzner API has unexpected format (expected 'priority hostname')")
@@ -140,7 +140,7 @@ func createEndpointFromRecord(slash string, rrset *hcloud.ZoneRRSet) *endpoint.E
 	} else {
 		ep.RecordTTL = endpoint.TTL(rrset.Zone.TTL)
 	}
-	log.WithFields(getEndpointLogFields(ep)).Debugf("Reading extracted endpoint %s", ep.DNSName)
+	providerLog.WithFields(getEndpointLogFields(ep)).Debugf("Reading extracted endpoint %s", ep.DNSName)
 	return ep
 }
 
@@ -151,10 +151,10 @@ func endpointsByZoneID(zoneIDNameMapper zoneIDName, endpoints []*endpoint.Endpoi
 	for idx, ep := range endpoints {
 		zoneID, _ := zoneIDNameMapper.FindZone(ep.DNSName)
 		if zoneID == -1 {
-			log.Warnf("Skipping record %s of type %s because no hosted zone matching record DNS Name was detected", ep.DNSName, ep.RecordType)
+			providerLog.Warnf("Skipping record %s of type %s because no hosted zone matching record DNS Name was detected", ep.DNSName, ep.RecordType)
 			continue
 		} else {
-			log.WithFields(getEndpointLogFields(ep)).Debugf("Reading endpoint %d for dividing by zone", idx)
+			providerLog.WithFields(getEndpointLogFields(ep)).Debugf("Reading endpoint %d for dividing by zone", idx)
 		}
 		endpointsByZoneID[zoneID] = append(endpointsByZoneID[zoneID], ep)
 	}
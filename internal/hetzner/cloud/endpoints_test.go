@@ -421,14 +421,14 @@ func Test_endpointsByZoneID(t *testing.T) {
 				endpoints        []*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				endpoints: []*endpoint.Endpoint{},
 			},
@@ -441,14 +441,14 @@ func Test_endpointsByZoneID(t *testing.T) {
 				endpoints        []*endpoint.Endpoint
 			}{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 				endpoints: []*endpoint.Endpoint{
 					{
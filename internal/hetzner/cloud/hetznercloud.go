@@ -19,13 +19,19 @@ package hetznercloud
 
 import (
 	"context"
-	"errors"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
 	"time"
 
+	"external-dns-hetzner-webhook/internal/hetzner"
 	"external-dns-hetzner-webhook/internal/metrics"
+	"external-dns-hetzner-webhook/internal/tracing"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -39,125 +45,217 @@ const (
 	actDeleteRRSet        = "delete_rrset"
 	actExportZonefile     = "export_zonefile"
 	actImportZonefile     = "import_zonefile"
+	actWaitForAction      = "wait_for_action"
 )
 
 type metricsHolder interface {
-	IncFailedApiCallsTotal(string)
-	IncSuccessfulApiCallsTotal(string)
-	AddApiDelayHist(string, int64)
-	SetRateLimitStats(string, http.Header)
+	IncFailedApiCallsTotal(string, string)
+	IncSuccessfulApiCallsTotal(string, string)
+	AddApiDelayHist(string, string, int64)
+	SetRateLimitStats(string, string, string, http.Header)
 }
 
-// hetznerCloud is the Cloud API client.
+// hetznerCloud is the Cloud API client of a project.
 type hetznerCloud struct {
-	client  *hcloud.Client
-	metrics metricsHolder
+	project     string
+	credentials *credentials
+	metrics     metricsHolder
 }
 
-// writeMetrics writes all the metrics related to the API calls.
-func (h hetznerCloud) writeMetrics(a string, s time.Time, r *hcloud.Response, err error) {
+// writeMetrics writes all the metrics related to the API calls. The rate limit
+// stats are attributed to the token of the client that made the call.
+func (h hetznerCloud) writeMetrics(tc *tokenClient, a string, s time.Time, r *hcloud.Response, err error) {
 	delay := time.Since(s)
 	if err != nil {
-		h.metrics.IncFailedApiCallsTotal(a)
+		h.metrics.IncFailedApiCallsTotal(h.project, a)
+		apiLog.Debugf("API call %s failed after %d ms: %v", a, delay.Milliseconds(), err)
 	} else {
-		h.metrics.IncSuccessfulApiCallsTotal(a)
+		h.metrics.IncSuccessfulApiCallsTotal(h.project, a)
+		apiLog.Debugf("API call %s completed in %d ms.", a, delay.Milliseconds())
 	}
-	h.metrics.AddApiDelayHist(a, delay.Milliseconds())
+	h.metrics.AddApiDelayHist(h.project, a, delay.Milliseconds())
 	if r != nil {
-		h.metrics.SetRateLimitStats(a, r.Header)
+		token := ""
+		if tc != nil {
+			token = tc.token
+		}
+		h.metrics.SetRateLimitStats(h.project, token, a, r.Header)
 	}
 }
 
-// NewHetznerCloud returns a new client. The API key is passed as an argument.
-func NewHetznerCloud(apiKey string) (*hetznerCloud, error) {
-	if apiKey == "" {
-		return nil, errors.New("nil API key provided")
+// startSpan starts the span of an API call on a zone. The zone name is empty
+// for calls that do not refer to a zone.
+func startSpan(ctx context.Context, a string, zone string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{tracing.AttrAction.String(a)}
+	if zone != "" {
+		attrs = append(attrs, tracing.AttrZone.String(zone))
+	}
+	return tracing.StartKind(ctx, trace.SpanKindClient, "hetzner."+a, attrs...)
+}
+
+// endSpan records the HTTP status of the response, if any, and ends the span.
+func endSpan(span trace.Span, r *hcloud.Response, err error) {
+	if r != nil && r.Response != nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(r.StatusCode))
+	}
+	tracing.End(span, err)
+}
+
+// rrsetZoneName returns the name of the zone of a recordset, if known.
+func rrsetZoneName(rrset *hcloud.ZoneRRSet) string {
+	if rrset == nil || rrset.Zone == nil {
+		return ""
+	}
+	return rrset.Zone.Name
+}
+
+// tokenFingerprint returns a short fingerprint of the API key, used for
+// telling the tokens apart in the metrics without disclosing them.
+func tokenFingerprint(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:4])
+}
+
+// NewHetznerCloud returns a new client for a project. The API key is passed
+// either directly or as the path of the file containing it. In the latter
+// case, the client is replaced when the key in the file changes.
+func NewHetznerCloud(project hetzner.Project, m *metrics.OpenMetrics) (*hetznerCloud, error) {
+	c, err := newCredentials(project.APIKey, project.APIKeyFile, project.Endpoint)
+	if err != nil {
+		return nil, err
 	}
 	return &hetznerCloud{
-		client:  hcloud.NewClient(hcloud.WithToken(apiKey)),
-		metrics: metrics.GetOpenMetricsInstance(),
+		project:     project.Name,
+		credentials: c,
+		metrics:     m,
 	}, nil
 }
 
+// watchAPIKey checks the API key file for changes until the context is done.
+// It returns immediately if the API key was not read from a file.
+func (h hetznerCloud) watchAPIKey(ctx context.Context) {
+	h.credentials.watch(ctx, apiKeyCheckInterval)
+}
+
 // GetZones returns the available zones.
 func (h hetznerCloud) GetZones(ctx context.Context, opts hcloud.ZoneListOpts) ([]*hcloud.Zone, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actGetZones, "")
 	start := time.Now()
-	result, response, err := zoneClient.List(ctx, opts)
-	h.writeMetrics(actGetZones, start, response, err)
+	result, response, err := tc.client.Zone.List(ctx, opts)
+	h.writeMetrics(tc, actGetZones, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
 
 // GetRRSets returns the recordset found in a given zone.
 func (h hetznerCloud) GetRRSets(ctx context.Context, zone *hcloud.Zone, opts hcloud.ZoneRRSetListOpts) ([]*hcloud.ZoneRRSet, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actGetRRSets, zone.Name)
 	start := time.Now()
-	result, response, err := zoneClient.ListRRSets(ctx, zone, opts)
-	h.writeMetrics(actGetRRSets, start, response, err)
+	result, response, err := tc.client.Zone.ListRRSets(ctx, zone, opts)
+	h.writeMetrics(tc, actGetRRSets, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
 
 // CreateRRSet creates a new recordset in the specified zone.
 func (h hetznerCloud) CreateRRSet(ctx context.Context, zone *hcloud.Zone, opts hcloud.ZoneRRSetCreateOpts) (hcloud.ZoneRRSetCreateResult, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actCreateRRSet, zone.Name)
 	start := time.Now()
-	result, response, err := zoneClient.CreateRRSet(ctx, zone, opts)
-	h.writeMetrics(actCreateRRSet, start, response, err)
+	result, response, err := tc.client.Zone.CreateRRSet(ctx, zone, opts)
+	h.writeMetrics(tc, actCreateRRSet, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
 
 // UpdateRRSetTTL updates the TTL of a recordset. It is not possible to set a
 // different TTL for each target.
 func (h hetznerCloud) UpdateRRSetTTL(ctx context.Context, rrset *hcloud.ZoneRRSet, opts hcloud.ZoneRRSetChangeTTLOpts) (*hcloud.Action, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actUpdateRRSetTTL, rrsetZoneName(rrset))
 	start := time.Now()
-	result, response, err := zoneClient.ChangeRRSetTTL(ctx, rrset, opts)
-	h.writeMetrics(actUpdateRRSetTTL, start, response, err)
+	result, response, err := tc.client.Zone.ChangeRRSetTTL(ctx, rrset, opts)
+	h.writeMetrics(tc, actUpdateRRSetTTL, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
 
 // UpdateRRSetRecords updates the targets of a recordset. The provided targets
 // overwrite completely the previous ones.
 func (h hetznerCloud) UpdateRRSetRecords(ctx context.Context, rrset *hcloud.ZoneRRSet, opts hcloud.ZoneRRSetSetRecordsOpts) (*hcloud.Action, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actUpdateRRSetRecords, rrsetZoneName(rrset))
 	start := time.Now()
-	result, response, err := zoneClient.SetRRSetRecords(ctx, rrset, opts)
-	h.writeMetrics(actUpdateRRSetRecords, start, response, err)
+	result, response, err := tc.client.Zone.SetRRSetRecords(ctx, rrset, opts)
+	h.writeMetrics(tc, actUpdateRRSetRecords, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
 
 // UpdateRRSetLabels updates the labels of a recordset.
 func (h hetznerCloud) UpdateRRSetLabels(ctx context.Context, rrset *hcloud.ZoneRRSet, opts hcloud.ZoneRRSetUpdateOpts) (*hcloud.ZoneRRSet, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actUpdateRRSet, rrsetZoneName(rrset))
 	start := time.Now()
-	result, response, err := zoneClient.UpdateRRSet(ctx, rrset, opts)
-	h.writeMetrics(actUpdateRRSet, start, response, err)
+	result, response, err := tc.client.Zone.UpdateRRSet(ctx, rrset, opts)
+	h.writeMetrics(tc, actUpdateRRSet, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
 
 // DeleteRecord deletes a recordset from a zone.
 func (h hetznerCloud) DeleteRRSet(ctx context.Context, rrset *hcloud.ZoneRRSet) (hcloud.ZoneRRSetDeleteResult, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actDeleteRRSet, rrsetZoneName(rrset))
 	start := time.Now()
-	result, response, err := zoneClient.DeleteRRSet(ctx, rrset)
-	h.writeMetrics(actDeleteRRSet, start, response, err)
+	result, response, err := tc.client.Zone.DeleteRRSet(ctx, rrset)
+	h.writeMetrics(tc, actDeleteRRSet, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
 
 // ExportZonefile downloads a zonefile from Hetzner.
 func (h hetznerCloud) ExportZonefile(ctx context.Context, zone *hcloud.Zone) (hcloud.ZoneExportZonefileResult, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actExportZonefile, zone.Name)
 	start := time.Now()
-	result, response, err := zoneClient.ExportZonefile(ctx, zone)
-	h.writeMetrics(actExportZonefile, start, response, err)
+	result, response, err := tc.client.Zone.ExportZonefile(ctx, zone)
+	h.writeMetrics(tc, actExportZonefile, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
 
 // ImportZonefile uploads a zonefile to Hetzner.
 func (h hetznerCloud) ImportZonefile(ctx context.Context, zone *hcloud.Zone, opts hcloud.ZoneImportZonefileOpts) (*hcloud.Action, *hcloud.Response, error) {
-	zoneClient := h.client.Zone
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actImportZonefile, zone.Name)
 	start := time.Now()
-	result, response, err := zoneClient.ImportZonefile(ctx, zone, opts)
-	h.writeMetrics(actImportZonefile, start, response, err)
+	result, response, err := tc.client.Zone.ImportZonefile(ctx, zone, opts)
+	h.writeMetrics(tc, actImportZonefile, start, response, err)
+	h.credentials.checkAuth(response, err)
+	endSpan(span, response, err)
 	return result, response, err
 }
+
+// WaitForAction waits until an action is completed, returning an error if the
+// action failed.
+func (h hetznerCloud) WaitForAction(ctx context.Context, action *hcloud.Action) error {
+	tc := h.credentials.get()
+	ctx, span := startSpan(ctx, actWaitForAction, "")
+	start := time.Now()
+	err := tc.client.Action.WaitFor(ctx, action)
+	h.writeMetrics(tc, actWaitForAction, start, nil, err)
+	h.credentials.checkAuth(nil, err)
+	endSpan(span, nil, err)
+	return err
+}
@@ -21,16 +21,59 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"external-dns-hetzner-webhook/internal/fakeapi"
+	"external-dns-hetzner-webhook/internal/hetzner"
+	"external-dns-hetzner-webhook/internal/metrics"
+
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // testTTL is a test ttl.
 var testTTL = 7200
 
+// newTestMetrics returns a new metrics instance with the default options.
+func newTestMetrics(t *testing.T) *metrics.OpenMetrics {
+	m, err := metrics.NewOpenMetrics(metrics.DefaultOptions())
+	assert.NoError(t, err)
+	return m
+}
+
+// metricValue returns the value of a counter or gauge with the given labels,
+// or -1 if it was not found.
+func metricValue(t *testing.T, m *metrics.OpenMetrics, name string, labels map[string]string) float64 {
+	families, err := m.GetRegistry().Gather()
+	assert.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+	nextMetric:
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if labels[lp.GetName()] != lp.GetValue() {
+					continue nextMetric
+				}
+			}
+			if m.GetCounter() != nil {
+				return m.GetCounter().GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+	}
+	return -1
+}
+
 // zonesResponse simulates a response that returns a list of zones.
 type zonesResponse struct {
 	zones []*hcloud.Zone
@@ -91,6 +134,7 @@ type mockClientState struct {
 	DeleteRRSetCalled        bool
 	ExportZonefileCalled     bool
 	ImportZonefileCalled     bool
+	WaitForActionCalled      bool
 }
 
 // mockClientArgs keeps track of the arguments passed.
@@ -99,6 +143,10 @@ type mockClientArgs struct {
 		zone *hcloud.Zone
 		opts hcloud.ZoneImportZonefileOpts
 	}
+	UpdateRRSetLabels []struct {
+		rrset *hcloud.ZoneRRSet
+		opts  hcloud.ZoneRRSetUpdateOpts
+	}
 }
 
 // mockClient represents the mock client used to simulate calls to the DNS API.
@@ -111,7 +159,10 @@ type mockClient struct {
 	updateRRSetLabels  rrSetResponse
 	deleteRRSet        deleteRRSetResponse
 	exportZonefile     exportZonefileResponse
+	exportZonefileSeq  []exportZonefileResponse
+	exportZonefileNum  int
 	importZonefile     actionResponse
+	waitForAction      error
 	filterRRSetsByZone bool
 	state              mockClientState
 	args               mockClientArgs
@@ -179,6 +230,10 @@ func (m *mockClient) UpdateRRSetRecords(ctx context.Context, rrset *hcloud.ZoneR
 func (m *mockClient) UpdateRRSetLabels(ctx context.Context, rrset *hcloud.ZoneRRSet, opts hcloud.ZoneRRSetUpdateOpts) (*hcloud.ZoneRRSet, *hcloud.Response, error) {
 	r := m.updateRRSetLabels
 	m.state.UpdateRRSetLabelsCalled = true
+	m.args.UpdateRRSetLabels = append(m.args.UpdateRRSetLabels, struct {
+		rrset *hcloud.ZoneRRSet
+		opts  hcloud.ZoneRRSetUpdateOpts
+	}{rrset: rrset, opts: opts})
 	return r.rrset, r.resp, r.err
 }
 
@@ -192,6 +247,11 @@ func (m *mockClient) DeleteRRSet(ctx context.Context, rrset *hcloud.ZoneRRSet) (
 // ExportZonefile simulates a request to export a zone file.
 func (m *mockClient) ExportZonefile(ctx context.Context, zone *hcloud.Zone) (hcloud.ZoneExportZonefileResult, *hcloud.Response, error) {
 	r := m.exportZonefile
+	// When a sequence is provided, each call returns the next response.
+	if m.exportZonefileNum < len(m.exportZonefileSeq) {
+		r = m.exportZonefileSeq[m.exportZonefileNum]
+	}
+	m.exportZonefileNum++
 	m.state.ExportZonefileCalled = true
 	return r.result, r.resp, r.err
 }
@@ -205,27 +265,35 @@ func (m *mockClient) ImportZonefile(ctx context.Context, zone *hcloud.Zone, opts
 	return r.action, r.resp, r.err
 }
 
+// WaitForAction simulates waiting for an action to complete.
+func (m *mockClient) WaitForAction(ctx context.Context, action *hcloud.Action) error {
+	m.state.WaitForActionCalled = true
+	return m.waitForAction
+}
+
 type mockMetrics struct {
 	CalledIncFailedApiCallsTotal     int
 	CalledIncSuccessfulApiCallsTotal int
 	CalledAddApiDelayHist            int
 	CalledSetRateLimitStats          int
+	RateLimitToken                   string
 }
 
-func (mm *mockMetrics) IncFailedApiCallsTotal(string) {
+func (mm *mockMetrics) IncFailedApiCallsTotal(string, string) {
 	mm.CalledIncFailedApiCallsTotal += 1
 }
 
-func (mm *mockMetrics) IncSuccessfulApiCallsTotal(string) {
+func (mm *mockMetrics) IncSuccessfulApiCallsTotal(string, string) {
 	mm.CalledIncSuccessfulApiCallsTotal += 1
 }
 
-func (mm *mockMetrics) AddApiDelayHist(string, int64) {
+func (mm *mockMetrics) AddApiDelayHist(string, string, int64) {
 	mm.CalledAddApiDelayHist += 1
 }
 
-func (mm *mockMetrics) SetRateLimitStats(string, http.Header) {
+func (mm *mockMetrics) SetRateLimitStats(_, token, _ string, _ http.Header) {
 	mm.CalledSetRateLimitStats += 1
+	mm.RateLimitToken = token
 }
 
 // assertError checks if an error is thrown when expected.
@@ -247,6 +315,7 @@ func Test_hetznerCloud_writeMetrics(t *testing.T) {
 		name   string
 		object hetznerCloud
 		input  struct {
+			tc  *tokenClient
 			a   string
 			s   time.Time
 			r   *hcloud.Response
@@ -259,7 +328,7 @@ func Test_hetznerCloud_writeMetrics(t *testing.T) {
 		obj := tc.object
 		inp := tc.input
 		exp := tc.expObject
-		obj.writeMetrics(inp.a, inp.s, inp.r, inp.err)
+		obj.writeMetrics(inp.tc, inp.a, inp.s, inp.r, inp.err)
 		assert.Equal(t, exp, obj)
 	}
 
@@ -270,6 +339,7 @@ func Test_hetznerCloud_writeMetrics(t *testing.T) {
 				metrics: &mockMetrics{},
 			},
 			input: struct {
+				tc  *tokenClient
 				a   string
 				s   time.Time
 				r   *hcloud.Response
@@ -295,11 +365,13 @@ func Test_hetznerCloud_writeMetrics(t *testing.T) {
 				metrics: &mockMetrics{},
 			},
 			input: struct {
+				tc  *tokenClient
 				a   string
 				s   time.Time
 				r   *hcloud.Response
 				err error
 			}{
+				tc:  &tokenClient{token: "test-token"},
 				a:   "test",
 				s:   time.Now(),
 				r:   &hcloud.Response{Response: &http.Response{}},
@@ -311,6 +383,7 @@ func Test_hetznerCloud_writeMetrics(t *testing.T) {
 					CalledIncSuccessfulApiCallsTotal: 1,
 					CalledAddApiDelayHist:            1,
 					CalledSetRateLimitStats:          1,
+					RateLimitToken:                   "test-token",
 				},
 			},
 		},
@@ -320,6 +393,7 @@ func Test_hetznerCloud_writeMetrics(t *testing.T) {
 				metrics: &mockMetrics{},
 			},
 			input: struct {
+				tc  *tokenClient
 				a   string
 				s   time.Time
 				r   *hcloud.Response
@@ -345,6 +419,7 @@ func Test_hetznerCloud_writeMetrics(t *testing.T) {
 				metrics: &mockMetrics{},
 			},
 			input: struct {
+				tc  *tokenClient
 				a   string
 				s   time.Time
 				r   *hcloud.Response
@@ -373,6 +448,135 @@ func Test_hetznerCloud_writeMetrics(t *testing.T) {
 	}
 }
 
+// Test_startSpan_endSpan tests startSpan() and endSpan().
+func Test_startSpan_endSpan(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			a    string
+			zone string
+			r    *hcloud.Response
+			err  error
+		}
+		expected struct {
+			attrs []attribute.KeyValue
+			code  codes.Code
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		bkp := otel.GetTracerProvider()
+		defer otel.SetTracerProvider(bkp)
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		_, span := startSpan(context.Background(), inp.a, inp.zone)
+		endSpan(span, inp.r, inp.err)
+		spans := recorder.Ended()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "hetzner."+inp.a, spans[0].Name())
+		assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+		assert.ElementsMatch(t, exp.attrs, spans[0].Attributes())
+		assert.Equal(t, exp.code, spans[0].Status().Code)
+	}
+
+	testCases := []testCase{
+		{
+			name: "no zone and no response",
+			input: struct {
+				a    string
+				zone string
+				r    *hcloud.Response
+				err  error
+			}{
+				a: "get_zones",
+			},
+			expected: struct {
+				attrs []attribute.KeyValue
+				code  codes.Code
+			}{
+				attrs: []attribute.KeyValue{
+					attribute.String("hetzner.action", "get_zones"),
+				},
+				code: codes.Unset,
+			},
+		},
+		{
+			name: "zone and response",
+			input: struct {
+				a    string
+				zone string
+				r    *hcloud.Response
+				err  error
+			}{
+				a:    "create_rrset",
+				zone: "alpha.com",
+				r: &hcloud.Response{Response: &http.Response{
+					StatusCode: http.StatusCreated,
+				}},
+			},
+			expected: struct {
+				attrs []attribute.KeyValue
+				code  codes.Code
+			}{
+				attrs: []attribute.KeyValue{
+					attribute.String("hetzner.action", "create_rrset"),
+					attribute.String("dns.zone", "alpha.com"),
+					attribute.Int("http.response.status_code", http.StatusCreated),
+				},
+				code: codes.Unset,
+			},
+		},
+		{
+			name: "error",
+			input: struct {
+				a    string
+				zone string
+				r    *hcloud.Response
+				err  error
+			}{
+				a:    "delete_rrset",
+				zone: "alpha.com",
+				err:  errors.New("test error"),
+			},
+			expected: struct {
+				attrs []attribute.KeyValue
+				code  codes.Code
+			}{
+				attrs: []attribute.KeyValue{
+					attribute.String("hetzner.action", "delete_rrset"),
+					attribute.String("dns.zone", "alpha.com"),
+				},
+				code: codes.Error,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_rrsetZoneName tests rrsetZoneName().
+func Test_rrsetZoneName(t *testing.T) {
+	assert.Equal(t, "", rrsetZoneName(nil))
+	assert.Equal(t, "", rrsetZoneName(&hcloud.ZoneRRSet{}))
+	assert.Equal(t, "alpha.com", rrsetZoneName(&hcloud.ZoneRRSet{
+		Zone: &hcloud.Zone{Name: "alpha.com"},
+	}))
+}
+
+// Test_tokenFingerprint tests tokenFingerprint().
+func Test_tokenFingerprint(t *testing.T) {
+	fp := tokenFingerprint("TEST_API_KEY")
+	assert.Len(t, fp, 8)
+	assert.Equal(t, fp, tokenFingerprint("TEST_API_KEY"))
+	assert.NotEqual(t, fp, tokenFingerprint("OTHER_API_KEY"))
+}
+
 // Test_NewHetznerCloud tests NewHetznerCloud().
 func Test_NewHetznerCloud(t *testing.T) {
 	type testCase struct {
@@ -386,11 +590,12 @@ func Test_NewHetznerCloud(t *testing.T) {
 
 	run := func(t *testing.T, tc testCase) {
 		exp := tc.expected
-		client, err := NewHetznerCloud(tc.input)
+		client, err := NewHetznerCloud(hetzner.Project{APIKey: tc.input}, nil)
 		if !assertError(t, exp.err, err) {
 			if exp.clientPresent {
 				assert.NotNil(t, client)
-				assert.NotNil(t, client.client)
+				assert.NotNil(t, client.credentials.get().client)
+				assert.Equal(t, tokenFingerprint(tc.input), client.credentials.token())
 			} else {
 				assert.Nil(t, client)
 			}
@@ -426,3 +631,48 @@ func Test_NewHetznerCloud(t *testing.T) {
 		})
 	}
 }
+
+// Test_NewHetznerCloud_endpoint tests that the client uses the API endpoint
+// of the project.
+func Test_NewHetznerCloud_endpoint(t *testing.T) {
+	fake := fakeapi.NewServer("TEST_API_KEY")
+	defer fake.Close()
+	fake.AddZone("alpha.com", 3600)
+
+	client, err := NewHetznerCloud(hetzner.Project{APIKey: "TEST_API_KEY", Endpoint: fake.URL}, nil)
+	assert.Nil(t, err)
+	zones, resp, err := client.GetZones(context.Background(), hcloud.ZoneListOpts{})
+	assert.Nil(t, err)
+	assert.Len(t, zones, 1)
+	assert.Equal(t, "alpha.com", zones[0].Name)
+	assert.Equal(t, 1, lastPage(resp))
+	assert.Len(t, fake.Requests(), 1)
+}
+
+// Test_hetznerCloud_tokenRotation tests that the rate limit stats of a call are
+// attributed to the token that made it, even if the token rotates meanwhile.
+func Test_hetznerCloud_tokenRotation(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := writeAPIKeyFile(t, dir, "OLD_API_KEY")
+	var creds *credentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeAPIKeyFile(t, dir, "NEW_API_KEY")
+		_, err := creds.reload()
+		assert.Nil(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("RateLimit-Limit", "3600")
+		w.Header().Set("RateLimit-Remaining", "3599")
+		_, _ = w.Write([]byte(`{"zones":[],"meta":{"pagination":{"page":1,"per_page":25,"last_page":1,"total_entries":0}}}`))
+	}))
+	defer server.Close()
+	creds, err := newCredentials("", keyFile, server.URL)
+	assert.Nil(t, err)
+	mm := &mockMetrics{}
+	client := hetznerCloud{credentials: creds, metrics: mm}
+
+	_, _, err = client.GetZones(context.Background(), hcloud.ZoneListOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, tokenFingerprint("NEW_API_KEY"), creds.token())
+	assert.Equal(t, 1, mm.CalledSetRateLimitStats)
+	assert.Equal(t, tokenFingerprint("OLD_API_KEY"), mm.RateLimitToken)
+}
@@ -0,0 +1,85 @@
+/*
+ * Hybrid changes - Code for choosing between bulk and single RRSet changes for
+ * each zone.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+
+	"external-dns-hetzner-webhook/internal/metrics"
+)
+
+// hybridChanges stores the changes by zone like bulkChanges, but decides
+// for each zone whether to apply them with the bulk system or with single
+// RRSet calls. Zones with a number of changes greater than or equal to the
+// threshold use the bulk system.
+type hybridChanges struct {
+	bulkChanges
+	threshold int
+}
+
+// NewHybridChanges creates a new hybridChanges object.
+func NewHybridChanges(dnsClient apiClient, dryRun bool, slash string, defaults defaultLabels, maxRetries int, backups zonefileBackups, threshold int, m *metrics.OpenMetrics) *hybridChanges {
+	return &hybridChanges{
+		bulkChanges: *NewBulkChanges(dnsClient, dryRun, slash, defaults, maxRetries, backups, m),
+		threshold:   threshold,
+	}
+}
+
+// count returns the number of changes queued for a zone.
+func (zc zoneChanges) count() int {
+	return len(zc.creates) + len(zc.updates) + len(zc.deletes)
+}
+
+// split divides the queued changes between a bulkChanges and a hetznerChanges
+// object, depending on the number of changes for each zone.
+func (c hybridChanges) split() (*bulkChanges, *hetznerChanges) {
+	bulk := NewBulkChanges(c.dnsClient, c.dryRun, c.slash, c.defaults, c.maxRetries, c.backups, c.metrics)
+	single := NewHetznerChanges(c.dnsClient, c.dryRun, c.slash, c.defaults, c.metrics)
+	for zoneID, zone := range c.zones {
+		zc := c.changes[zoneID]
+		n := zc.count()
+		if n >= c.threshold {
+			changesLog.Debugf("Zone [%s] has %d changes: using bulk mode.", zone.Name, n)
+			bulk.zones[zoneID] = zone
+			bulk.changes[zoneID] = zc
+		} else {
+			changesLog.Debugf("Zone [%s] has %d changes: using single RRSet calls.", zone.Name, n)
+			single.creates = append(single.creates, zc.creates...)
+			single.updates = append(single.updates, zc.updates...)
+			single.deletes = append(single.deletes, zc.deletes...)
+		}
+	}
+	return bulk, single
+}
+
+// ApplyChanges applies the planned changes. The zones using single RRSet
+// calls and the ones using the bulk system are applied independently, so that
+// a failure in one does not prevent the other: all the errors are returned.
+func (c hybridChanges) ApplyChanges(ctx context.Context) error {
+	// No changes = nothing to do.
+	if c.empty() {
+		changesLog.Debug("No changes to be applied found.")
+		return nil
+	}
+	bulk, single := c.split()
+	singleErr := single.ApplyChanges(ctx)
+	bulkErr := bulk.ApplyChanges(ctx)
+	return errors.Join(singleErr, bulkErr)
+}
@@ -0,0 +1,302 @@
+/*
+ * Hybrid changes - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	hybridZoneSmall = &hcloud.Zone{ID: 1, Name: "alpha.com", TTL: 3600}
+	hybridZoneLarge = &hcloud.Zone{ID: 2, Name: "fastipletonis.eu", TTL: 3600}
+)
+
+// hybridTestChanges returns a hybridChanges object with one change for
+// hybridZoneSmall and three changes for hybridZoneLarge.
+func hybridTestChanges(client apiClient, threshold int) *hybridChanges {
+	c := NewHybridChanges(client, false, "--slash--", nil, 0, nil, threshold, nil)
+	c.AddChangeCreate(hybridZoneSmall, hcloud.ZoneRRSetCreateOpts{
+		Name:    "www",
+		Type:    hcloud.ZoneRRSetTypeA,
+		TTL:     &ttl3600,
+		Records: []hcloud.ZoneRRSetRecord{{Value: "1.1.1.1"}},
+	})
+	c.AddChangeCreate(hybridZoneLarge, hcloud.ZoneRRSetCreateOpts{
+		Name:    "ftp",
+		Type:    hcloud.ZoneRRSetTypeA,
+		TTL:     &ttl3600,
+		Records: []hcloud.ZoneRRSetRecord{{Value: "116.202.181.1"}},
+	})
+	c.AddChangeCreate(hybridZoneLarge, hcloud.ZoneRRSetCreateOpts{
+		Name:    "mail",
+		Type:    hcloud.ZoneRRSetTypeA,
+		TTL:     &ttl3600,
+		Records: []hcloud.ZoneRRSetRecord{{Value: "116.202.181.4"}},
+	})
+	c.AddChangeDelete(&hcloud.ZoneRRSet{
+		Zone: hybridZoneLarge,
+		ID:   "www/A",
+		Name: "www",
+		Type: hcloud.ZoneRRSetTypeA,
+	})
+	return c
+}
+
+// Test_hybridChanges_split tests hybridChanges.split().
+func Test_hybridChanges_split(t *testing.T) {
+	type testCase struct {
+		name      string
+		threshold int
+		expected  struct {
+			bulkZones     []int64
+			singleCreates int
+			singleDeletes int
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		bulk, single := hybridTestChanges(&mockClient{}, tc.threshold).split()
+		bulkZones := make([]int64, 0)
+		for zoneID := range bulk.zones {
+			bulkZones = append(bulkZones, zoneID)
+		}
+		assert.ElementsMatch(t, exp.bulkZones, bulkZones)
+		assert.Len(t, single.creates, exp.singleCreates)
+		assert.Len(t, single.deletes, exp.singleDeletes)
+	}
+
+	testCases := []testCase{
+		{
+			name:      "all zones in bulk mode",
+			threshold: 1,
+			expected: struct {
+				bulkZones     []int64
+				singleCreates int
+				singleDeletes int
+			}{
+				bulkZones: []int64{1, 2},
+			},
+		},
+		{
+			name:      "mixed zones",
+			threshold: 3,
+			expected: struct {
+				bulkZones     []int64
+				singleCreates int
+				singleDeletes int
+			}{
+				bulkZones:     []int64{2},
+				singleCreates: 1,
+			},
+		},
+		{
+			name:      "no zones in bulk mode",
+			threshold: 4,
+			expected: struct {
+				bulkZones     []int64
+				singleCreates int
+				singleDeletes int
+			}{
+				bulkZones:     []int64{},
+				singleCreates: 3,
+				singleDeletes: 1,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_hybridChanges_ApplyChanges tests hybridChanges.ApplyChanges().
+func Test_hybridChanges_ApplyChanges(t *testing.T) {
+	type testCase struct {
+		name      string
+		inpClient mockClient
+		threshold int
+		empty     bool
+		expected  error
+		expState  mockClientState
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		client := tc.inpClient
+		var obj *hybridChanges
+		if tc.empty {
+			obj = NewHybridChanges(&client, false, "--slash--", nil, 0, nil, tc.threshold, nil)
+		} else {
+			obj = hybridTestChanges(&client, tc.threshold)
+		}
+		err := obj.ApplyChanges(context.Background())
+		assertError(t, tc.expected, err)
+		assert.Equal(t, tc.expState, client.state)
+	}
+
+	okResponse := &hcloud.Response{
+		Response: &http.Response{StatusCode: http.StatusOK},
+	}
+
+	testCases := []testCase{
+		{
+			name:      "no changes",
+			threshold: 3,
+			empty:     true,
+			expState:  mockClientState{},
+		},
+		{
+			name: "mixed zones",
+			inpClient: mockClient{
+				createRRSet: createRRSetResponse{resp: okResponse},
+				exportZonefile: exportZonefileResponse{
+					result: hcloud.ZoneExportZonefileResult{Zonefile: inputZoneFile},
+					resp:   okResponse,
+				},
+			},
+			threshold: 3,
+			expState: mockClientState{
+				CreateRRSetCalled:    true,
+				ExportZonefileCalled: true,
+				ImportZonefileCalled: true,
+			},
+		},
+		{
+			name: "single RRSet calls only",
+			inpClient: mockClient{
+				createRRSet: createRRSetResponse{resp: okResponse},
+				deleteRRSet: deleteRRSetResponse{resp: okResponse},
+			},
+			threshold: 4,
+			expState: mockClientState{
+				CreateRRSetCalled: true,
+				DeleteRRSetCalled: true,
+			},
+		},
+		{
+			name: "single RRSet calls error",
+			inpClient: mockClient{
+				createRRSet: createRRSetResponse{err: errors.New("test create error")},
+				exportZonefile: exportZonefileResponse{
+					result: hcloud.ZoneExportZonefileResult{Zonefile: inputZoneFile},
+					resp:   okResponse,
+				},
+			},
+			threshold: 3,
+			expected:  errors.New("test create error"),
+			expState: mockClientState{
+				CreateRRSetCalled:    true,
+				ExportZonefileCalled: true,
+				ImportZonefileCalled: true,
+			},
+		},
+		{
+			name: "single RRSet and bulk errors",
+			inpClient: mockClient{
+				createRRSet:    createRRSetResponse{err: errors.New("test create error")},
+				exportZonefile: exportZonefileResponse{err: errors.New("test export error")},
+			},
+			threshold: 3,
+			expected:  errors.New("test create error\ncannot download zonefile for zone fastipletonis.eu: test export error"),
+			expState: mockClientState{
+				CreateRRSetCalled:    true,
+				ExportZonefileCalled: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_hybridChanges_countChanges tests that only the applied changes are
+// counted, with both the bulk system and the single RRSet calls.
+func Test_hybridChanges_countChanges(t *testing.T) {
+	type testCase struct {
+		name     string
+		dryRun   bool
+		expected struct {
+			smallCreates float64
+			largeCreates float64
+			applied      bool
+		}
+	}
+
+	okResponse := &hcloud.Response{
+		Response: &http.Response{StatusCode: http.StatusOK},
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		client := mockClient{
+			createRRSet: createRRSetResponse{resp: okResponse},
+			exportZonefile: exportZonefileResponse{
+				result: hcloud.ZoneExportZonefileResult{Zonefile: inputZoneFile},
+				resp:   okResponse,
+			},
+		}
+		m := newTestMetrics(t)
+		obj := hybridTestChanges(&client, 3)
+		obj.dryRun = tc.dryRun
+		obj.metrics = m
+		assert.Nil(t, obj.ApplyChanges(context.Background()))
+		assert.Equal(t, exp.smallCreates, metricValue(t, m, "record_changes_total",
+			map[string]string{"zone": hybridZoneSmall.Name, "operation": opCreate}))
+		assert.Equal(t, exp.largeCreates, metricValue(t, m, "record_changes_total",
+			map[string]string{"zone": hybridZoneLarge.Name, "operation": opCreate}))
+		assert.Equal(t, exp.applied, client.state.CreateRRSetCalled)
+		assert.Equal(t, exp.applied, client.state.ImportZonefileCalled)
+	}
+
+	testCases := []testCase{
+		{
+			name: "changes applied",
+			expected: struct {
+				smallCreates float64
+				largeCreates float64
+				applied      bool
+			}{smallCreates: 1, largeCreates: 2, applied: true},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			expected: struct {
+				smallCreates float64
+				largeCreates float64
+				applied      bool
+			}{smallCreates: -1, largeCreates: -1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * Inspection - runtime state of the provider for the admin API.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// ZoneInfo describes a zone known to the provider.
+type ZoneInfo struct {
+	// Zone ID
+	ID int64 `json:"id"`
+	// Zone name
+	Name string `json:"name"`
+	// True if the zone matches the domain filter
+	Managed bool `json:"managed"`
+	// Project that owns the zone, empty with a single project
+	Project string `json:"project,omitempty"`
+}
+
+// ZonesInfo describes the zones cached by the provider.
+type ZonesInfo struct {
+	// Zones from the last fetch, sorted by name
+	Zones []ZoneInfo `json:"zones"`
+	// Time of the last fetch
+	Updated *time.Time `json:"updated,omitempty"`
+	// Expiration of the zone cache, if enabled
+	CacheExpiry *time.Time `json:"cacheExpiry,omitempty"`
+}
+
+// FailuresInfo describes the failure count of the provider.
+type FailuresInfo struct {
+	// Current fail count
+	FailCount int `json:"failCount"`
+	// Maximum fail count, 0 or negative if disabled
+	MaxFailCount int `json:"maxFailCount"`
+}
+
+// ApplySummary summarizes an ApplyChanges call.
+type ApplySummary struct {
+	// Start time
+	Started time.Time `json:"started"`
+	// Duration in milliseconds
+	DurationMs int64 `json:"durationMs"`
+	// Number of endpoints to create
+	Creates int `json:"creates"`
+	// Number of endpoints to update
+	Updates int `json:"updates"`
+	// Number of endpoints to delete
+	Deletes int `json:"deletes"`
+	// Error message, if the call failed
+	Error string `json:"error,omitempty"`
+}
+
+// newApplySummary creates the summary of an ApplyChanges call.
+func newApplySummary(changes *plan.Changes, started time.Time, err error) *ApplySummary {
+	summary := &ApplySummary{
+		Started:    started.UTC(),
+		DurationMs: time.Since(started).Milliseconds(),
+		Creates:    len(changes.Create),
+		Updates:    len(changes.UpdateNew),
+		Deletes:    len(changes.Delete),
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	return summary
+}
+
+// inspection keeps a copy of the provider state that can be read
+// concurrently by the admin API. All the methods do nothing on a nil receiver.
+type inspection struct {
+	m         sync.Mutex
+	zones     ZonesInfo
+	failCount int
+	lastApply *ApplySummary
+}
+
+// setZones records the zones from the last fetch.
+func (i *inspection) setZones(mapper zoneIDName, filter *endpoint.DomainFilter, updated time.Time, cacheExpiry *time.Time) {
+	if i == nil {
+		return
+	}
+	zones := make([]ZoneInfo, 0, len(mapper))
+	for id, entry := range mapper {
+		zones = append(zones, ZoneInfo{
+			ID:      id,
+			Name:    entry.zone.Name,
+			Managed: filter == nil || filter.Match(entry.zone.Name),
+			Project: entry.project,
+		})
+	}
+	sort.Slice(zones, func(a, b int) bool {
+		return zones[a].Name < zones[b].Name
+	})
+	updated = updated.UTC()
+	i.m.Lock()
+	defer i.m.Unlock()
+	i.zones = ZonesInfo{
+		Zones:       zones,
+		Updated:     &updated,
+		CacheExpiry: cacheExpiry,
+	}
+}
+
+// setFailCount records the current fail count.
+func (i *inspection) setFailCount(failCount int) {
+	if i == nil {
+		return
+	}
+	i.m.Lock()
+	defer i.m.Unlock()
+	i.failCount = failCount
+}
+
+// setLastApply records the summary of the last ApplyChanges call.
+func (i *inspection) setLastApply(summary *ApplySummary) {
+	if i == nil {
+		return
+	}
+	i.m.Lock()
+	defer i.m.Unlock()
+	i.lastApply = summary
+}
+
+// getZones returns the zones from the last fetch.
+func (i *inspection) getZones() ZonesInfo {
+	if i == nil {
+		return ZonesInfo{Zones: []ZoneInfo{}}
+	}
+	i.m.Lock()
+	defer i.m.Unlock()
+	zones := i.zones
+	if zones.Zones == nil {
+		zones.Zones = []ZoneInfo{}
+	}
+	return zones
+}
+
+// getFailCount returns the current fail count.
+func (i *inspection) getFailCount() int {
+	if i == nil {
+		return 0
+	}
+	i.m.Lock()
+	defer i.m.Unlock()
+	return i.failCount
+}
+
+// getLastApply returns the summary of the last ApplyChanges call, or nil if
+// no changes were applied yet.
+func (i *inspection) getLastApply() *ApplySummary {
+	if i == nil {
+		return nil
+	}
+	i.m.Lock()
+	defer i.m.Unlock()
+	return i.lastApply
+}
+
+// InspectZones returns the zones known to the provider, with their IDs.
+func (p *HetznerProvider) InspectZones() any {
+	return p.inspection.getZones()
+}
+
+// InspectFailures returns the current fail count.
+func (p *HetznerProvider) InspectFailures() any {
+	return FailuresInfo{
+		FailCount:    p.inspection.getFailCount(),
+		MaxFailCount: p.maxFailCount,
+	}
+}
+
+// InspectLastApply returns the summary of the last ApplyChanges call.
+func (p *HetznerProvider) InspectLastApply() any {
+	return p.inspection.getLastApply()
+}
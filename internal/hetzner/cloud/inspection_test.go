@@ -0,0 +1,195 @@
+/*
+ * Inspection - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/fakeapi"
+	"external-dns-hetzner-webhook/internal/hetzner"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func Test_newApplySummary(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			changes *plan.Changes
+			err     error
+		}
+		expected ApplySummary
+	}
+
+	ep := &endpoint.Endpoint{DNSName: "www.alpha.com", RecordType: "A"}
+	started := time.Now().Add(-time.Second)
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		actual := newApplySummary(inp.changes, started, inp.err)
+		assert.Equal(t, started.UTC(), actual.Started)
+		assert.GreaterOrEqual(t, actual.DurationMs, int64(1000))
+		actual.Started = time.Time{}
+		actual.DurationMs = 0
+		assert.Equal(t, exp, *actual)
+	}
+
+	testCases := []testCase{
+		{
+			name: "successful changes",
+			input: struct {
+				changes *plan.Changes
+				err     error
+			}{
+				changes: &plan.Changes{
+					Create:    []*endpoint.Endpoint{ep, ep},
+					UpdateOld: []*endpoint.Endpoint{ep},
+					UpdateNew: []*endpoint.Endpoint{ep},
+					Delete:    []*endpoint.Endpoint{ep, ep, ep},
+				},
+			},
+			expected: ApplySummary{Creates: 2, Updates: 1, Deletes: 3},
+		},
+		{
+			name: "failed changes",
+			input: struct {
+				changes *plan.Changes
+				err     error
+			}{
+				changes: &plan.Changes{Create: []*endpoint.Endpoint{ep}},
+				err:     errors.New("test error"),
+			},
+			expected: ApplySummary{Creates: 1, Error: "test error"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_inspection_nil(t *testing.T) {
+	var i *inspection
+	i.setZones(zoneIDName{}, nil, time.Now(), nil)
+	i.setFailCount(1)
+	i.setLastApply(&ApplySummary{})
+	assert.Equal(t, ZonesInfo{Zones: []ZoneInfo{}}, i.getZones())
+	assert.Equal(t, 0, i.getFailCount())
+	assert.Nil(t, i.getLastApply())
+}
+
+func Test_inspection_setZones(t *testing.T) {
+	mapper := zoneIDName{}
+	mapper.Add(&hcloud.Zone{ID: 2, Name: "beta.com"}, "beta")
+	mapper.Add(&hcloud.Zone{ID: 1, Name: "alpha.com"}, "alpha")
+	filter := endpoint.NewDomainFilter([]string{"alpha.com"})
+	updated := time.Now()
+	expiry := updated.Add(time.Minute)
+
+	i := &inspection{}
+	assert.Equal(t, []ZoneInfo{}, i.getZones().Zones)
+	i.setZones(mapper, filter, updated, &expiry)
+	actual := i.getZones()
+	assert.Equal(t, []ZoneInfo{
+		{ID: 1, Name: "alpha.com", Managed: true, Project: "alpha"},
+		{ID: 2, Name: "beta.com", Managed: false, Project: "beta"},
+	}, actual.Zones)
+	assert.True(t, updated.Equal(*actual.Updated))
+	assert.Equal(t, &expiry, actual.CacheExpiry)
+}
+
+func Test_HetznerProvider_Inspect(t *testing.T) {
+	client := &mockClient{
+		getZones: zonesResponse{
+			zones: []*hcloud.Zone{{ID: 1, Name: "alpha.com"}},
+		},
+	}
+	obj := &HetznerProvider{
+		client:       client,
+		batchSize:    100,
+		maxFailCount: 3,
+		domainFilter: endpoint.NewDomainFilter([]string{}),
+		degraded:     make(chan error, 1),
+		inspection:   &inspection{},
+	}
+	assert.Nil(t, obj.InspectLastApply())
+
+	changes := &plan.Changes{
+		Delete: []*endpoint.Endpoint{{DNSName: "www.beta.com", RecordType: "A", Targets: endpoint.Targets{"1.1.1.1"}}},
+	}
+	err := obj.ApplyChanges(context.Background(), changes)
+	assert.Nil(t, err)
+	zones := obj.InspectZones().(ZonesInfo)
+	assert.Equal(t, []ZoneInfo{{ID: 1, Name: "alpha.com", Managed: true}}, zones.Zones)
+	assert.Nil(t, zones.CacheExpiry)
+	lastApply := obj.InspectLastApply().(*ApplySummary)
+	assert.Equal(t, 1, lastApply.Deletes)
+	assert.Empty(t, lastApply.Error)
+
+	client.getZones = zonesResponse{err: errors.New("test zones error")}
+	err = obj.ApplyChanges(context.Background(), changes)
+	assert.NotNil(t, err)
+	assert.Equal(t, FailuresInfo{FailCount: 1, MaxFailCount: 3}, obj.InspectFailures())
+	lastApply = obj.InspectLastApply().(*ApplySummary)
+	assert.Equal(t, "test zones error", lastApply.Error)
+}
+
+// Test_HetznerProvider_Inspect_concurrent tests that the admin API can inspect
+// the provider while the records are read. Run it with -race.
+func Test_HetznerProvider_Inspect_concurrent(t *testing.T) {
+	fake := fakeapi.NewServer("TEST_API_KEY")
+	defer fake.Close()
+	fake.AddZone("alpha.com", 3600)
+	p, err := NewHetznerProvider(&hetzner.Configuration{
+		APIKey:       "TEST_API_KEY",
+		APIEndpoint:  fake.URL,
+		BatchSize:    50,
+		SlashEscSeq:  "--slash--",
+		MaxFailCount: 3,
+	}, nil)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 10 {
+			_, err := p.Records(context.Background())
+			assert.Nil(t, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 10 {
+			_ = p.InspectZones()
+			_ = p.InspectFailures()
+			_ = p.InspectLastApply()
+		}
+	}()
+	wg.Wait()
+}
@@ -20,8 +20,11 @@ package hetznercloud
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"regexp"
+	"slices"
 	"strings"
+	"text/template"
 
 	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/external-dns/endpoint"
@@ -58,14 +61,14 @@ func formatLabels(labels map[string]string) string {
 // map.
 func getProviderSpecific(slash string, labels map[string]string) endpoint.ProviderSpecific {
 	if len(labels) == 0 {
-		log.Debug("No labels found")
+		providerLog.Debug("No labels found")
 		return nil
 	}
 	ps := make(endpoint.ProviderSpecific, 0)
 	for label, value := range labels {
 		label = strings.ReplaceAll(label, "/", slash)
 		name := providerPrefix + label
-		log.Debugf("Adding provider-specific: [%s: %s]", name, value)
+		providerLog.Debugf("Adding provider-specific: [%s: %s]", name, value)
 		ps = append(ps, endpoint.ProviderSpecificProperty{
 			Name:  name,
 			Value: value,
@@ -105,7 +108,7 @@ func extractHetznerLabels(slash string, ps endpoint.ProviderSpecific) (map[strin
 	labels := make(map[string]string, 0)
 	for _, p := range ps {
 		if strings.HasPrefix(p.Name, providerPrefix) {
-			log.Debugf("Processing provider-specific: [%s: %s]", p.Name, p.Value)
+			providerLog.Debugf("Processing provider-specific: [%s: %s]", p.Name, p.Value)
 			label := strings.TrimPrefix(p.Name, providerPrefix)
 			label = strings.ReplaceAll(label, slash, "/")
 			value := p.Value
@@ -116,8 +119,122 @@ func extractHetznerLabels(slash string, ps endpoint.ProviderSpecific) (map[strin
 			}
 			labels[label] = value
 		} else {
-			log.Debugf("Ignoring provider-specific: [%s: %s]", p.Name, p.Value)
+			providerLog.Debugf("Ignoring provider-specific: [%s: %s]", p.Name, p.Value)
 		}
 	}
 	return labels, nil
 }
+
+// labelTemplateData contains the fields that can be used in the default label
+// templates.
+type labelTemplateData struct {
+	// Zone name
+	Zone string
+	// Record type
+	Type string
+	// Record name, relative to the zone ("@" for the apex)
+	Name string
+}
+
+// defaultLabels associates each default label with its value template.
+type defaultLabels map[string]*template.Template
+
+// newDefaultLabels parses the default labels from a list of "label=value"
+// entries. The values can be templates using the fields of labelTemplateData.
+// Empty entries are ignored.
+func newDefaultLabels(entries []string) (defaultLabels, error) {
+	dl := make(defaultLabels, 0)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		label, value, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("default label \"%s\" is not in the label=value format", entry)
+		}
+		label = strings.TrimSpace(label)
+		if err := checkLabel(label); err != nil {
+			return nil, fmt.Errorf("cannot process default label \"%s\": %w", entry, err)
+		}
+		tmpl, err := template.New(label).Option("missingkey=error").Parse(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse template for default label [%s]: %w", label, err)
+		}
+		// Check that the template only references existing fields.
+		sample := labelTemplateData{Zone: "example.com", Type: "A", Name: "www"}
+		if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+			return nil, fmt.Errorf("cannot execute template for default label [%s]: %w", label, err)
+		}
+		dl[label] = tmpl
+	}
+	return dl, nil
+}
+
+// render returns the default labels for a record. Labels whose value cannot
+// be rendered or is not acceptable are skipped with a warning. The returned
+// map is always instantiated.
+func (dl defaultLabels) render(zoneName, recordType, name string) map[string]string {
+	labels := make(map[string]string, len(dl))
+	data := labelTemplateData{Zone: zoneName, Type: recordType, Name: name}
+	for label, tmpl := range dl {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			providerLog.WithFields(log.Fields{
+				"zoneName":   zoneName,
+				"dnsName":    name,
+				"recordType": recordType,
+			}).Warnf("Default label [%s] skipped: %s", label, err.Error())
+			continue
+		}
+		value := sb.String()
+		if err := checkValue(value); err != nil {
+			providerLog.WithFields(log.Fields{
+				"zoneName":   zoneName,
+				"dnsName":    name,
+				"recordType": recordType,
+			}).Warnf("Default label [%s] skipped: %s", label, err.Error())
+			continue
+		}
+		labels[label] = value
+	}
+	return labels
+}
+
+// mergeLabels merges the default labels with the endpoint labels. The
+// endpoint labels take precedence over the default ones. The returned map is
+// nil only if both arguments are empty.
+func mergeLabels(defaults, labels map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return labels
+	}
+	merged := make(map[string]string, len(defaults)+len(labels))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return merged
+}
+
+// addDefaultLabels adds to the endpoint provider-specific properties the
+// rendered default labels that the endpoint does not set. The desired
+// endpoints then carry the labels the records must have, so that the records
+// with missing or stale default labels are updated.
+func addDefaultLabels(slash string, defaults map[string]string, ep *endpoint.Endpoint) {
+	if slash == "" {
+		slash = slashDefault
+	}
+	for _, label := range slices.Sorted(maps.Keys(defaults)) {
+		name := providerPrefix + strings.ReplaceAll(label, "/", slash)
+		if _, found := ep.GetProviderSpecificProperty(name); found {
+			continue
+		}
+		providerLog.Debugf("Adding default label: [%s: %s]", label, defaults[label])
+		ep.ProviderSpecific = append(ep.ProviderSpecific, endpoint.ProviderSpecificProperty{
+			Name:  name,
+			Value: defaults[label],
+		})
+	}
+}
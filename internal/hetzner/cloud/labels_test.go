@@ -440,3 +440,320 @@ func Test_extractHetznerLabels(t *testing.T) {
 		})
 	}
 }
+
+// Test_newDefaultLabels tests newDefaultLabels().
+func Test_newDefaultLabels(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    []string
+		expected struct {
+			labels []string
+			err    error
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		actual, err := newDefaultLabels(tc.input)
+		if !assertError(t, exp.err, err) {
+			labels := make([]string, 0, len(actual))
+			for label := range actual {
+				labels = append(labels, label)
+			}
+			assert.ElementsMatch(t, exp.labels, labels)
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name:  "no labels",
+			input: []string{""},
+			expected: struct {
+				labels []string
+				err    error
+			}{
+				labels: []string{},
+			},
+		},
+		{
+			name:  "static and templated labels",
+			input: []string{"cost-center=cc1", " alpha.com/cluster = {{.Zone}} ", "type={{.Type}}"},
+			expected: struct {
+				labels []string
+				err    error
+			}{
+				labels: []string{"cost-center", "alpha.com/cluster", "type"},
+			},
+		},
+		{
+			name:  "missing value",
+			input: []string{"cost-center"},
+			expected: struct {
+				labels []string
+				err    error
+			}{
+				err: errors.New("default label \"cost-center\" is not in the label=value format"),
+			},
+		},
+		{
+			name:  "wrong label",
+			input: []string{"-wrong=value"},
+			expected: struct {
+				labels []string
+				err    error
+			}{
+				err: errors.New("cannot process default label \"-wrong=value\": label [-wrong] is not acceptable"),
+			},
+		},
+		{
+			name:  "wrong template",
+			input: []string{"zone={{.Zone"},
+			expected: struct {
+				labels []string
+				err    error
+			}{
+				err: errors.New("cannot parse template for default label [zone]: template: zone:1: unclosed action"),
+			},
+		},
+		{
+			name:  "unknown field",
+			input: []string{"zone={{.Unknown}}"},
+			expected: struct {
+				labels []string
+				err    error
+			}{
+				err: errors.New("cannot execute template for default label [zone]: template: zone:1:2: executing \"zone\" at <.Unknown>: can't evaluate field Unknown in type hetznercloud.labelTemplateData"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_defaultLabels_render tests defaultLabels.render().
+func Test_defaultLabels_render(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			entries    []string
+			zoneName   string
+			recordType string
+			recName    string
+		}
+		expected map[string]string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		dl, err := newDefaultLabels(inp.entries)
+		assert.Nil(t, err)
+		actual := dl.render(inp.zoneName, inp.recordType, inp.recName)
+		assert.Equal(t, tc.expected, actual)
+	}
+
+	testCases := []testCase{
+		{
+			name: "nil default labels",
+			input: struct {
+				entries    []string
+				zoneName   string
+				recordType string
+				recName    string
+			}{
+				zoneName:   "alpha.com",
+				recordType: "A",
+				recName:    "www",
+			},
+			expected: map[string]string{},
+		},
+		{
+			name: "templated labels",
+			input: struct {
+				entries    []string
+				zoneName   string
+				recordType string
+				recName    string
+			}{
+				entries:    []string{"cost-center=cc1", "zone={{.Zone}}", "record={{.Name}}-{{.Type}}"},
+				zoneName:   "alpha.com",
+				recordType: "A",
+				recName:    "www",
+			},
+			expected: map[string]string{
+				"cost-center": "cc1",
+				"zone":        "alpha.com",
+				"record":      "www-A",
+			},
+		},
+		{
+			name: "unacceptable value skipped",
+			input: struct {
+				entries    []string
+				zoneName   string
+				recordType string
+				recName    string
+			}{
+				entries:    []string{"cost-center=cc1", "record={{.Name}}"},
+				zoneName:   "alpha.com",
+				recordType: "A",
+				recName:    "@",
+			},
+			expected: map[string]string{
+				"cost-center": "cc1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_mergeLabels tests mergeLabels().
+func Test_mergeLabels(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			defaults map[string]string
+			labels   map[string]string
+		}
+		expected map[string]string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		actual := mergeLabels(inp.defaults, inp.labels)
+		assert.Equal(t, tc.expected, actual)
+	}
+
+	testCases := []testCase{
+		{
+			name: "no defaults",
+			input: struct {
+				defaults map[string]string
+				labels   map[string]string
+			}{
+				labels: map[string]string{"env": "test"},
+			},
+			expected: map[string]string{"env": "test"},
+		},
+		{
+			name: "no labels",
+			input: struct {
+				defaults map[string]string
+				labels   map[string]string
+			}{
+				defaults: map[string]string{"cluster": "c1"},
+			},
+			expected: map[string]string{"cluster": "c1"},
+		},
+		{
+			name: "endpoint labels take precedence",
+			input: struct {
+				defaults map[string]string
+				labels   map[string]string
+			}{
+				defaults: map[string]string{"cluster": "c1", "cost-center": "cc1"},
+				labels:   map[string]string{"cluster": "c2", "env": "test"},
+			},
+			expected: map[string]string{
+				"cluster":     "c2",
+				"cost-center": "cc1",
+				"env":         "test",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_addDefaultLabels tests addDefaultLabels().
+func Test_addDefaultLabels(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			defaults map[string]string
+			ps       endpoint.ProviderSpecific
+		}
+		expected endpoint.ProviderSpecific
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		ep := &endpoint.Endpoint{
+			DNSName:          "www.alpha.com",
+			RecordType:       "A",
+			ProviderSpecific: inp.ps,
+		}
+		addDefaultLabels("--slash--", inp.defaults, ep)
+		assert.Equal(t, tc.expected, ep.ProviderSpecific)
+	}
+
+	testCases := []testCase{
+		{
+			name: "no defaults",
+			input: struct {
+				defaults map[string]string
+				ps       endpoint.ProviderSpecific
+			}{
+				ps: endpoint.ProviderSpecific{
+					{Name: "webhook/hetzner-label-env", Value: "test"},
+				},
+			},
+			expected: endpoint.ProviderSpecific{
+				{Name: "webhook/hetzner-label-env", Value: "test"},
+			},
+		},
+		{
+			name: "missing defaults added",
+			input: struct {
+				defaults map[string]string
+				ps       endpoint.ProviderSpecific
+			}{
+				defaults: map[string]string{
+					"alpha.com/cluster": "c1",
+					"cost-center":       "cc1",
+				},
+				ps: endpoint.ProviderSpecific{
+					{Name: "webhook/hetzner-label-cost-center", Value: "cc2"},
+					{Name: "webhook/hetzner-label-env", Value: "test"},
+					{Name: "other-property", Value: "c1"},
+				},
+			},
+			expected: endpoint.ProviderSpecific{
+				{Name: "webhook/hetzner-label-cost-center", Value: "cc2"},
+				{Name: "webhook/hetzner-label-env", Value: "test"},
+				{Name: "other-property", Value: "c1"},
+				{Name: "webhook/hetzner-label-alpha.com--slash--cluster", Value: "c1"},
+			},
+		},
+		{
+			name: "no provider-specific properties",
+			input: struct {
+				defaults map[string]string
+				ps       endpoint.ProviderSpecific
+			}{
+				defaults: map[string]string{"cluster": "c1"},
+			},
+			expected: endpoint.ProviderSpecific{
+				{Name: "webhook/hetzner-label-cluster", Value: "c1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
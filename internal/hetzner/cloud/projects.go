@@ -0,0 +1,397 @@
+/*
+ * Projects - routing of the API calls to the clients of several projects.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/hetzner"
+	"external-dns-hetzner-webhook/internal/metrics"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// actionOwnerTTL is the time after which the project of an action that was
+// never waited for is forgotten.
+const actionOwnerTTL = time.Hour
+
+// keyWatcher is the interface of the clients that watch their API key files.
+type keyWatcher interface {
+	watchAPIKey(ctx context.Context)
+}
+
+// zoneRouter is the interface of the clients that route the calls on a zone
+// to the project that owns it.
+type zoneRouter interface {
+	// routedZones returns the zones used to route the calls, with the
+	// projects that own them.
+	routedZones() zoneIDName
+}
+
+// projectClient is the client of a project.
+type projectClient struct {
+	name   string
+	client apiClient
+}
+
+// actionOwner is the project that started an action.
+type actionOwner struct {
+	project projectClient
+	started time.Time
+}
+
+// zoneListing is a listing of the zones of all the projects in progress.
+type zoneListing struct {
+	zones    zoneIDName
+	pending  []projectClient
+	lastPage int
+	// Projects whose zones were all listed
+	done map[string]bool
+	// Project that listed each zone name
+	owners map[string]string
+}
+
+// newZoneListing starts a listing of the zones of the projects.
+func newZoneListing(projects []projectClient) *zoneListing {
+	return &zoneListing{
+		zones:   zoneIDName{},
+		pending: projects,
+		done:    make(map[string]bool, len(projects)),
+		owners:  make(map[string]string),
+	}
+}
+
+// projectClients routes the API calls to the clients of several projects.
+// The zones are listed from all the projects and each zone is assigned to
+// the project it was listed from. The calls on a zone, on its RRSets and on
+// the resulting actions are then sent to the client of that project.
+type projectClients struct {
+	projects []projectClient
+	byName   map[string]projectClient
+	listing  *zoneListing
+	lm       sync.Mutex
+	m        sync.Mutex
+	zones    zoneIDName
+	actions  map[int64]actionOwner
+}
+
+// newProjectClients creates a router for the clients of the projects.
+func newProjectClients(projects []projectClient) *projectClients {
+	byName := make(map[string]projectClient, len(projects))
+	for _, project := range projects {
+		byName[project.name] = project
+	}
+	return &projectClients{
+		projects: projects,
+		byName:   byName,
+		zones:    zoneIDName{},
+		actions:  make(map[int64]actionOwner),
+	}
+}
+
+// newClient creates the API client for the configured projects. A single
+// project uses its own client directly.
+func newClient(config *hetzner.Configuration, m *metrics.OpenMetrics) (apiClient, error) {
+	projects, err := config.GetProjects()
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) == 1 {
+		return NewHetznerCloud(projects[0], m)
+	}
+	clients := make([]projectClient, 0, len(projects))
+	for _, project := range projects {
+		client, err := NewHetznerCloud(project, m)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %w", project.Name, err)
+		}
+		clients = append(clients, projectClient{name: project.Name, client: client})
+	}
+	providerLog.Infof("Using %d projects.", len(clients))
+	return newProjectClients(clients), nil
+}
+
+// lastPage returns the last page of a paginated response, or 0 if unknown.
+func lastPage(r *hcloud.Response) int {
+	if r == nil || r.Meta.Pagination == nil {
+		return 0
+	}
+	return r.Meta.Pagination.LastPage
+}
+
+// routedZones returns the zones used to route the calls.
+func (pc *projectClients) routedZones() zoneIDName {
+	pc.m.Lock()
+	defer pc.m.Unlock()
+	return pc.zones
+}
+
+// zoneOwner returns the project that owns a zone.
+func (pc *projectClients) zoneOwner(zone *hcloud.Zone) (projectClient, error) {
+	if zone == nil {
+		return projectClient{}, errors.New("unknown zone")
+	}
+	pc.m.Lock()
+	defer pc.m.Unlock()
+	entry, found := pc.zones[zone.ID]
+	if !found {
+		return projectClient{}, fmt.Errorf("zone %s does not belong to any configured project", zone.Name)
+	}
+	return pc.byName[entry.project], nil
+}
+
+// rrsetOwner returns the project that owns the zone of an RRSet.
+func (pc *projectClients) rrsetOwner(rrset *hcloud.ZoneRRSet) (projectClient, error) {
+	if rrset == nil {
+		return projectClient{}, errors.New("unknown RRSet")
+	}
+	return pc.zoneOwner(rrset.Zone)
+}
+
+// setActionOwner remembers the project that started an action, forgetting the
+// actions that were never waited for.
+func (pc *projectClients) setActionOwner(action *hcloud.Action, project projectClient) {
+	if action == nil {
+		return
+	}
+	now := time.Now()
+	pc.m.Lock()
+	defer pc.m.Unlock()
+	for id, owner := range pc.actions {
+		if now.Sub(owner.started) > actionOwnerTTL {
+			delete(pc.actions, id)
+		}
+	}
+	pc.actions[action.ID] = actionOwner{project: project, started: now}
+}
+
+// takeActionOwner returns the project that started an action and forgets it.
+func (pc *projectClients) takeActionOwner(action *hcloud.Action) (projectClient, error) {
+	if action == nil {
+		return projectClient{}, errors.New("unknown action")
+	}
+	pc.m.Lock()
+	defer pc.m.Unlock()
+	owner, found := pc.actions[action.ID]
+	if !found {
+		return projectClient{}, fmt.Errorf("action %d was not started by any configured project", action.ID)
+	}
+	delete(pc.actions, action.ID)
+	return owner.project, nil
+}
+
+// GetZones returns a page of the zones of all the projects. The first page
+// starts a new listing, and the next pages are only requested to the projects
+// that have them. The returned pagination ends with the last page of the
+// longest project. Once the listing is complete, its zones are used to route
+// the calls. A zone name listed by two projects fails the listing.
+func (pc *projectClients) GetZones(ctx context.Context, opts hcloud.ZoneListOpts) ([]*hcloud.Zone, *hcloud.Response, error) {
+	pc.lm.Lock()
+	defer pc.lm.Unlock()
+	page := max(opts.Page, 1)
+	if page == 1 || pc.listing == nil {
+		pc.listing = newZoneListing(pc.projects)
+	}
+	l := pc.listing
+	zones := []*hcloud.Zone{}
+	pending := []projectClient{}
+	for _, project := range l.pending {
+		projectZones, resp, err := project.client.GetZones(ctx, opts)
+		if err != nil {
+			pc.failListing(l, project.name, "")
+			return nil, resp, fmt.Errorf("project %s: %w", project.name, err)
+		}
+		for _, zone := range projectZones {
+			if owner, found := l.owners[zone.Name]; found && owner != project.name {
+				pc.failListing(l, "", zone.Name)
+				return nil, resp, fmt.Errorf("zone %s is in both projects %s and %s", zone.Name, owner, project.name)
+			}
+			l.owners[zone.Name] = project.name
+			l.zones.Add(zone, project.name)
+		}
+		zones = append(zones, projectZones...)
+		if last := lastPage(resp); last > page {
+			l.lastPage = max(l.lastPage, last)
+			pending = append(pending, project)
+		} else {
+			l.done[project.name] = true
+		}
+	}
+	l.pending = pending
+	last := page
+	if len(pending) > 0 {
+		last = l.lastPage
+	} else {
+		pc.listing = nil
+		pc.m.Lock()
+		pc.zones = l.zones
+		pc.m.Unlock()
+	}
+	merged := &hcloud.Response{
+		Meta: hcloud.Meta{
+			Pagination: &hcloud.Pagination{Page: page, PerPage: opts.PerPage, LastPage: last},
+		},
+	}
+	return zones, merged, nil
+}
+
+// failListing ends a listing that failed and updates the routing with what is
+// known. The projects whose zones were all listed own the zones as listed. The
+// project that failed no longer owns any zone, and neither does a zone name
+// listed by two projects. The other projects keep the zones of the last
+// complete listing, unless another project listed them.
+func (pc *projectClients) failListing(l *zoneListing, failed, conflict string) {
+	pc.listing = nil
+	zones := zoneIDName{}
+	pc.m.Lock()
+	defer pc.m.Unlock()
+	for id, entry := range pc.zones {
+		if entry.project == failed || l.done[entry.project] {
+			continue
+		}
+		if owner, found := l.owners[entry.zone.Name]; found && owner != entry.project {
+			continue
+		}
+		zones[id] = entry
+	}
+	for id, entry := range l.zones {
+		if l.done[entry.project] {
+			zones[id] = entry
+		}
+	}
+	for id, entry := range zones {
+		if entry.zone.Name == conflict {
+			delete(zones, id)
+		}
+	}
+	pc.zones = zones
+}
+
+// GetRRSets returns the RRSets of a zone from the project that owns it.
+func (pc *projectClients) GetRRSets(ctx context.Context, zone *hcloud.Zone, opts hcloud.ZoneRRSetListOpts) ([]*hcloud.ZoneRRSet, *hcloud.Response, error) {
+	project, err := pc.zoneOwner(zone)
+	if err != nil {
+		return nil, nil, err
+	}
+	return project.client.GetRRSets(ctx, zone, opts)
+}
+
+// CreateRRSet creates an RRSet in the project that owns the zone.
+func (pc *projectClients) CreateRRSet(ctx context.Context, zone *hcloud.Zone, opts hcloud.ZoneRRSetCreateOpts) (hcloud.ZoneRRSetCreateResult, *hcloud.Response, error) {
+	project, err := pc.zoneOwner(zone)
+	if err != nil {
+		return hcloud.ZoneRRSetCreateResult{}, nil, err
+	}
+	result, resp, err := project.client.CreateRRSet(ctx, zone, opts)
+	pc.setActionOwner(result.Action, project)
+	return result, resp, err
+}
+
+// UpdateRRSetTTL updates the TTL of an RRSet in the project that owns it.
+func (pc *projectClients) UpdateRRSetTTL(ctx context.Context, rrset *hcloud.ZoneRRSet, opts hcloud.ZoneRRSetChangeTTLOpts) (*hcloud.Action, *hcloud.Response, error) {
+	project, err := pc.rrsetOwner(rrset)
+	if err != nil {
+		return nil, nil, err
+	}
+	action, resp, err := project.client.UpdateRRSetTTL(ctx, rrset, opts)
+	pc.setActionOwner(action, project)
+	return action, resp, err
+}
+
+// UpdateRRSetRecords updates the records of an RRSet in the project that owns
+// it.
+func (pc *projectClients) UpdateRRSetRecords(ctx context.Context, rrset *hcloud.ZoneRRSet, opts hcloud.ZoneRRSetSetRecordsOpts) (*hcloud.Action, *hcloud.Response, error) {
+	project, err := pc.rrsetOwner(rrset)
+	if err != nil {
+		return nil, nil, err
+	}
+	action, resp, err := project.client.UpdateRRSetRecords(ctx, rrset, opts)
+	pc.setActionOwner(action, project)
+	return action, resp, err
+}
+
+// UpdateRRSetLabels updates the labels of an RRSet in the project that owns
+// it.
+func (pc *projectClients) UpdateRRSetLabels(ctx context.Context, rrset *hcloud.ZoneRRSet, opts hcloud.ZoneRRSetUpdateOpts) (*hcloud.ZoneRRSet, *hcloud.Response, error) {
+	project, err := pc.rrsetOwner(rrset)
+	if err != nil {
+		return nil, nil, err
+	}
+	return project.client.UpdateRRSetLabels(ctx, rrset, opts)
+}
+
+// DeleteRRSet deletes an RRSet from the project that owns it.
+func (pc *projectClients) DeleteRRSet(ctx context.Context, rrset *hcloud.ZoneRRSet) (hcloud.ZoneRRSetDeleteResult, *hcloud.Response, error) {
+	project, err := pc.rrsetOwner(rrset)
+	if err != nil {
+		return hcloud.ZoneRRSetDeleteResult{}, nil, err
+	}
+	result, resp, err := project.client.DeleteRRSet(ctx, rrset)
+	pc.setActionOwner(result.Action, project)
+	return result, resp, err
+}
+
+// ExportZonefile exports the zonefile from the project that owns the zone.
+func (pc *projectClients) ExportZonefile(ctx context.Context, zone *hcloud.Zone) (hcloud.ZoneExportZonefileResult, *hcloud.Response, error) {
+	project, err := pc.zoneOwner(zone)
+	if err != nil {
+		return hcloud.ZoneExportZonefileResult{}, nil, err
+	}
+	return project.client.ExportZonefile(ctx, zone)
+}
+
+// ImportZonefile imports the zonefile in the project that owns the zone.
+func (pc *projectClients) ImportZonefile(ctx context.Context, zone *hcloud.Zone, opts hcloud.ZoneImportZonefileOpts) (*hcloud.Action, *hcloud.Response, error) {
+	project, err := pc.zoneOwner(zone)
+	if err != nil {
+		return nil, nil, err
+	}
+	action, resp, err := project.client.ImportZonefile(ctx, zone, opts)
+	pc.setActionOwner(action, project)
+	return action, resp, err
+}
+
+// WaitForAction waits for an action using the client of the project that
+// started it.
+func (pc *projectClients) WaitForAction(ctx context.Context, action *hcloud.Action) error {
+	project, err := pc.takeActionOwner(action)
+	if err != nil {
+		return err
+	}
+	return project.client.WaitForAction(ctx, action)
+}
+
+// watchAPIKey watches the API key files of all the projects until the context
+// is done.
+func (pc *projectClients) watchAPIKey(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, project := range pc.projects {
+		if w, ok := project.client.(keyWatcher); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.watchAPIKey(ctx)
+			}()
+		}
+	}
+	wg.Wait()
+}
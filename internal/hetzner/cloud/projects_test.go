@@ -0,0 +1,403 @@
+/*
+ * Projects - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/fakeapi"
+	"external-dns-hetzner-webhook/internal/hetzner"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+// paginatedResponse returns a response with the given last page.
+func paginatedResponse(lastPage int) *hcloud.Response {
+	return &hcloud.Response{
+		Meta: hcloud.Meta{
+			Pagination: &hcloud.Pagination{LastPage: lastPage},
+		},
+	}
+}
+
+// newTestProjectClients returns a router for two mock projects, with the
+// zones of both projects already listed.
+func newTestProjectClients(t *testing.T) (*projectClients, *mockClient, *mockClient) {
+	alpha := &mockClient{
+		getZones: zonesResponse{
+			zones: []*hcloud.Zone{{ID: 1, Name: "alpha.com"}},
+			resp:  paginatedResponse(1),
+		},
+		createRRSet: createRRSetResponse{
+			result: hcloud.ZoneRRSetCreateResult{Action: &hcloud.Action{ID: 10}},
+		},
+		importZonefile: actionResponse{action: &hcloud.Action{ID: 11}},
+	}
+	beta := &mockClient{
+		getZones: zonesResponse{
+			zones: []*hcloud.Zone{{ID: 2, Name: "beta.com"}},
+			resp:  paginatedResponse(2),
+		},
+		updateRRSetTTL: actionResponse{action: &hcloud.Action{ID: 20}},
+	}
+	pc := newProjectClients([]projectClient{
+		{name: "alpha", client: alpha},
+		{name: "beta", client: beta},
+	})
+	_, err := fetchZones(context.Background(), pc, 0)
+	assert.Nil(t, err)
+	return pc, alpha, beta
+}
+
+// newFakeProject starts a fake API with the given zones, numbered from
+// firstID, and returns a project using it.
+func newFakeProject(t *testing.T, name string, firstID int64, zones ...string) (projectClient, *fakeapi.Server) {
+	fake := fakeapi.NewServer("TEST_API_KEY")
+	t.Cleanup(fake.Close)
+	fake.SetFirstID(firstID)
+	for _, zone := range zones {
+		fake.AddZone(zone, 3600)
+	}
+	client, err := NewHetznerCloud(hetzner.Project{Name: name, APIKey: "TEST_API_KEY", Endpoint: fake.URL}, nil)
+	assert.Nil(t, err)
+	return projectClient{name: name, client: client}, fake
+}
+
+// Test_newClient tests newClient().
+func Test_newClient(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    hetzner.Configuration
+		expected struct {
+			router bool
+			err    string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		client, err := newClient(&tc.input, nil)
+		if exp.err != "" {
+			assert.EqualError(t, err, exp.err)
+			return
+		}
+		assert.Nil(t, err)
+		_, isRouter := client.(*projectClients)
+		assert.Equal(t, exp.router, isRouter)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "single project",
+			input: hetzner.Configuration{APIKey: "TEST_API_KEY"},
+		},
+		{
+			name: "several projects",
+			input: hetzner.Configuration{
+				APIKey:  "TEST_API_KEY",
+				APIKeys: []string{"staging=STAGING_API_KEY"},
+			},
+			expected: struct {
+				router bool
+				err    string
+			}{
+				router: true,
+			},
+		},
+		{
+			name: "invalid project",
+			input: hetzner.Configuration{
+				APIKey:      "TEST_API_KEY",
+				APIKeyFiles: []string{"staging=/nonexistent/token"},
+			},
+			expected: struct {
+				router bool
+				err    string
+			}{
+				err: "project staging: cannot read API key file: open /nonexistent/token: no such file or directory",
+			},
+		},
+		{
+			name:  "invalid configuration",
+			input: hetzner.Configuration{APIKeys: []string{"staging"}},
+			expected: struct {
+				router bool
+				err    string
+			}{
+				err: "invalid entry in HETZNER_API_KEYS: expected name=value",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_projectClients_GetZones tests projectClients.GetZones().
+func Test_projectClients_GetZones(t *testing.T) {
+	ctx := context.Background()
+	alpha, alphaAPI := newFakeProject(t, "alpha", 1, "alpha.com", "alpha.net", "alpha.org")
+	beta, betaAPI := newFakeProject(t, "beta", 100, "beta.com")
+	pc := newProjectClients([]projectClient{alpha, beta})
+
+	zones, err := fetchZones(ctx, pc, 1)
+	assert.Nil(t, err)
+	names := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		names = append(names, zone.Name)
+	}
+	assert.ElementsMatch(t, []string{"alpha.com", "alpha.net", "alpha.org", "beta.com"}, names)
+	// Each project is only asked for its own pages.
+	assert.Len(t, alphaAPI.Requests(), 3)
+	assert.Len(t, betaAPI.Requests(), 1)
+	routed := pc.routedZones()
+	assert.Len(t, routed, 4)
+	for _, entry := range routed {
+		assert.True(t, strings.HasPrefix(entry.zone.Name, entry.project+"."))
+	}
+
+	// A failed listing stops routing the zones of the failed project.
+	betaAPI.InjectError(fakeapi.Error{Status: http.StatusForbidden, Code: "forbidden", Message: "test error"})
+	_, err = fetchZones(ctx, pc, 1)
+	assert.ErrorContains(t, err, "project beta: test error")
+	routed = pc.routedZones()
+	assert.Len(t, routed, 3)
+	for _, entry := range routed {
+		assert.Equal(t, "alpha", entry.project)
+	}
+}
+
+// Test_projectClients_failListing tests the routing after a failed listing.
+func Test_projectClients_failListing(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			alpha zonesResponse
+			beta  zonesResponse
+		}
+		expected struct {
+			routed map[int64]string
+			err    string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		alpha := &mockClient{
+			getZones: zonesResponse{
+				zones: []*hcloud.Zone{{ID: 1, Name: "alpha.com"}, {ID: 2, Name: "moved.com"}},
+				resp:  paginatedResponse(1),
+			},
+		}
+		beta := &mockClient{
+			getZones: zonesResponse{
+				zones: []*hcloud.Zone{{ID: 3, Name: "beta.com"}},
+				resp:  paginatedResponse(1),
+			},
+		}
+		pc := newProjectClients([]projectClient{
+			{name: "alpha", client: alpha},
+			{name: "beta", client: beta},
+		})
+		_, err := fetchZones(context.Background(), pc, 0)
+		assert.Nil(t, err)
+
+		alpha.getZones = inp.alpha
+		beta.getZones = inp.beta
+		_, err = fetchZones(context.Background(), pc, 0)
+		assert.EqualError(t, err, exp.err)
+		routed := map[int64]string{}
+		for id, entry := range pc.routedZones() {
+			routed[id] = entry.project
+		}
+		assert.Equal(t, exp.routed, routed)
+		assert.Nil(t, pc.listing)
+	}
+
+	testCases := []testCase{
+		{
+			name: "failed project",
+			input: struct {
+				alpha zonesResponse
+				beta  zonesResponse
+			}{
+				alpha: zonesResponse{
+					zones: []*hcloud.Zone{{ID: 1, Name: "alpha.com"}, {ID: 2, Name: "moved.com"}},
+					resp:  paginatedResponse(1),
+				},
+				beta: zonesResponse{err: errors.New("test error")},
+			},
+			expected: struct {
+				routed map[int64]string
+				err    string
+			}{
+				routed: map[int64]string{1: "alpha", 2: "alpha"},
+				err:    "project beta: test error",
+			},
+		},
+		{
+			name: "zone moved from a listed project",
+			input: struct {
+				alpha zonesResponse
+				beta  zonesResponse
+			}{
+				alpha: zonesResponse{
+					zones: []*hcloud.Zone{{ID: 1, Name: "alpha.com"}},
+					resp:  paginatedResponse(1),
+				},
+				beta: zonesResponse{err: errors.New("test error")},
+			},
+			expected: struct {
+				routed map[int64]string
+				err    string
+			}{
+				routed: map[int64]string{1: "alpha"},
+				err:    "project beta: test error",
+			},
+		},
+		{
+			name: "zone in two projects",
+			input: struct {
+				alpha zonesResponse
+				beta  zonesResponse
+			}{
+				alpha: zonesResponse{
+					zones: []*hcloud.Zone{{ID: 1, Name: "alpha.com"}, {ID: 2, Name: "moved.com"}},
+					resp:  paginatedResponse(1),
+				},
+				beta: zonesResponse{
+					zones: []*hcloud.Zone{{ID: 3, Name: "beta.com"}, {ID: 4, Name: "moved.com"}},
+					resp:  paginatedResponse(1),
+				},
+			},
+			expected: struct {
+				routed map[int64]string
+				err    string
+			}{
+				routed: map[int64]string{1: "alpha", 3: "beta"},
+				err:    "zone moved.com is in both projects alpha and beta",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_projectClients_routing tests that the calls reach the owner project.
+func Test_projectClients_routing(t *testing.T) {
+	ctx := context.Background()
+	pc, alpha, beta := newTestProjectClients(t)
+	alphaZone := &hcloud.Zone{ID: 1, Name: "alpha.com"}
+	betaZone := &hcloud.Zone{ID: 2, Name: "beta.com"}
+
+	_, _, err := pc.GetRRSets(ctx, betaZone, hcloud.ZoneRRSetListOpts{})
+	assert.Nil(t, err)
+	assert.False(t, alpha.state.GetRRSetsCalled)
+	assert.True(t, beta.state.GetRRSetsCalled)
+
+	_, _, err = pc.CreateRRSet(ctx, alphaZone, hcloud.ZoneRRSetCreateOpts{})
+	assert.Nil(t, err)
+	assert.True(t, alpha.state.CreateRRSetCalled)
+	assert.False(t, beta.state.CreateRRSetCalled)
+
+	rrset := &hcloud.ZoneRRSet{Zone: betaZone}
+	_, _, err = pc.UpdateRRSetTTL(ctx, rrset, hcloud.ZoneRRSetChangeTTLOpts{})
+	assert.Nil(t, err)
+	_, _, err = pc.UpdateRRSetRecords(ctx, rrset, hcloud.ZoneRRSetSetRecordsOpts{})
+	assert.Nil(t, err)
+	_, _, err = pc.UpdateRRSetLabels(ctx, rrset, hcloud.ZoneRRSetUpdateOpts{})
+	assert.Nil(t, err)
+	_, _, err = pc.DeleteRRSet(ctx, rrset)
+	assert.Nil(t, err)
+	assert.Equal(t, mockClientState{
+		GetZonesCalled:           true,
+		GetRRSetsCalled:          true,
+		UpdateRRSetTTLCalled:     true,
+		UpdateRRSetRecordsCalled: true,
+		UpdateRRSetLabelsCalled:  true,
+		DeleteRRSetCalled:        true,
+	}, beta.state)
+
+	_, _, err = pc.ExportZonefile(ctx, alphaZone)
+	assert.Nil(t, err)
+	_, _, err = pc.ImportZonefile(ctx, alphaZone, hcloud.ZoneImportZonefileOpts{})
+	assert.Nil(t, err)
+	assert.True(t, alpha.state.ExportZonefileCalled)
+	assert.True(t, alpha.state.ImportZonefileCalled)
+
+	unknown := &hcloud.Zone{ID: 3, Name: "gamma.com"}
+	_, _, err = pc.GetRRSets(ctx, unknown, hcloud.ZoneRRSetListOpts{})
+	assert.EqualError(t, err, "zone gamma.com does not belong to any configured project")
+	_, _, err = pc.DeleteRRSet(ctx, &hcloud.ZoneRRSet{Zone: unknown})
+	assert.EqualError(t, err, "zone gamma.com does not belong to any configured project")
+	_, _, err = pc.ExportZonefile(ctx, nil)
+	assert.EqualError(t, err, "unknown zone")
+	_, _, err = pc.UpdateRRSetTTL(ctx, nil, hcloud.ZoneRRSetChangeTTLOpts{})
+	assert.EqualError(t, err, "unknown RRSet")
+}
+
+// Test_projectClients_WaitForAction tests projectClients.WaitForAction().
+func Test_projectClients_WaitForAction(t *testing.T) {
+	ctx := context.Background()
+	pc, alpha, beta := newTestProjectClients(t)
+	action, _, err := pc.ImportZonefile(ctx, &hcloud.Zone{ID: 1, Name: "alpha.com"}, hcloud.ZoneImportZonefileOpts{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, pc.WaitForAction(ctx, action))
+	assert.True(t, alpha.state.WaitForActionCalled)
+	assert.False(t, beta.state.WaitForActionCalled)
+	// The action is forgotten once waited for.
+	assert.EqualError(t, pc.WaitForAction(ctx, action), "action 11 was not started by any configured project")
+	assert.EqualError(t, pc.WaitForAction(ctx, nil), "unknown action")
+}
+
+// Test_projectClients_setActionOwner tests that stale actions are forgotten.
+func Test_projectClients_setActionOwner(t *testing.T) {
+	pc := newProjectClients(nil)
+	pc.actions[1] = actionOwner{started: time.Now().Add(-2 * actionOwnerTTL)}
+	pc.setActionOwner(&hcloud.Action{ID: 2}, projectClient{name: "alpha"})
+	pc.setActionOwner(nil, projectClient{name: "alpha"})
+	assert.Len(t, pc.actions, 1)
+	assert.Equal(t, "alpha", pc.actions[2].project.name)
+}
+
+// Test_projectClients_watchAPIKey tests projectClients.watchAPIKey().
+func Test_projectClients_watchAPIKey(t *testing.T) {
+	client, err := NewHetznerCloud(hetzner.Project{APIKey: "TEST_API_KEY"}, nil)
+	assert.Nil(t, err)
+	pc := newProjectClients([]projectClient{
+		{name: "alpha", client: client},
+		{name: "beta", client: &mockClient{}},
+	})
+	// Returns immediately: there is no file to watch.
+	pc.watchAPIKey(context.Background())
+}
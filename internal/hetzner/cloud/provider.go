@@ -23,22 +23,34 @@ package hetznercloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"external-dns-hetzner-webhook/internal/hetzner"
+	"external-dns-hetzner-webhook/internal/logging"
 	"external-dns-hetzner-webhook/internal/metrics"
+	"external-dns-hetzner-webhook/internal/tracing"
+	"external-dns-hetzner-webhook/internal/zonefile"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
-	log "github.com/sirupsen/logrus"
 )
 
-// logFatalf is a mockable call to log.Fatalf
-var logFatalf = log.Fatalf
+var (
+	// Logger of the provider subsystem
+	providerLog = logging.Logger(logging.Provider)
+	// Logger of the changes subsystem
+	changesLog = logging.Logger(logging.Changes)
+	// Logger of the zonefile subsystem
+	zonefileLog = logging.Logger(logging.Zonefile)
+	// Logger of the API client subsystem
+	apiLog = logging.Logger(logging.APIClient)
+)
 
 // changesRunner is the general interface for applying changes.
 type changesRunner interface {
@@ -53,6 +65,8 @@ type changesRunner interface {
 	// GetSlash returns the current slash escape sequence and a boolean that
 	// determines if labels are supported by the implementation.
 	GetSlash() (string, bool)
+	// GetDefaultLabels returns the default labels applied to every RRSet.
+	GetDefaultLabels() defaultLabels
 }
 
 // HetznerProvider implements ExternalDNS' provider.Provider interface for
@@ -68,23 +82,27 @@ type HetznerProvider struct {
 	slashEscSeq       string
 	maxFailCount      int
 	failCount         int
+	applyFailed       bool
 	zoneCacheDuration time.Duration
 	zoneCacheUpdate   time.Time
 	zoneCache         []*hcloud.Zone
 	bulkMode          bool
+	bulkThreshold     int
+	bulkRetries       int
+	bulkBackups       zonefileBackups
+	backupDir         string
+	defaultLabels     defaultLabels
+	degraded          chan error
+	running           *applyTracker
+	inspection        *inspection
+	metrics           *metrics.OpenMetrics
+	pending           *pendingSettings
+	drift             *driftTracker
 }
 
 // NewHetznerProvider creates a new HetznerProvider instance.
-func NewHetznerProvider(config *hetzner.Configuration) (*HetznerProvider, error) {
-	var logLevel log.Level
-	if config.Debug {
-		logLevel = log.DebugLevel
-	} else {
-		logLevel = log.InfoLevel
-	}
-	log.SetLevel(logLevel)
-
-	client, err := NewHetznerCloud(config.APIKey)
+func NewHetznerProvider(config *hetzner.Configuration, m *metrics.OpenMetrics) (*HetznerProvider, error) {
+	client, err := newClient(config, m)
 	if err != nil {
 		return nil, fmt.Errorf("cannot instantiate cloud DNS provider: %w", err)
 	}
@@ -95,19 +113,59 @@ func NewHetznerProvider(config *hetzner.Configuration) (*HetznerProvider, error)
 	} else {
 		msg = "Configuring cloud DNS provider without maximum fail count"
 	}
-	log.Info(msg)
+	providerLog.Info(msg)
+
+	defaultLabels, err := newDefaultLabels(config.DefaultLabels)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read default labels: %w", err)
+	}
+	if len(defaultLabels) > 0 {
+		providerLog.Infof("Default labels configured: %d.", len(defaultLabels))
+	}
+
+	if config.BulkMode && config.BulkModeThreshold > 0 {
+		providerLog.Infof("Experimental BULK_MODE activated: zones with at least %d changes will use import/export endpoints.",
+			config.BulkModeThreshold)
+	} else if config.BulkMode {
+		providerLog.Info("Experimental BULK_MODE activated: changes will use import/export endpoints.")
+	}
 
-	if config.BulkMode {
-		log.Info("Experimental BULK_MODE activated: changes will use import/export endpoints.")
+	var bulkBackups zonefileBackups
+	if config.BulkMode && config.BulkModeBackupDir != "" {
+		maxAge := time.Duration(int64(config.BulkModeBackupMaxAge) * int64(time.Hour))
+		backups, err := zonefile.NewBackups(config.BulkModeBackupDir, config.BulkModeBackupRetention, maxAge)
+		if err != nil {
+			return nil, fmt.Errorf("cannot configure zonefile backups: %w", err)
+		}
+		bulkBackups = backups
+		providerLog.Infof("Zonefile backups enabled in %s.", config.BulkModeBackupDir)
+	}
+
+	if config.RateLimitWarningHorizon > 0 {
+		horizon := time.Duration(int64(config.RateLimitWarningHorizon) * int64(time.Second))
+		m.SetRateLimitWarningHorizon(horizon)
+	}
+
+	domainFilter, err := hetzner.GetDomainFilter(*config)
+	if err != nil {
+		return nil, err
 	}
 
 	zcTTL := time.Duration(int64(config.ZoneCacheTTL) * int64(time.Second))
 	zcUpdate := time.Now()
 
 	if zcTTL > 0 {
-		log.Infof("Zone cache enabled. TTL=%ds.", config.ZoneCacheTTL)
+		providerLog.Infof("Zone cache enabled. TTL=%ds.", config.ZoneCacheTTL)
 	} else {
-		log.Info("Zone cache disabled in configuration.")
+		providerLog.Info("Zone cache disabled in configuration.")
+	}
+
+	driftInterval := time.Duration(int64(config.DriftCheckInterval) * int64(time.Second))
+	drift := newDriftTracker(driftInterval, config.DriftAutoCorrect)
+	if drift != nil && config.DriftAutoCorrect {
+		providerLog.Infof("Drift detection enabled every %ds, with automatic correction.", config.DriftCheckInterval)
+	} else if drift != nil {
+		providerLog.Infof("Drift detection enabled every %ds.", config.DriftCheckInterval)
 	}
 
 	return &HetznerProvider{
@@ -115,32 +173,65 @@ func NewHetznerProvider(config *hetzner.Configuration) (*HetznerProvider, error)
 		batchSize:         config.BatchSize,
 		debug:             config.Debug,
 		dryRun:            config.DryRun,
-		domainFilter:      hetzner.GetDomainFilter(*config),
+		domainFilter:      domainFilter,
 		slashEscSeq:       config.SlashEscSeq,
 		maxFailCount:      config.MaxFailCount,
 		zoneCacheDuration: zcTTL,
 		zoneCacheUpdate:   zcUpdate,
 		bulkMode:          config.BulkMode,
+		bulkThreshold:     config.BulkModeThreshold,
+		bulkRetries:       config.BulkModeConflictRetries,
+		bulkBackups:       bulkBackups,
+		backupDir:         config.BulkModeBackupDir,
+		defaultLabels:     defaultLabels,
+		degraded:          make(chan error, 1),
+		running:           &applyTracker{},
+		inspection:        &inspection{},
+		metrics:           m,
+		pending:           &pendingSettings{},
+		drift:             drift,
 	}, nil
 }
 
-// incFailCount increments the fail count and exit if necessary.
+// incFailCount increments the fail count and reports the degraded state if
+// necessary.
 func (p *HetznerProvider) incFailCount() {
 	if p.maxFailCount <= 0 {
 		return
 	}
 	p.failCount++
+	p.inspection.setFailCount(p.failCount)
 	if p.failCount >= p.maxFailCount {
-		logFatalf("Failure count reached %d. Shutting down container.", p.failCount)
+		providerLog.Errorf("Failure count reached %d. Reporting degraded state.", p.failCount)
+		// Only the first report is kept, the following ones are discarded.
+		select {
+		case p.degraded <- fmt.Errorf("failure count reached %d", p.failCount):
+		default:
+		}
 	}
 }
 
+// WatchAPIKey checks the API key file for changes until the context is done.
+// It returns immediately if the API key was not read from a file.
+func (p HetznerProvider) WatchAPIKey(ctx context.Context) {
+	if w, ok := p.client.(keyWatcher); ok {
+		w.watchAPIKey(ctx)
+	}
+}
+
+// Degraded returns a channel that receives an error when the provider enters
+// a degraded state and the webhook must be shut down.
+func (p HetznerProvider) Degraded() <-chan error {
+	return p.degraded
+}
+
 // resetFailCount resets the fail count.
 func (p *HetznerProvider) resetFailCount() {
 	if p.maxFailCount <= 0 {
 		return
 	}
 	p.failCount = 0
+	p.inspection.setFailCount(0)
 }
 
 // Zones returns the list of the hosted DNS zones.
@@ -149,15 +240,14 @@ func (p *HetznerProvider) Zones(ctx context.Context) ([]*hcloud.Zone, error) {
 	now := time.Now()
 	if now.Before(p.zoneCacheUpdate) && p.zoneCache != nil {
 		nextUpdate := int(p.zoneCacheUpdate.Sub(now).Seconds())
-		log.Debugf("Using cached zones. The cache expires in %d seconds.", nextUpdate)
+		providerLog.Debugf("Using cached zones. The cache expires in %d seconds.", nextUpdate)
 		return p.zoneCache, nil
 	}
-	metrics := metrics.GetOpenMetricsInstance()
 	result := []*hcloud.Zone{}
 
 	zones, err := fetchZones(ctx, p.client, p.batchSize)
 	if err != nil {
-		log.Errorf("Got an error while fetching zones: %s", err.Error())
+		providerLog.Errorf("Got an error while fetching zones: %s", err.Error())
 		return nil, err
 	}
 
@@ -169,12 +259,18 @@ func (p *HetznerProvider) Zones(ctx context.Context) ([]*hcloud.Zone, error) {
 			filteredOutZones++
 		}
 	}
-	metrics.SetFilteredOutZones(filteredOutZones)
+	p.metrics.SetFilteredOutZones(filteredOutZones)
 
-	log.Debugf("Got %d zones, filtered out %d zones.", len(zones), filteredOutZones)
+	providerLog.Debugf("Got %d zones, filtered out %d zones.", len(zones), filteredOutZones)
 	p.ensureZoneIDMappingPresent(zones)
 	p.zoneCache = result
 	p.zoneCacheUpdate = now.Add(p.zoneCacheDuration)
+	var cacheExpiry *time.Time
+	if p.zoneCacheDuration > 0 {
+		expiry := p.zoneCacheUpdate.UTC()
+		cacheExpiry = &expiry
+	}
+	p.inspection.setZones(p.zoneIDNameMapper, p.domainFilter, now, cacheExpiry)
 
 	return result, nil
 }
@@ -194,6 +290,9 @@ func (p HetznerProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*end
 			if adjustedTargets, err = adjustEndpointTargets(ep.Targets); err != nil {
 				return nil, err
 			}
+			name := makeEndpointName(zone.Name, ep.DNSName)
+			defaults := p.defaultLabels.render(zone.Name, ep.RecordType, name)
+			addDefaultLabels(p.slashEscSeq, defaults, ep)
 		}
 		ep.Targets = adjustedTargets
 		adjustedEndpoints = append(adjustedEndpoints, ep)
@@ -204,12 +303,17 @@ func (p HetznerProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*end
 // logDebugEndpoints logs every endpoint as a a line.
 func logDebugEndpoints(endpoints []*endpoint.Endpoint) {
 	for idx, ep := range endpoints {
-		log.WithFields(getEndpointLogFields(ep)).Debugf("Endpoint %d", idx)
+		providerLog.WithFields(getEndpointLogFields(ep)).Debugf("Endpoint %d", idx)
 	}
 }
 
 // Records returns the list of records in all zones as a slice of endpoints.
-func (p *HetznerProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+func (p *HetznerProvider) Records(ctx context.Context) (_ []*endpoint.Endpoint, err error) {
+	ctx, span := tracing.Start(ctx, "provider.Records")
+	defer func() { tracing.End(span, err) }()
+	started := time.Now()
+	defer func() { p.recordSync(started, err, false) }()
+	p.applyPendingSettings()
 	zones, err := p.Zones(ctx)
 	if err != nil {
 		p.incFailCount()
@@ -219,44 +323,91 @@ func (p *HetznerProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, er
 
 	endpoints := []*endpoint.Endpoint{}
 	for _, zone := range zones {
-		rrsets, err := fetchRecords(ctx, zone, p.client, p.batchSize)
+		zoneEndpoints, err := p.zoneEndpoints(ctx, zone)
 		if err != nil {
 			return nil, err
 		}
-
-		skippedRecords := 0
-		// Add only endpoints from supported types.
-		for _, rrset := range rrsets {
-			// Ensure the record has all the required zone information
-			rrset.Zone = zone
-			// Use our own IsSupportedRecordType instead of provider.SupportedRecordType
-			// because the SDK function doesn't include MX in its hardcoded list.
-			if hetzner.IsSupportedRecordType(string(rrset.Type)) {
-				ep := createEndpointFromRecord(p.slashEscSeq, rrset)
-				endpoints = append(endpoints, ep)
-			} else {
-				skippedRecords++
-			}
-		}
-		m := metrics.GetOpenMetricsInstance()
-		m.SetSkippedRecords(zone.Name, skippedRecords)
+		endpoints = append(endpoints, zoneEndpoints...)
 	}
 
+	span.SetAttributes(tracing.AttrRecords.Int(len(endpoints)))
 	// Log the endpoints that were found.
 	if p.debug {
-		log.Debugf("Returning %d endpoints.", len(endpoints))
+		providerLog.Debugf("Returning %d endpoints.", len(endpoints))
 		logDebugEndpoints(endpoints)
 	}
 
 	return endpoints, nil
 }
 
+// zoneEndpoints returns the records of a zone as a slice of endpoints and
+// updates the record metrics of the zone.
+func (p *HetznerProvider) zoneEndpoints(ctx context.Context, zone *hcloud.Zone) ([]*endpoint.Endpoint, error) {
+	zctx, zspan := tracing.Start(ctx, "provider.Records.zone", tracing.AttrZone.String(zone.Name))
+	rrsets, err := fetchRecords(zctx, zone, p.client, p.batchSize)
+	zspan.SetAttributes(tracing.AttrRecords.Int(len(rrsets)))
+	tracing.End(zspan, err)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints, managed, skippedRecords := createZoneEndpoints(p.slashEscSeq, zone, rrsets)
+	p.metrics.SetSkippedRecords(zone.Name, skippedRecords)
+	p.metrics.SetManagedRRSets(zone.Name, managed)
+	return endpoints, nil
+}
+
+// createZoneEndpoints converts the RRSets of a zone to endpoints. It also
+// returns the number of RRSets per type and the number of RRSets skipped
+// because their type is not supported.
+func createZoneEndpoints(slash string, zone *hcloud.Zone, rrsets []*hcloud.ZoneRRSet) ([]*endpoint.Endpoint, map[string]int, int) {
+	endpoints := []*endpoint.Endpoint{}
+	skippedRecords := 0
+	managed := map[string]int{}
+	// Add only endpoints from supported types.
+	for _, rrset := range rrsets {
+		// Ensure the record has all the required zone information
+		rrset.Zone = zone
+		// Use our own IsSupportedRecordType instead of provider.SupportedRecordType
+		// because the SDK function doesn't include MX in its hardcoded list.
+		if hetzner.IsSupportedRecordType(string(rrset.Type)) {
+			ep := createEndpointFromRecord(slash, rrset)
+			endpoints = append(endpoints, ep)
+			managed[string(rrset.Type)]++
+		} else {
+			skippedRecords++
+		}
+	}
+	return endpoints, managed, skippedRecords
+}
+
+// recordSync updates the duration histograms and the last successful sync
+// timestamp after a Records or ApplyChanges call. A successful Records call
+// does not count as a sync while the last ApplyChanges call failed.
+func (p *HetznerProvider) recordSync(started time.Time, err error, apply bool) {
+	m := p.metrics
+	if apply {
+		m.ObserveApplyChangesDuration(time.Since(started))
+		p.applyFailed = err != nil
+	} else {
+		m.ObserveRecordsDuration(time.Since(started))
+	}
+	if err == nil && !p.applyFailed {
+		m.SetLastSuccessfulSync(time.Now())
+	}
+}
+
 // ensureZoneIDMappingPresent prepares the zoneIDNameMapper, that associates
-// each ZoneID woth the zone name.
+// each ZoneID woth the zone name. With several projects, the mapper is the
+// one the client routes the calls with, so that both agree on the owners.
 func (p *HetznerProvider) ensureZoneIDMappingPresent(zones []*hcloud.Zone) {
+	if router, ok := p.client.(zoneRouter); ok {
+		p.zoneIDNameMapper = router.routedZones()
+		return
+	}
 	zoneIDNameMapper := zoneIDName{}
 	for _, z := range zones {
-		zoneIDNameMapper.Add(z)
+		zoneIDNameMapper.Add(z, "")
 	}
 	p.zoneIDNameMapper = zoneIDNameMapper
 }
@@ -284,31 +435,51 @@ func (p *HetznerProvider) getRRSetsByZoneID(ctx context.Context) (map[int64][]*h
 }
 
 // getChangesRunner returns the appropriate changesRunner depending on the
-// BULK_MODE flag.
+// BULK_MODE flag and on the BULK_MODE_THRESHOLD value.
 func (p HetznerProvider) getChangesRunner() changesRunner {
-	if p.bulkMode {
-		return NewBulkChanges(p.client, p.dryRun, p.slashEscSeq)
+	if p.bulkMode && p.bulkThreshold > 0 {
+		return NewHybridChanges(p.client, p.dryRun, p.slashEscSeq, p.defaultLabels, p.bulkRetries, p.bulkBackups, p.bulkThreshold, p.metrics)
+	} else if p.bulkMode {
+		return NewBulkChanges(p.client, p.dryRun, p.slashEscSeq, p.defaultLabels, p.bulkRetries, p.bulkBackups, p.metrics)
 	} else {
-		return NewHetznerChanges(p.client, p.dryRun, p.slashEscSeq)
+		return NewHetznerChanges(p.client, p.dryRun, p.slashEscSeq, p.defaultLabels, p.metrics)
 	}
 }
 
 // ApplyChanges applies the given set of generic changes to the provider.
-func (p *HetznerProvider) ApplyChanges(ctx context.Context, planChanges *plan.Changes) error {
+func (p *HetznerProvider) ApplyChanges(ctx context.Context, planChanges *plan.Changes) (err error) {
+	planChanges = p.drift.correct(planChanges, p.metrics)
 	if !planChanges.HasChanges() {
 		return nil
 	}
+	if p.running != nil {
+		if !p.running.start() {
+			return errors.New("shutting down: changes not applied")
+		}
+		defer p.running.done()
+	}
+	started := time.Now()
+	defer func() {
+		p.inspection.setLastApply(newApplySummary(planChanges, started, err))
+		p.recordSync(started, err, true)
+	}()
+	ctx, span := tracing.Start(ctx, "provider.ApplyChanges",
+		tracing.AttrCreates.Int(len(planChanges.Create)),
+		tracing.AttrUpdates.Int(len(planChanges.UpdateNew)),
+		tracing.AttrDeletes.Int(len(planChanges.Delete)))
+	defer func() { tracing.End(span, err) }()
 
 	rrSetsByZoneID, err := p.getRRSetsByZoneID(ctx)
 	if err != nil {
+		p.incFailCount()
 		return err
 	}
 
-	log.Debug("Preparing creates")
+	providerLog.Debug("Preparing creates")
 	createsByZoneID := endpointsByZoneID(p.zoneIDNameMapper, planChanges.Create)
-	log.Debug("Preparing updates")
+	providerLog.Debug("Preparing updates")
 	updatesByZoneID := endpointsByZoneID(p.zoneIDNameMapper, planChanges.UpdateNew)
-	log.Debug("Preparing deletes")
+	providerLog.Debug("Preparing deletes")
 	deletesByZoneID := endpointsByZoneID(p.zoneIDNameMapper, planChanges.Delete)
 
 	changes := p.getChangesRunner()
@@ -317,10 +488,81 @@ func (p *HetznerProvider) ApplyChanges(ctx context.Context, planChanges *plan.Ch
 	processUpdateActions(p.zoneIDNameMapper, rrSetsByZoneID, updatesByZoneID, changes)
 	processDeleteActions(p.zoneIDNameMapper, rrSetsByZoneID, deletesByZoneID, changes)
 
-	return changes.ApplyChanges(ctx)
+	if err := changes.ApplyChanges(ctx); err != nil {
+		p.incFailCount()
+		return err
+	}
+	p.resetFailCount()
+	if !p.dryRun {
+		p.drift.record(p.zoneIDNameMapper, planChanges)
+	}
+	return nil
 }
 
 // GetDomainFilter returns the domain filter
 func (p HetznerProvider) GetDomainFilter() endpoint.DomainFilterInterface {
 	return p.domainFilter
 }
+
+// applyTracker tracks the running ApplyChanges calls and rejects the new ones
+// once draining has started.
+type applyTracker struct {
+	m        sync.Mutex
+	draining bool
+	wg       sync.WaitGroup
+}
+
+// start registers a new ApplyChanges call. It returns false if draining has
+// started.
+func (a *applyTracker) start() bool {
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.draining {
+		return false
+	}
+	a.wg.Add(1)
+	return true
+}
+
+// done marks an ApplyChanges call as completed.
+func (a *applyTracker) done() {
+	a.wg.Done()
+}
+
+// drain rejects the new ApplyChanges calls and returns a channel that is
+// closed when the running ones are completed.
+func (a *applyTracker) drain() <-chan struct{} {
+	a.m.Lock()
+	a.draining = true
+	a.m.Unlock()
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// Drain rejects the new ApplyChanges calls and waits for the running ones to
+// complete, until the context expires.
+func (p *HetznerProvider) Drain(ctx context.Context) error {
+	if p.running == nil {
+		return nil
+	}
+	select {
+	case <-p.running.drain():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("changes still being applied: %w", ctx.Err())
+	}
+}
+
+// Ping performs a cheap authenticated call, requesting a single zone, to check
+// that the Hetzner API is reachable.
+func (p *HetznerProvider) Ping(ctx context.Context) error {
+	opts := hcloud.ZoneListOpts{
+		ListOpts: hcloud.ListOpts{Page: 1, PerPage: 1},
+	}
+	_, _, err := p.client.GetZones(ctx, opts)
+	return err
+}
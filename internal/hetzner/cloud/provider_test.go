@@ -20,17 +20,19 @@ package hetznercloud
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
+	"sync"
 	"testing"
 	"time"
 
+	"external-dns-hetzner-webhook/internal/fakeapi"
 	"external-dns-hetzner-webhook/internal/hetzner"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 	"github.com/stretchr/testify/assert"
 
 	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
 )
 
 // assertEqualDomainFilter checks that the domain filters have the same information.
@@ -98,7 +100,7 @@ func Test_NewHetznerProvider(t *testing.T) {
 
 	run := func(t *testing.T, tc testCase) {
 		exp := tc.expected
-		provider, err := NewHetznerProvider(tc.input)
+		provider, err := NewHetznerProvider(tc.input, nil)
 		assertError(t, exp.err, err)
 		assertEqualProviders(t, exp.provider, provider, time.Second)
 	}
@@ -395,19 +397,22 @@ func Test_AdjustEndpoints(t *testing.T) {
 		assert.EqualValues(t, exp, actual)
 	}
 
+	defaults, err := newDefaultLabels([]string{"cluster=c1", "record={{.Name}}"})
+	assert.Nil(t, err)
+
 	testCases := []testCase{
 		{
 			name: "empty list",
 			provider: HetznerProvider{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 			},
 			input:    []*endpoint.Endpoint{},
@@ -417,14 +422,14 @@ func Test_AdjustEndpoints(t *testing.T) {
 			name: "adjusted elements",
 			provider: HetznerProvider{
 				zoneIDNameMapper: zoneIDName{
-					1: &hcloud.Zone{
+					1: {zone: &hcloud.Zone{
 						ID:   1,
 						Name: "alpha.com",
-					},
-					2: &hcloud.Zone{
+					}},
+					2: {zone: &hcloud.Zone{
 						ID:   2,
 						Name: "beta.com",
-					},
+					}},
 				},
 			},
 			input: []*endpoint.Endpoint{
@@ -472,6 +477,50 @@ func Test_AdjustEndpoints(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "default labels",
+			provider: HetznerProvider{
+				zoneIDNameMapper: zoneIDName{
+					1: {zone: &hcloud.Zone{
+						ID:   1,
+						Name: "alpha.com",
+					}},
+				},
+				slashEscSeq:   "--slash--",
+				defaultLabels: defaults,
+			},
+			input: []*endpoint.Endpoint{
+				{
+					DNSName:    "www.alpha.com",
+					RecordType: "A",
+					Targets:    endpoint.Targets{"1.1.1.1"},
+					ProviderSpecific: endpoint.ProviderSpecific{
+						{Name: "webhook/hetzner-label-cluster", Value: "custom"},
+					},
+				},
+				{
+					DNSName:    "www.beta.com",
+					RecordType: "A",
+					Targets:    endpoint.Targets{"2.2.2.2"},
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{
+					DNSName:    "www.alpha.com",
+					RecordType: "A",
+					Targets:    endpoint.Targets{"1.1.1.1"},
+					ProviderSpecific: endpoint.ProviderSpecific{
+						{Name: "webhook/hetzner-label-cluster", Value: "custom"},
+						{Name: "webhook/hetzner-label-record", Value: "www"},
+					},
+				},
+				{
+					DNSName:    "www.beta.com",
+					RecordType: "A",
+					Targets:    endpoint.Targets{"2.2.2.2"},
+				},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -786,7 +835,7 @@ func Test_ensureZoneIDMappingPresent(t *testing.T) {
 			name:     "empty list",
 			provider: HetznerProvider{},
 			input:    []*hcloud.Zone{},
-			expected: zoneIDName(map[int64]*hcloud.Zone{}),
+			expected: zoneIDName{},
 		},
 		{
 			name:     "zones present",
@@ -801,16 +850,35 @@ func Test_ensureZoneIDMappingPresent(t *testing.T) {
 					Name: "beta.com",
 				},
 			},
-			expected: zoneIDName(map[int64]*hcloud.Zone{
-				1: {
+			expected: zoneIDName{
+				1: {zone: &hcloud.Zone{
 					ID:   1,
 					Name: "alpha.com",
-				},
-				2: {
+				}},
+				2: {zone: &hcloud.Zone{
 					ID:   2,
 					Name: "beta.com",
+				}},
+			},
+		},
+		{
+			name: "zones routed to projects",
+			provider: HetznerProvider{
+				client: &projectClients{
+					zones: zoneIDName{
+						1: {zone: &hcloud.Zone{ID: 1, Name: "alpha.com"}, project: "alpha"},
+					},
+				},
+			},
+			input: []*hcloud.Zone{
+				{
+					ID:   1,
+					Name: "alpha.com",
 				},
-			}),
+			},
+			expected: zoneIDName{
+				1: {zone: &hcloud.Zone{ID: 1, Name: "alpha.com"}, project: "alpha"},
+			},
 		},
 	}
 
@@ -1140,32 +1208,23 @@ func Test_incFailCount(t *testing.T) {
 		name     string
 		object   *HetznerProvider
 		expected struct {
-			failCount       int
-			logFatalfCalled bool
-			logFatalfMsg    string
+			failCount int
+			degraded  error
 		}
 	}
 
 	run := func(t *testing.T, tc testCase) {
-		origLogFatalf := logFatalf
 		obj := tc.object
 		exp := tc.expected
-		logFatalfCalled := false
-		logFatalfMsg := ""
-
-		// Mock logFatalf
-		logFatalf = func(format string, a ...interface{}) {
-			logFatalfCalled = true
-			logFatalfMsg = fmt.Sprintf(format, a...)
-		}
-		// Do the call
+		obj.degraded = make(chan error, 1)
 		obj.incFailCount()
-		// Restore logFatalf
-		logFatalf = origLogFatalf
-
 		assert.Equal(t, exp.failCount, obj.failCount)
-		assert.Equal(t, exp.logFatalfCalled, logFatalfCalled)
-		assert.Equal(t, exp.logFatalfMsg, logFatalfMsg)
+		select {
+		case err := <-obj.Degraded():
+			assert.Equal(t, exp.degraded, err)
+		default:
+			assert.Nil(t, exp.degraded)
+		}
 	}
 
 	testCases := []testCase{
@@ -1176,9 +1235,8 @@ func Test_incFailCount(t *testing.T) {
 				failCount:    -1, // impossible value, but will not be reset if disabled
 			},
 			expected: struct {
-				failCount       int
-				logFatalfCalled bool
-				logFatalfMsg    string
+				failCount int
+				degraded  error
 			}{
 				failCount: -1,
 			},
@@ -1190,9 +1248,8 @@ func Test_incFailCount(t *testing.T) {
 				failCount:    0,
 			},
 			expected: struct {
-				failCount       int
-				logFatalfCalled bool
-				logFatalfMsg    string
+				failCount int
+				degraded  error
 			}{
 				failCount: 1,
 			},
@@ -1204,9 +1261,8 @@ func Test_incFailCount(t *testing.T) {
 				failCount:    1,
 			},
 			expected: struct {
-				failCount       int
-				logFatalfCalled bool
-				logFatalfMsg    string
+				failCount int
+				degraded  error
 			}{
 				failCount: 2,
 			},
@@ -1218,13 +1274,11 @@ func Test_incFailCount(t *testing.T) {
 				failCount:    2,
 			},
 			expected: struct {
-				failCount       int
-				logFatalfCalled bool
-				logFatalfMsg    string
+				failCount int
+				degraded  error
 			}{
-				failCount:       3,
-				logFatalfCalled: true,
-				logFatalfMsg:    "Failure count reached 3. Shutting down container.",
+				failCount: 3,
+				degraded:  errors.New("failure count reached 3"),
 			},
 		},
 	}
@@ -1236,6 +1290,117 @@ func Test_incFailCount(t *testing.T) {
 	}
 }
 
+// Test_incFailCount_reported tests that only the first degraded state is
+// reported and that incFailCount() never blocks.
+func Test_incFailCount_reported(t *testing.T) {
+	obj := &HetznerProvider{
+		maxFailCount: 1,
+		degraded:     make(chan error, 1),
+	}
+	obj.incFailCount()
+	obj.incFailCount()
+	assert.Equal(t, errors.New("failure count reached 1"), <-obj.Degraded())
+	assert.Empty(t, obj.Degraded())
+}
+
+// Test_ApplyChanges_failCount tests that ApplyChanges() counts the failures.
+func Test_ApplyChanges_failCount(t *testing.T) {
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{{DNSName: "www.alpha.com", RecordType: "A", Targets: endpoint.Targets{"1.1.1.1"}}},
+	}
+	client := &mockClient{getZones: zonesResponse{err: errors.New("test zones error")}}
+	obj := &HetznerProvider{
+		client:       client,
+		batchSize:    100,
+		maxFailCount: 2,
+		degraded:     make(chan error, 1),
+	}
+	err := obj.ApplyChanges(context.Background(), changes)
+	assert.EqualError(t, err, "test zones error")
+	assert.Equal(t, 1, obj.failCount)
+
+	client.getZones = zonesResponse{zones: []*hcloud.Zone{}}
+	err = obj.ApplyChanges(context.Background(), changes)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, obj.failCount)
+}
+
+// Test_recordSync tests HetznerProvider.recordSync().
+func Test_recordSync(t *testing.T) {
+	const name = "last_successful_sync_seconds"
+	m := newTestMetrics(t)
+	obj := &HetznerProvider{metrics: m}
+	started := time.Now()
+
+	obj.recordSync(started, nil, false)
+	synced := metricValue(t, m, name, nil)
+	assert.GreaterOrEqual(t, synced, float64(started.Unix()))
+
+	// A failed ApplyChanges blocks the sync until the next successful one.
+	obj.recordSync(started, errors.New("test error"), true)
+	assert.True(t, obj.applyFailed)
+	m.SetLastSuccessfulSync(time.Unix(0, 0))
+	obj.recordSync(started, nil, false)
+	assert.Equal(t, float64(0), metricValue(t, m, name, nil))
+
+	obj.recordSync(started, nil, true)
+	assert.False(t, obj.applyFailed)
+	assert.GreaterOrEqual(t, metricValue(t, m, name, nil), float64(started.Unix()))
+}
+
+// Test_WatchAPIKey tests HetznerProvider.WatchAPIKey().
+func Test_WatchAPIKey(t *testing.T) {
+	t.Run("api key from environment", func(t *testing.T) {
+		client, err := NewHetznerCloud(hetzner.Project{APIKey: "TEST_API_KEY"}, nil)
+		assert.Nil(t, err)
+		obj := HetznerProvider{client: client}
+		// Returns immediately: there is no file to watch.
+		obj.WatchAPIKey(context.Background())
+	})
+	t.Run("api key file", func(t *testing.T) {
+		dir := t.TempDir()
+		client, err := NewHetznerCloud(hetzner.Project{APIKeyFile: writeAPIKeyFile(t, dir, "TEST_API_KEY")}, nil)
+		assert.Nil(t, err)
+		obj := HetznerProvider{client: client}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		obj.WatchAPIKey(ctx)
+		assert.Equal(t, tokenFingerprint("TEST_API_KEY"), client.credentials.token())
+	})
+	t.Run("other client", func(t *testing.T) {
+		obj := HetznerProvider{client: &mockClient{}}
+		obj.WatchAPIKey(context.Background())
+	})
+}
+
+// Test_Drain tests HetznerProvider.Drain().
+func Test_Drain(t *testing.T) {
+	t.Run("nothing running", func(t *testing.T) {
+		obj := &HetznerProvider{running: &applyTracker{}}
+		assert.Nil(t, obj.Drain(context.Background()))
+	})
+	t.Run("changes running", func(t *testing.T) {
+		obj := &HetznerProvider{running: &applyTracker{}}
+		assert.True(t, obj.running.start())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := obj.Drain(ctx)
+		assert.EqualError(t, err, "changes still being applied: context deadline exceeded")
+		obj.running.done()
+		assert.Nil(t, obj.Drain(context.Background()))
+	})
+	t.Run("changes rejected after drain", func(t *testing.T) {
+		client := &mockClient{}
+		obj := &HetznerProvider{client: client, running: &applyTracker{}, inspection: &inspection{}}
+		assert.Nil(t, obj.Drain(context.Background()))
+		err := obj.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("www.alpha.com", "A", "127.0.0.1")},
+		})
+		assert.EqualError(t, err, "shutting down: changes not applied")
+		assert.Equal(t, mockClientState{}, client.state)
+	})
+}
+
 // Test_resetFailCount tests resetFailCount().
 func Test_resetFailCount(t *testing.T) {
 	type testCase struct {
@@ -1284,3 +1449,116 @@ func Test_resetFailCount(t *testing.T) {
 		})
 	}
 }
+
+// Test_getChangesRunner tests HetznerProvider.getChangesRunner().
+func Test_getChangesRunner(t *testing.T) {
+	type testCase struct {
+		name     string
+		object   HetznerProvider
+		expected changesRunner
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		actual := tc.object.getChangesRunner()
+		assert.IsType(t, tc.expected, actual)
+	}
+
+	testCases := []testCase{
+		{
+			name:     "single RRSet changes",
+			object:   HetznerProvider{},
+			expected: &hetznerChanges{},
+		},
+		{
+			name:     "bulk changes",
+			object:   HetznerProvider{bulkMode: true},
+			expected: &bulkChanges{},
+		},
+		{
+			name:     "hybrid changes",
+			object:   HetznerProvider{bulkMode: true, bulkThreshold: 3},
+			expected: &hybridChanges{},
+		},
+		{
+			name:     "threshold without bulk mode",
+			object:   HetznerProvider{bulkThreshold: 3},
+			expected: &hetznerChanges{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_Ping tests HetznerProvider.Ping().
+func Test_Ping(t *testing.T) {
+	type testCase struct {
+		name     string
+		client   *mockClient
+		expected error
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		p := HetznerProvider{client: tc.client}
+		err := p.Ping(context.Background())
+		assertError(t, tc.expected, err)
+		assert.True(t, tc.client.state.GetZonesCalled)
+	}
+
+	testCases := []testCase{
+		{
+			name: "API reachable",
+			client: &mockClient{
+				getZones: zonesResponse{zones: []*hcloud.Zone{{ID: 1, Name: "alpha.com"}}},
+			},
+		},
+		{
+			name: "API error",
+			client: &mockClient{
+				getZones: zonesResponse{err: errors.New("test zones error")},
+			},
+			expected: errors.New("test zones error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_Ping_concurrent tests that Ping can run while the records are read,
+// as the health checker does. Run it with -race.
+func Test_Ping_concurrent(t *testing.T) {
+	fake := fakeapi.NewServer("TEST_API_KEY")
+	defer fake.Close()
+	fake.AddZone("alpha.com", 3600)
+	p, err := NewHetznerProvider(&hetzner.Configuration{
+		APIKey:      "TEST_API_KEY",
+		APIEndpoint: fake.URL,
+		BatchSize:   50,
+		SlashEscSeq: "--slash--",
+	}, nil)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 10 {
+			_, err := p.Records(context.Background())
+			assert.Nil(t, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 10 {
+			assert.Nil(t, p.Ping(context.Background()))
+		}
+	}()
+	wg.Wait()
+}
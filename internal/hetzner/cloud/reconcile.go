@@ -0,0 +1,178 @@
+/*
+ * Reconcile - planning of the changes from a declarative record set.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"external-dns-hetzner-webhook/internal/hetzner"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// ReconcileOwnerLabel is the Hetzner label marking the records owned by a
+// reconciled record set. Its value is the owner ID.
+const ReconcileOwnerLabel = "reconcile-owner"
+
+// ReconcilePlan contains the changes needed to reach the desired records.
+type ReconcilePlan struct {
+	// Changes to apply
+	Changes *plan.Changes `json:"changes"`
+	// Desired records that differ from existing records not owned by the
+	// record set, which are never changed
+	Conflicts []*endpoint.Endpoint `json:"conflicts,omitempty"`
+}
+
+// HasDrift returns true if the records differ from the desired ones.
+func (rp ReconcilePlan) HasDrift() bool {
+	return rp.Changes.HasChanges() || len(rp.Conflicts) > 0
+}
+
+// endpointKey identifies an endpoint by name and type.
+type endpointKey struct {
+	name       string
+	recordType string
+}
+
+// newEndpointKey returns the key of an endpoint.
+func newEndpointKey(ep *endpoint.Endpoint) endpointKey {
+	return endpointKey{name: ep.DNSName, recordType: ep.RecordType}
+}
+
+// labelsOf returns the Hetzner labels of an endpoint, as provider specific
+// name/value pairs.
+func labelsOf(ep *endpoint.Endpoint) map[string]string {
+	labels := make(map[string]string)
+	for _, ps := range ep.ProviderSpecific {
+		if strings.HasPrefix(ps.Name, providerPrefix) {
+			labels[ps.Name] = ps.Value
+		}
+	}
+	return labels
+}
+
+// sameEndpoint returns true if the current endpoint already matches the
+// desired one. A desired TTL of 0 uses the default TTL of the zone and is not
+// compared.
+func sameEndpoint(desired, current *endpoint.Endpoint) bool {
+	if desired.RecordTTL != 0 && desired.RecordTTL != current.RecordTTL {
+		return false
+	}
+	desiredTargets := slices.Clone([]string(desired.Targets))
+	currentTargets := slices.Clone([]string(current.Targets))
+	slices.Sort(desiredTargets)
+	slices.Sort(currentTargets)
+	if !slices.Equal(desiredTargets, currentTargets) {
+		return false
+	}
+	desiredLabels := labelsOf(desired)
+	currentLabels := labelsOf(current)
+	if len(desiredLabels) != len(currentLabels) {
+		return false
+	}
+	for name, value := range desiredLabels {
+		if v, found := currentLabels[name]; !found || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareDesired normalizes the desired endpoints, checks that they belong to
+// the managed zones and sets the owner label.
+func (p *HetznerProvider) prepareDesired(desired []*endpoint.Endpoint, owner string) ([]*endpoint.Endpoint, error) {
+	ownerName := providerPrefix + ReconcileOwnerLabel
+	seen := make(map[endpointKey]bool)
+	prepared := make([]*endpoint.Endpoint, 0, len(desired))
+	for _, d := range desired {
+		ep := d.DeepCopy()
+		ep.DNSName = strings.TrimSuffix(strings.ToLower(ep.DNSName), ".")
+		if !hetzner.IsSupportedRecordType(ep.RecordType) {
+			return nil, fmt.Errorf("unsupported record type %s for %s", ep.RecordType, ep.DNSName)
+		}
+		if len(ep.Targets) == 0 {
+			return nil, fmt.Errorf("no targets for %s %s", ep.DNSName, ep.RecordType)
+		}
+		_, zone := p.zoneIDNameMapper.FindZone(ep.DNSName)
+		if zone == nil || !p.domainFilter.Match(zone.Name) {
+			return nil, fmt.Errorf("no managed zone for %s", ep.DNSName)
+		}
+		key := newEndpointKey(ep)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate record %s %s", ep.DNSName, ep.RecordType)
+		}
+		seen[key] = true
+		ep.DeleteProviderSpecificProperty(ownerName)
+		ep.SetProviderSpecificProperty(ownerName, owner)
+		prepared = append(prepared, ep)
+	}
+	return p.AdjustEndpoints(prepared)
+}
+
+// PlanReconcile computes the changes that turn the records of the managed
+// zones into the desired ones. Only the records labelled with the owner ID
+// are updated or deleted; the created records get the label.
+func (p *HetznerProvider) PlanReconcile(ctx context.Context, desired []*endpoint.Endpoint, owner string) (*ReconcilePlan, error) {
+	if err := checkValue(owner); err != nil || owner == "" {
+		return nil, fmt.Errorf("invalid owner ID %q", owner)
+	}
+	current, err := p.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	desired, err = p.prepareDesired(desired, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	currentByKey := make(map[endpointKey]*endpoint.Endpoint, len(current))
+	for _, ep := range current {
+		currentByKey[newEndpointKey(ep)] = ep
+	}
+	owned := func(ep *endpoint.Endpoint) bool {
+		return labelsOf(ep)[providerPrefix+ReconcileOwnerLabel] == owner
+	}
+
+	rp := &ReconcilePlan{Changes: &plan.Changes{}}
+	desiredKeys := make(map[endpointKey]bool, len(desired))
+	for _, ep := range desired {
+		key := newEndpointKey(ep)
+		desiredKeys[key] = true
+		cur, found := currentByKey[key]
+		switch {
+		case !found:
+			rp.Changes.Create = append(rp.Changes.Create, ep)
+		case sameEndpoint(ep, cur):
+		case owned(cur):
+			rp.Changes.UpdateOld = append(rp.Changes.UpdateOld, cur)
+			rp.Changes.UpdateNew = append(rp.Changes.UpdateNew, ep)
+		default:
+			rp.Conflicts = append(rp.Conflicts, ep)
+		}
+	}
+	for _, ep := range current {
+		if owned(ep) && !desiredKeys[newEndpointKey(ep)] {
+			rp.Changes.Delete = append(rp.Changes.Delete, ep)
+		}
+	}
+	return rp, nil
+}
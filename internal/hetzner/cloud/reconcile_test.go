@@ -0,0 +1,191 @@
+/*
+ * Reconcile - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// newReconcileProvider returns a provider managing alpha.com, with records
+// owned by "ci", by another owner and by nobody.
+func newReconcileProvider() *HetznerProvider {
+	alpha := &hcloud.Zone{ID: 1, Name: "alpha.com", TTL: 3600}
+	owned := map[string]string{ReconcileOwnerLabel: "ci"}
+	rrset := func(name, value string, labels map[string]string) *hcloud.ZoneRRSet {
+		return &hcloud.ZoneRRSet{
+			Zone:    alpha,
+			Name:    name,
+			Type:    hcloud.ZoneRRSetTypeA,
+			TTL:     &testTTL,
+			Labels:  labels,
+			Records: []hcloud.ZoneRRSetRecord{{Value: value}},
+		}
+	}
+	client := &mockClient{
+		getRRSets: rrSetsResponse{
+			rrsets: []*hcloud.ZoneRRSet{
+				rrset("www", "127.0.0.1", owned),
+				rrset("same", "127.0.0.3", owned),
+				rrset("old", "127.0.0.4", owned),
+				rrset("api", "127.0.0.2", nil),
+				rrset("manual", "127.0.0.5", nil),
+				rrset("other", "127.0.0.6", map[string]string{ReconcileOwnerLabel: "other"}),
+			},
+			resp: singlePageResponse(6),
+		},
+	}
+	p := newCommandsProvider(client)
+	p.slashEscSeq = slashDefault
+	return p
+}
+
+// Test_PlanReconcile tests PlanReconcile().
+func Test_PlanReconcile(t *testing.T) {
+	p := newReconcileProvider()
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("WWW.alpha.com.", "A", "127.0.0.10"),
+		endpoint.NewEndpoint("same.alpha.com", "A", "127.0.0.3"),
+		endpoint.NewEndpoint("api.alpha.com", "A", "127.0.0.20"),
+		endpoint.NewEndpointWithTTL("new.alpha.com", "A", 600, "127.0.0.30"),
+	}
+
+	rp, err := p.PlanReconcile(context.Background(), desired, "ci")
+	assert.Nil(t, err)
+	assert.True(t, rp.HasDrift())
+	names := func(endpoints []*endpoint.Endpoint) []string {
+		result := make([]string, 0, len(endpoints))
+		for _, ep := range endpoints {
+			result = append(result, ep.DNSName)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"new.alpha.com"}, names(rp.Changes.Create))
+	assert.Equal(t, []string{"www.alpha.com"}, names(rp.Changes.UpdateOld))
+	assert.Equal(t, []string{"www.alpha.com"}, names(rp.Changes.UpdateNew))
+	assert.Equal(t, []string{"old.alpha.com"}, names(rp.Changes.Delete))
+	assert.Equal(t, []string{"api.alpha.com"}, names(rp.Conflicts))
+	// The created and updated records are labelled with the owner.
+	owner, _ := rp.Changes.Create[0].GetProviderSpecificProperty(providerPrefix + ReconcileOwnerLabel)
+	assert.Equal(t, "ci", owner)
+	assert.Equal(t, endpoint.Targets{"127.0.0.10"}, rp.Changes.UpdateNew[0].Targets)
+	// The desired endpoints are not modified.
+	assert.Empty(t, desired[0].ProviderSpecific)
+
+	// No drift when the records match.
+	rp, err = p.PlanReconcile(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("www.alpha.com", "A", "127.0.0.1"),
+		endpoint.NewEndpoint("same.alpha.com", "A", "127.0.0.3"),
+		endpoint.NewEndpoint("old.alpha.com", "A", "127.0.0.4"),
+	}, "ci")
+	assert.Nil(t, err)
+	assert.False(t, rp.HasDrift())
+}
+
+// Test_PlanReconcile_errors tests the errors of PlanReconcile().
+func Test_PlanReconcile_errors(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			desired []*endpoint.Endpoint
+			owner   string
+		}
+		expected error
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		p := newReconcileProvider()
+		_, err := p.PlanReconcile(context.Background(), tc.input.desired, tc.input.owner)
+		assert.Equal(t, tc.expected, err)
+	}
+
+	testCases := []testCase{
+		{
+			name: "invalid owner",
+			input: struct {
+				desired []*endpoint.Endpoint
+				owner   string
+			}{
+				owner: "-ci-",
+			},
+			expected: errors.New(`invalid owner ID "-ci-"`),
+		},
+		{
+			name:     "empty owner",
+			expected: errors.New(`invalid owner ID ""`),
+		},
+		{
+			name: "zone not managed",
+			input: struct {
+				desired []*endpoint.Endpoint
+				owner   string
+			}{
+				desired: []*endpoint.Endpoint{endpoint.NewEndpoint("www.beta.com", "A", "127.0.0.1")},
+				owner:   "ci",
+			},
+			expected: errors.New("no managed zone for www.beta.com"),
+		},
+		{
+			name: "duplicate record",
+			input: struct {
+				desired []*endpoint.Endpoint
+				owner   string
+			}{
+				desired: []*endpoint.Endpoint{
+					endpoint.NewEndpoint("www.alpha.com", "A", "127.0.0.1"),
+					endpoint.NewEndpoint("www.alpha.com.", "A", "127.0.0.2"),
+				},
+				owner: "ci",
+			},
+			expected: errors.New("duplicate record www.alpha.com A"),
+		},
+		{
+			name: "unsupported type",
+			input: struct {
+				desired []*endpoint.Endpoint
+				owner   string
+			}{
+				desired: []*endpoint.Endpoint{endpoint.NewEndpoint("www.alpha.com", "PTR", "alpha.com")},
+				owner:   "ci",
+			},
+			expected: errors.New("unsupported record type PTR for www.alpha.com"),
+		},
+		{
+			name: "no targets",
+			input: struct {
+				desired []*endpoint.Endpoint
+				owner   string
+			}{
+				desired: []*endpoint.Endpoint{endpoint.NewEndpoint("www.alpha.com", "A")},
+				owner:   "ci",
+			},
+			expected: errors.New("no targets for www.alpha.com A"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
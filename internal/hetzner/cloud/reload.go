@@ -0,0 +1,107 @@
+/*
+ * Reload - runtime reconfiguration of the provider.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"sync"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/hetzner"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// settings contains the provider settings that can change at runtime.
+type settings struct {
+	batchSize         int
+	debug             bool
+	dryRun            bool
+	domainFilter      *endpoint.DomainFilter
+	zoneCacheDuration time.Duration
+}
+
+// pendingSettings holds the settings waiting to be applied. The settings are
+// applied at the start of the next Records call, which begins a
+// synchronization, so that the changes are never planned and applied with
+// different settings.
+type pendingSettings struct {
+	m        sync.Mutex
+	settings *settings
+}
+
+// set stores the settings, replacing any settings not applied yet.
+func (ps *pendingSettings) set(s *settings) {
+	if ps == nil {
+		return
+	}
+	ps.m.Lock()
+	defer ps.m.Unlock()
+	ps.settings = s
+}
+
+// take returns the pending settings, if any, and clears them.
+func (ps *pendingSettings) take() *settings {
+	if ps == nil {
+		return nil
+	}
+	ps.m.Lock()
+	defer ps.m.Unlock()
+	s := ps.settings
+	ps.settings = nil
+	return s
+}
+
+// Reconfigure validates the settings of config that can change at runtime and
+// schedules them for the next synchronization: domain filters, zone cache
+// TTL, dry run, batch size and debug. The other settings are ignored.
+func (p *HetznerProvider) Reconfigure(config *hetzner.Configuration) error {
+	if err := config.ValidateSettings(); err != nil {
+		return err
+	}
+	domainFilter, err := hetzner.GetDomainFilter(*config)
+	if err != nil {
+		return err
+	}
+	p.pending.set(&settings{
+		batchSize:         config.BatchSize,
+		debug:             config.Debug,
+		dryRun:            config.DryRun,
+		domainFilter:      domainFilter,
+		zoneCacheDuration: time.Duration(int64(config.ZoneCacheTTL) * int64(time.Second)),
+	})
+	return nil
+}
+
+// applyPendingSettings applies the settings scheduled by Reconfigure. The zone
+// cache is cleared, because the domain filter may have changed.
+func (p *HetznerProvider) applyPendingSettings() {
+	s := p.pending.take()
+	if s == nil {
+		return
+	}
+	if s.dryRun != p.dryRun {
+		providerLog.Warnf("Dry run changed to %t.", s.dryRun)
+	}
+	p.batchSize = s.batchSize
+	p.debug = s.debug
+	p.dryRun = s.dryRun
+	p.domainFilter = s.domainFilter
+	p.zoneCacheDuration = s.zoneCacheDuration
+	p.zoneCache = nil
+	providerLog.Info("Applied the reloaded configuration.")
+}
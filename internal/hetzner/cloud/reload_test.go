@@ -0,0 +1,118 @@
+/*
+ * Reload - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/fakeapi"
+	"external-dns-hetzner-webhook/internal/hetzner"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func Test_pendingSettings(t *testing.T) {
+	var nilPending *pendingSettings
+	assert.NotPanics(t, func() {
+		nilPending.set(&settings{})
+	})
+	assert.Nil(t, nilPending.take())
+
+	ps := &pendingSettings{}
+	assert.Nil(t, ps.take())
+	ps.set(&settings{batchSize: 10})
+	ps.set(&settings{batchSize: 20})
+	assert.Equal(t, &settings{batchSize: 20}, ps.take())
+	assert.Nil(t, ps.take())
+}
+
+func Test_Reconfigure(t *testing.T) {
+	p := &HetznerProvider{
+		batchSize:    100,
+		domainFilter: endpoint.NewDomainFilter([]string{"alpha.com"}),
+		zoneCache:    []*hcloud.Zone{{ID: 1, Name: "alpha.com"}},
+		pending:      &pendingSettings{},
+	}
+
+	// Invalid settings are rejected and nothing is scheduled.
+	err := p.Reconfigure(&hetzner.Configuration{BatchSize: 500})
+	assert.NotNil(t, err)
+	p.applyPendingSettings()
+	assert.Equal(t, 100, p.batchSize)
+	assert.NotNil(t, p.zoneCache)
+
+	// Valid settings are applied at the next synchronization.
+	err = p.Reconfigure(&hetzner.Configuration{
+		BatchSize:    50,
+		Debug:        true,
+		DryRun:       true,
+		DomainFilter: []string{"beta.com"},
+		ZoneCacheTTL: 60,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 100, p.batchSize)
+	p.applyPendingSettings()
+	assert.Equal(t, 50, p.batchSize)
+	assert.True(t, p.debug)
+	assert.True(t, p.dryRun)
+	assertEqualDomainFilter(t, endpoint.NewDomainFilter([]string{"beta.com"}), p.domainFilter)
+	assert.Equal(t, time.Minute, p.zoneCacheDuration)
+	assert.Nil(t, p.zoneCache)
+}
+
+// Test_Reconfigure_concurrent tests that the configuration can be reloaded
+// while the records are read. Run it with -race.
+func Test_Reconfigure_concurrent(t *testing.T) {
+	fake := fakeapi.NewServer("TEST_API_KEY")
+	defer fake.Close()
+	fake.AddZone("alpha.com", 3600)
+	config := &hetzner.Configuration{
+		APIKey:      "TEST_API_KEY",
+		APIEndpoint: fake.URL,
+		BatchSize:   50,
+		SlashEscSeq: "--slash--",
+	}
+	p, err := NewHetznerProvider(config, nil)
+	assert.Nil(t, err)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				assert.Nil(t, p.Reconfigure(config))
+			}
+		}
+	}()
+	for range 10 {
+		_, err := p.Records(context.Background())
+		assert.Nil(t, err)
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,107 @@
+/*
+ * Restore - restores the zonefile backups.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"external-dns-hetzner-webhook/internal/zonefile"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// zonefileStore reads and saves the zonefile backups.
+type zonefileStore interface {
+	zonefileBackups
+	Load(zone string, name string) (string, error)
+}
+
+// findZone returns the zone with the given name.
+func findZone(ctx context.Context, client apiClient, zoneName string) (*hcloud.Zone, error) {
+	zones, _, err := client.GetZones(ctx, hcloud.ZoneListOpts{Name: zoneName})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read zone %s: %w", zoneName, err)
+	}
+	for _, z := range zones {
+		if z.Name == zoneName {
+			return z, nil
+		}
+	}
+	return nil, fmt.Errorf("zone %s not found", zoneName)
+}
+
+// restoreZonefile imports a zonefile backup in the zone. An empty backupName
+// selects the newest backup. The current zonefile is saved first, so that the
+// restore can be undone. The SOA serial number is updated, so that the
+// restored zone is propagated to the secondary servers.
+func restoreZonefile(ctx context.Context, client apiClient, store zonefileStore, zoneName, backupName string, dryRun bool) error {
+	zf, err := store.Load(zoneName, backupName)
+	if err != nil {
+		return err
+	}
+	zone, err := findZone(ctx, client, zoneName)
+	if err != nil {
+		return err
+	}
+	bulk := NewBulkChanges(client, dryRun, "", nil, -1, store, nil)
+	// Running no changes only updates the SOA serial number.
+	nzf, err := bulk.runZoneChanges(zone, zf)
+	if err != nil {
+		return fmt.Errorf("cannot prepare backup for zone %s: %w", zoneName, err)
+	}
+	if dryRun {
+		zonefileLog.Infof("Dry run: zonefile for zone [%s] not restored.", zoneName)
+		return nil
+	}
+	current, _, err := client.ExportZonefile(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("cannot download zonefile for zone %s: %w", zoneName, err)
+	}
+	if err := bulk.backupZonefile(zone, current.Zonefile); err != nil {
+		return err
+	}
+	action, _, err := client.ImportZonefile(ctx, zone, hcloud.ZoneImportZonefileOpts{Zonefile: nzf})
+	if err != nil {
+		return fmt.Errorf("cannot upload zonefile for zone %s: %w", zoneName, err)
+	}
+	if action != nil {
+		if err := client.WaitForAction(ctx, action); err != nil {
+			return fmt.Errorf("error while importing zonefile for zone %s: %w", zoneName, err)
+		}
+	}
+	zonefileLog.Infof("Restored zonefile for zone [%s].", zoneName)
+	return nil
+}
+
+// RestoreZonefile restores a backup of the zonefile, saved in bulk mode, in
+// the zone. An empty backupName selects the newest backup. Nothing is imported
+// in dry run mode.
+func (p *HetznerProvider) RestoreZonefile(ctx context.Context, zoneName, backupName string) error {
+	if p.backupDir == "" {
+		return errors.New("backup directory not configured")
+	}
+	// Restoring does not rotate the backups, so that the backup of the
+	// replaced zonefile does not remove older ones.
+	backups, err := zonefile.NewBackups(p.backupDir, 0, 0)
+	if err != nil {
+		return err
+	}
+	return restoreZonefile(ctx, p.client, backups, zoneName, backupName, p.dryRun)
+}
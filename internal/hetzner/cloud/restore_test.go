@@ -0,0 +1,288 @@
+/*
+ * Restore - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetznercloud
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"external-dns-hetzner-webhook/internal/zonefile"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockStore simulates the zonefile backups.
+type mockStore struct {
+	mockBackups
+	zf  string
+	err error
+}
+
+// Load returns the configured zonefile.
+func (m *mockStore) Load(zone string, name string) (string, error) {
+	return m.zf, m.err
+}
+
+// Test_restoreZonefile tests restoreZonefile().
+func Test_restoreZonefile(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			client *mockClient
+			store  *mockStore
+			dryRun bool
+		}
+		expected struct {
+			imported bool
+			saved    string
+			err      error
+		}
+	}
+
+	zone := &hcloud.Zone{ID: 1, Name: "fastipletonis.eu", TTL: 3600}
+	zones := zonesResponse{zones: []*hcloud.Zone{zone}}
+	current := exportZonefileResponse{result: hcloud.ZoneExportZonefileResult{Zonefile: "current zonefile"}}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		err := restoreZonefile(context.Background(), inp.client, inp.store, "fastipletonis.eu", "", inp.dryRun)
+		if !assertError(t, exp.err, err) {
+			assert.Equal(t, exp.imported, inp.client.state.ImportZonefileCalled)
+		}
+		assert.Equal(t, exp.saved, inp.store.saved["fastipletonis.eu"])
+		if exp.imported {
+			nzf := inp.client.args.ImportZoneFile.opts.Zonefile
+			assert.True(t, strings.Contains(nzf, "www.fastipletonis.eu."))
+			assert.False(t, strings.Contains(nzf, oldSOA))
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name: "backup restored",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{getZones: zones, exportZonefile: current},
+				store:  &mockStore{zf: inputZoneFile},
+			},
+			expected: struct {
+				imported bool
+				saved    string
+				err      error
+			}{
+				imported: true,
+				saved:    "current zonefile",
+			},
+		},
+		{
+			name: "export error",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{
+					getZones:       zones,
+					exportZonefile: exportZonefileResponse{err: errors.New("test export error")},
+				},
+				store: &mockStore{zf: inputZoneFile},
+			},
+			expected: struct {
+				imported bool
+				saved    string
+				err      error
+			}{
+				err: errors.New("cannot download zonefile for zone fastipletonis.eu: test export error"),
+			},
+		},
+		{
+			name: "backup save error",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{getZones: zones, exportZonefile: current},
+				store: &mockStore{
+					mockBackups: mockBackups{err: errors.New("test save error")},
+					zf:          inputZoneFile,
+				},
+			},
+			expected: struct {
+				imported bool
+				saved    string
+				err      error
+			}{
+				err: errors.New("cannot save zonefile backup for zone fastipletonis.eu: test save error"),
+			},
+		},
+		{
+			name: "dry run",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{getZones: zones},
+				store:  &mockStore{zf: inputZoneFile},
+				dryRun: true,
+			},
+		},
+		{
+			name: "backup error",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{getZones: zones},
+				store:  &mockStore{err: errors.New("no backups found for zone fastipletonis.eu")},
+			},
+			expected: struct {
+				imported bool
+				saved    string
+				err      error
+			}{
+				err: errors.New("no backups found for zone fastipletonis.eu"),
+			},
+		},
+		{
+			name: "zone not found",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{},
+				store:  &mockStore{zf: inputZoneFile},
+			},
+			expected: struct {
+				imported bool
+				saved    string
+				err      error
+			}{
+				err: errors.New("zone fastipletonis.eu not found"),
+			},
+		},
+		{
+			name: "zones error",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{getZones: zonesResponse{err: errors.New("test zones error")}},
+				store:  &mockStore{zf: inputZoneFile},
+			},
+			expected: struct {
+				imported bool
+				saved    string
+				err      error
+			}{
+				err: errors.New("cannot read zone fastipletonis.eu: test zones error"),
+			},
+		},
+		{
+			name: "import error",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{
+					getZones:       zones,
+					exportZonefile: current,
+					importZonefile: actionResponse{err: errors.New("test import error")},
+				},
+				store: &mockStore{zf: inputZoneFile},
+			},
+			expected: struct {
+				imported bool
+				saved    string
+				err      error
+			}{
+				saved: "current zonefile",
+				err:   errors.New("cannot upload zonefile for zone fastipletonis.eu: test import error"),
+			},
+		},
+		{
+			name: "wait error",
+			input: struct {
+				client *mockClient
+				store  *mockStore
+				dryRun bool
+			}{
+				client: &mockClient{
+					getZones:       zones,
+					exportZonefile: current,
+					importZonefile: actionResponse{action: &hcloud.Action{ID: 1}},
+					waitForAction:  errors.New("test wait error"),
+				},
+				store: &mockStore{zf: inputZoneFile},
+			},
+			expected: struct {
+				imported bool
+				saved    string
+				err      error
+			}{
+				saved: "current zonefile",
+				err:   errors.New("error while importing zonefile for zone fastipletonis.eu: test wait error"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_HetznerProvider_RestoreZonefile tests HetznerProvider.RestoreZonefile().
+func Test_HetznerProvider_RestoreZonefile(t *testing.T) {
+	p := &HetznerProvider{}
+	err := p.RestoreZonefile(context.Background(), "fastipletonis.eu", "")
+	assert.EqualError(t, err, "backup directory not configured")
+
+	dir := t.TempDir()
+	backups, err := zonefile.NewBackups(dir, 0, 0)
+	assert.Nil(t, err)
+	_, err = backups.Save("fastipletonis.eu", inputZoneFile)
+	assert.Nil(t, err)
+	client := &mockClient{
+		getZones:       zonesResponse{zones: []*hcloud.Zone{{ID: 1, Name: "fastipletonis.eu", TTL: 3600}}},
+		exportZonefile: exportZonefileResponse{result: hcloud.ZoneExportZonefileResult{Zonefile: "current zonefile"}},
+	}
+	p = &HetznerProvider{client: client, backupDir: dir}
+	assert.Nil(t, p.RestoreZonefile(context.Background(), "fastipletonis.eu", ""))
+	assert.True(t, client.state.ImportZonefileCalled)
+	names, err := backups.List("fastipletonis.eu")
+	assert.Nil(t, err)
+	if assert.Len(t, names, 2) {
+		latest, err := backups.Load("fastipletonis.eu", names[0])
+		assert.Nil(t, err)
+		assert.Equal(t, "current zonefile", latest)
+	}
+}
@@ -25,17 +25,25 @@ import (
 	"strings"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
-	log "github.com/sirupsen/logrus"
 
 	"golang.org/x/net/idna"
 )
 
-type zoneIDName map[int64]*hcloud.Zone
+// zoneEntry is a zone and the name of the project that owns it. The project
+// is empty when a single project is configured.
+type zoneEntry struct {
+	zone    *hcloud.Zone
+	project string
+}
+
+// zoneIDName maps the zone IDs to the zones and the projects that own them.
+type zoneIDName map[int64]zoneEntry
 
-func (z zoneIDName) Add(zone *hcloud.Zone) {
+// Add adds a zone owned by a project.
+func (z zoneIDName) Add(zone *hcloud.Zone, project string) {
 	if zone != nil {
 		zoneID := zone.ID
-		z[zoneID] = zone
+		z[zoneID] = zoneEntry{zone: zone, project: project}
 	}
 }
 
@@ -60,7 +68,7 @@ func (z zoneIDName) FindZone(hostname string) (int64, *hcloud.Zone) {
 		}
 		convertedLabel, err := idna.ToUnicode(label)
 		if err != nil {
-			log.Warnf("Failed to convert label %q of hostname %q to its Unicode form: %v", label, hostname, err)
+			providerLog.Warnf("Failed to convert label %q of hostname %q to its Unicode form: %v", label, hostname, err)
 			convertedLabel = label
 		}
 		domainLabels[i] = convertedLabel
@@ -71,7 +79,8 @@ func (z zoneIDName) FindZone(hostname string) (int64, *hcloud.Zone) {
 	suitableZoneName := ""
 	var suitableZone *hcloud.Zone = nil
 
-	for zoneID, zone := range z {
+	for zoneID, entry := range z {
+		zone := entry.zone
 		zoneName := zone.Name
 		if name == zoneName || strings.HasSuffix(name, "."+zoneName) {
 			if suitableZone == nil || len(zoneName) > len(suitableZoneName) {
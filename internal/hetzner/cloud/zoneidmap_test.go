@@ -28,15 +28,19 @@ import (
 // Test_zoneIDName_Add tests zoneIDName.Add().
 func Test_zoneIDName_Add(t *testing.T) {
 	type testCase struct {
-		name           string
-		object         zoneIDName
-		input          *hcloud.Zone
+		name   string
+		object zoneIDName
+		input  struct {
+			zone    *hcloud.Zone
+			project string
+		}
 		expectedObject zoneIDName
 	}
 
 	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
 		obj := tc.object
-		obj.Add(tc.input)
+		obj.Add(inp.zone, inp.project)
 		assert.Equal(t, tc.expectedObject, obj)
 	}
 
@@ -44,21 +48,29 @@ func Test_zoneIDName_Add(t *testing.T) {
 		{
 			name:           "nil zone",
 			object:         zoneIDName{},
-			input:          nil,
 			expectedObject: zoneIDName{},
 		},
 		{
 			name:   "add zone",
 			object: zoneIDName{},
-			input: &hcloud.Zone{
-				ID:   1,
-				Name: "alpha.com",
-			},
-			expectedObject: zoneIDName{
-				1: &hcloud.Zone{
+			input: struct {
+				zone    *hcloud.Zone
+				project string
+			}{
+				zone: &hcloud.Zone{
 					ID:   1,
 					Name: "alpha.com",
 				},
+				project: "alpha",
+			},
+			expectedObject: zoneIDName{
+				1: {
+					zone: &hcloud.Zone{
+						ID:   1,
+						Name: "alpha.com",
+					},
+					project: "alpha",
+				},
 			},
 		},
 	}
@@ -94,10 +106,10 @@ func Test_zoneIDName_FindZone(t *testing.T) {
 		{
 			name: "zone found 1",
 			object: zoneIDName{
-				1: &hcloud.Zone{
+				1: {zone: &hcloud.Zone{
 					ID:   1,
 					Name: "alpha.com",
-				},
+				}},
 			},
 			input: "www.alpha.com",
 			expected: struct {
@@ -114,10 +126,10 @@ func Test_zoneIDName_FindZone(t *testing.T) {
 		{
 			name: "zone found 2",
 			object: zoneIDName{
-				1: &hcloud.Zone{
+				1: {zone: &hcloud.Zone{
 					ID:   1,
 					Name: "alpha.com",
-				},
+				}},
 			},
 			input: "www.sub.alpha.com",
 			expected: struct {
@@ -134,10 +146,10 @@ func Test_zoneIDName_FindZone(t *testing.T) {
 		{
 			name: "zone not found",
 			object: zoneIDName{
-				1: &hcloud.Zone{
+				1: {zone: &hcloud.Zone{
 					ID:   1,
 					Name: "alpha.com",
-				},
+				}},
 			},
 			input: "www.beta.com",
 			expected: struct {
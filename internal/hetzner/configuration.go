@@ -18,7 +18,9 @@
 package hetzner
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
@@ -28,10 +30,59 @@ import (
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
+// MaxBatchSize is the maximum page size accepted by the API.
+const MaxBatchSize = 100
+
+var (
+	// slashEscSeqChecker checks that the slash escape sequence only contains
+	// characters allowed in the names of the annotations.
+	slashEscSeqChecker = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+	// slashEscSeqAlnum and slashEscSeqSep check that the slash escape
+	// sequence mixes letters or digits with separators, so that it is unlikely
+	// to appear in ordinary label names.
+	slashEscSeqAlnum = regexp.MustCompile(`[a-zA-Z0-9]`)
+	slashEscSeqSep   = regexp.MustCompile(`[_.-]`)
+)
+
+// minSlashEscSeqLen is the minimum length of the slash escape sequence.
+const minSlashEscSeqLen = 3
+
+// validateSlashEscSeq checks that the slash escape sequence can be told apart
+// from the slashes it replaces.
+func validateSlashEscSeq(seq string) error {
+	if seq == "" || strings.Contains(seq, "/") {
+		return fmt.Errorf("invalid SLASH_ESC_SEQ %q: it cannot be empty or contain '/'", seq)
+	}
+	return nil
+}
+
+// slashEscSeqWarning returns the reason why the slash escape sequence is
+// discouraged, or an empty string. Such sequences are accepted, but they may
+// not be allowed in label names or may be confused with their ordinary
+// characters, which would be turned into slashes when the labels are read.
+func slashEscSeqWarning(seq string) string {
+	if !slashEscSeqChecker.MatchString(seq) {
+		return "only letters, digits, '-', '_' and '.' are allowed in label names"
+	}
+	if len(seq) < minSlashEscSeqLen || !slashEscSeqAlnum.MatchString(seq) || !slashEscSeqSep.MatchString(seq) {
+		return fmt.Sprintf("use at least %d characters, mixing letters or digits with '-', '_' or '.'", minSlashEscSeqLen)
+	}
+	return ""
+}
+
 // Configuration contains the Hetzner provider's configuration.
 type Configuration struct {
 	// DNS API key or Cloud API key
-	APIKey string `env:"HETZNER_API_KEY" required:"true"`
+	APIKey string `env:"HETZNER_API_KEY" default:""`
+	// File containing the API key, alternative to APIKey. The file is watched
+	// and the new key is used as soon as it changes.
+	APIKeyFile string `env:"HETZNER_API_KEY_FILE" default:""`
+	// API keys of additional projects, as name=key pairs
+	APIKeys []string `env:"HETZNER_API_KEYS" default:""`
+	// API key files of additional projects, as name=path pairs
+	APIKeyFiles []string `env:"HETZNER_API_KEY_FILES" default:""`
+	// Base URL of the Hetzner Cloud API, empty for the default one
+	APIEndpoint string `env:"HETZNER_API_ENDPOINT" default:""`
 	// If true, do not execute actions on the API
 	DryRun bool `env:"DRY_RUN" default:"false"`
 	// Enable debugging logs
@@ -48,14 +99,41 @@ type Configuration struct {
 	RegexDomainExclusion string `env:"REGEXP_DOMAIN_FILTER_EXCLUSION" default:""`
 	// Slash escape sequence for labels
 	SlashEscSeq string `env:"SLASH_ESC_SEQ" default:"--slash--"`
+	// Default labels applied to every managed RRSet, as label=value pairs.
+	// The values can be templates using {{.Zone}}, {{.Type}} and {{.Name}}.
+	DefaultLabels []string `env:"DEFAULT_LABELS" default:""`
 	// Failed reads count before shutting down the container. It gets reset for
 	// every successful API access. A negative or 0 value disables the fail shut
 	// down.
 	MaxFailCount int `env:"MAX_FAIL_COUNT" default:"-1"`
 	// Zones cache TTL in seconds.
 	ZoneCacheTTL int `env:"ZONE_CACHE_TTL" default:"0"`
+	// Seconds below which the predicted exhaustion of the rate limit raises a
+	// warning. A negative or 0 value disables the warning.
+	RateLimitWarningHorizon int `env:"RATE_LIMIT_WARNING_HORIZON" default:"600"`
 	// Enable bulk mode
 	BulkMode bool `env:"BULK_MODE" default:"false"`
+	// Minimum number of changes in a zone for using the bulk mode. Zones with
+	// fewer changes use single RRSet calls. 0 means that the bulk mode is always
+	// used. It is considered only if the bulk mode is enabled.
+	BulkModeThreshold int `env:"BULK_MODE_THRESHOLD" default:"0"`
+	// Number of times the changes are applied again when a zone is modified
+	// concurrently in bulk mode. A negative value disables the check.
+	BulkModeConflictRetries int `env:"BULK_MODE_CONFLICT_RETRIES" default:"2"`
+	// Directory where the zone files are saved before every bulk import. An
+	// empty value disables the backups.
+	BulkModeBackupDir string `env:"BULK_MODE_BACKUP_DIR" default:""`
+	// Maximum number of backups kept for each zone. A negative or 0 value
+	// disables the limit.
+	BulkModeBackupRetention int `env:"BULK_MODE_BACKUP_RETENTION" default:"10"`
+	// Maximum age of the backups in hours. A negative or 0 value disables the
+	// limit.
+	BulkModeBackupMaxAge int `env:"BULK_MODE_BACKUP_MAX_AGE" default:"0"`
+	// Interval in seconds between the checks of the applied RRSets for changes
+	// made outside the webhook. A negative or 0 value disables the checks.
+	DriftCheckInterval int `env:"DRIFT_CHECK_INTERVAL" default:"0"`
+	// Correct the detected drift on the next ApplyChanges call.
+	DriftAutoCorrect bool `env:"DRIFT_AUTO_CORRECT" default:"false"`
 }
 
 // NewConfiguration creates a new configuration object.
@@ -73,9 +151,77 @@ func NewConfiguration() (*Configuration, error) {
 	return cfg, nil
 }
 
+// ValidateSettings checks the settings that can change at runtime and returns
+// all the problems found.
+func (c Configuration) ValidateSettings() error {
+	errs := make([]error, 0)
+	if c.BatchSize < 1 || c.BatchSize > MaxBatchSize {
+		errs = append(errs, fmt.Errorf("BATCH_SIZE must be between 1 and %d, got %d", MaxBatchSize, c.BatchSize))
+	}
+	if _, err := regexp.Compile(c.RegexDomainFilter); err != nil {
+		errs = append(errs, fmt.Errorf("invalid REGEXP_DOMAIN_FILTER: %w", err))
+	}
+	if _, err := regexp.Compile(c.RegexDomainExclusion); err != nil {
+		errs = append(errs, fmt.Errorf("invalid REGEXP_DOMAIN_FILTER_EXCLUSION: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the configuration and returns all the problems found. The
+// API key files are not read.
+func (c Configuration) Validate() error {
+	errs := []error{c.ValidateSettings()}
+	if c.APIKey != "" && c.APIKeyFile != "" {
+		errs = append(errs, errors.New("only one of HETZNER_API_KEY and HETZNER_API_KEY_FILE can be set"))
+	}
+	projects, err := c.GetProjects()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if c.APIEndpoint != "" {
+		if u, err := url.Parse(c.APIEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid HETZNER_API_ENDPOINT %q: expected an http or https URL", c.APIEndpoint))
+		}
+	}
+	for _, p := range projects {
+		if p.APIKey == "" && p.APIKeyFile == "" {
+			errs = append(errs, errors.New("no API key provided: set HETZNER_API_KEY, HETZNER_API_KEY_FILE, HETZNER_API_KEYS or HETZNER_API_KEY_FILES"))
+		}
+	}
+	if c.DriftAutoCorrect && c.DriftCheckInterval <= 0 {
+		errs = append(errs, errors.New("DRIFT_AUTO_CORRECT requires a positive DRIFT_CHECK_INTERVAL"))
+	}
+	if err := validateSlashEscSeq(c.SlashEscSeq); err != nil {
+		errs = append(errs, err)
+	} else if warning := slashEscSeqWarning(c.SlashEscSeq); warning != "" {
+		log.Warnf("SLASH_ESC_SEQ %q is deprecated and may be rejected in the future: %s.", c.SlashEscSeq, warning)
+	}
+	return errors.Join(errs...)
+}
+
+// redacted replaces the secret values.
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the configuration with the API keys redacted.
+// The project names are kept.
+func (c Configuration) Redacted() Configuration {
+	if c.APIKey != "" {
+		c.APIKey = redacted
+	}
+	if len(c.APIKeys) > 0 {
+		keys := make([]string, len(c.APIKeys))
+		for i, pair := range c.APIKeys {
+			name, _, _ := strings.Cut(pair, "=")
+			keys[i] = name + "=" + redacted
+		}
+		c.APIKeys = keys
+	}
+	return c
+}
+
 // GetDomainFilter returns the domain filter from the configuration. If the
 // regular expression filters are set, the others are ignored.
-func GetDomainFilter(config Configuration) *endpoint.DomainFilter {
+func GetDomainFilter(config Configuration) (*endpoint.DomainFilter, error) {
 	var domainFilter *endpoint.DomainFilter
 	createMsg := "Creating Hetzner provider with "
 
@@ -84,10 +230,15 @@ func GetDomainFilter(config Configuration) *endpoint.DomainFilter {
 		if config.RegexDomainExclusion != "" {
 			createMsg += fmt.Sprintf("with exclusion: '%s', ", config.RegexDomainExclusion)
 		}
-		domainFilter = endpoint.NewRegexDomainFilter(
-			regexp.MustCompile(config.RegexDomainFilter),
-			regexp.MustCompile(config.RegexDomainExclusion),
-		)
+		filter, err := regexp.Compile(config.RegexDomainFilter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REGEXP_DOMAIN_FILTER: %w", err)
+		}
+		exclusion, err := regexp.Compile(config.RegexDomainExclusion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REGEXP_DOMAIN_FILTER_EXCLUSION: %w", err)
+		}
+		domainFilter = endpoint.NewRegexDomainFilter(filter, exclusion)
 	} else {
 		if len(config.DomainFilter) > 0 {
 			createMsg += fmt.Sprintf("zoneNode filter: '%s', ", strings.Join(config.DomainFilter, ","))
@@ -103,7 +254,7 @@ func GetDomainFilter(config Configuration) *endpoint.DomainFilter {
 		createMsg += "no kind of domain filters"
 	}
 	log.Info(createMsg)
-	return domainFilter
+	return domainFilter, nil
 }
 
 // IsSupportedRecordType checks if a record type is supported by this webhook.
@@ -40,10 +40,17 @@ func Test_GetDomainFilter(t *testing.T) {
 		name     string
 		config   Configuration
 		expected *endpoint.DomainFilter
+		err      string
 	}
 
 	run := func(t *testing.T, tc testCase) {
-		actual := GetDomainFilter(tc.config)
+		actual, err := GetDomainFilter(tc.config)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			assert.Nil(t, actual)
+			return
+		}
+		assert.Nil(t, err)
 		assertEqualDomainFilter(t, tc.expected, actual)
 	}
 
@@ -89,6 +96,21 @@ func Test_GetDomainFilter(t *testing.T) {
 				regexp.MustCompile(`[a-z]+-excluded\.com`),
 			),
 		},
+		{
+			name: "Invalid regular expression domain filter",
+			config: Configuration{
+				RegexDomainFilter: "(",
+			},
+			err: "invalid REGEXP_DOMAIN_FILTER: error parsing regexp: missing closing ): `(`",
+		},
+		{
+			name: "Invalid regular expression domain exclusion",
+			config: Configuration{
+				RegexDomainFilter:    `example-[a-z]+\.com`,
+				RegexDomainExclusion: "[",
+			},
+			err: "invalid REGEXP_DOMAIN_FILTER_EXCLUSION: error parsing regexp: missing closing ]: `[`",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -127,3 +149,227 @@ func Test_IsSupportedRecordType(t *testing.T) {
 		})
 	}
 }
+
+// Test_Configuration_Redacted tests that the API key is redacted.
+func Test_Configuration_Redacted(t *testing.T) {
+	cfg := Configuration{APIKey: "secret", BatchSize: 100}
+	actual := cfg.Redacted()
+	assert.Equal(t, Configuration{APIKey: "REDACTED", BatchSize: 100}, actual)
+	assert.Equal(t, "secret", cfg.APIKey)
+	assert.Equal(t, Configuration{}, Configuration{}.Redacted())
+
+	cfg = Configuration{APIKeys: []string{"prod=secret1", "staging=secret2"}}
+	actual = cfg.Redacted()
+	assert.Equal(t, []string{"prod=REDACTED", "staging=REDACTED"}, actual.APIKeys)
+	assert.Equal(t, "prod=secret1", cfg.APIKeys[0])
+}
+
+// Test_Configuration_ValidateSettings tests ValidateSettings().
+func Test_Configuration_ValidateSettings(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    Configuration
+		expected string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		err := tc.input.ValidateSettings()
+		if tc.expected == "" {
+			assert.Nil(t, err)
+			return
+		}
+		assert.EqualError(t, err, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "valid",
+			input: Configuration{BatchSize: 100, RegexDomainFilter: `.*\.com$`},
+		},
+		{
+			name:     "batch size too small",
+			input:    Configuration{BatchSize: 0},
+			expected: "BATCH_SIZE must be between 1 and 100, got 0",
+		},
+		{
+			name:     "batch size too large",
+			input:    Configuration{BatchSize: 101},
+			expected: "BATCH_SIZE must be between 1 and 100, got 101",
+		},
+		{
+			name:     "invalid domain filter",
+			input:    Configuration{BatchSize: 100, RegexDomainFilter: "("},
+			expected: "invalid REGEXP_DOMAIN_FILTER: error parsing regexp: missing closing ): `(`",
+		},
+		{
+			name:     "invalid domain exclusion",
+			input:    Configuration{BatchSize: 100, RegexDomainExclusion: "["},
+			expected: "invalid REGEXP_DOMAIN_FILTER_EXCLUSION: error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			name:  "all errors",
+			input: Configuration{BatchSize: 500, RegexDomainFilter: "(", RegexDomainExclusion: "["},
+			expected: "BATCH_SIZE must be between 1 and 100, got 500\n" +
+				"invalid REGEXP_DOMAIN_FILTER: error parsing regexp: missing closing ): `(`\n" +
+				"invalid REGEXP_DOMAIN_FILTER_EXCLUSION: error parsing regexp: missing closing ]: `[`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_Configuration_Validate tests Validate().
+func Test_Configuration_Validate(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    Configuration
+		expected string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		err := tc.input.Validate()
+		if tc.expected == "" {
+			assert.Nil(t, err)
+			return
+		}
+		assert.EqualError(t, err, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "valid",
+			input: Configuration{APIKey: "secret", BatchSize: 100, SlashEscSeq: "--slash--"},
+		},
+		{
+			name: "valid projects",
+			input: Configuration{
+				APIKeys:     []string{"prod=secret"},
+				APIKeyFiles: []string{"staging=/run/secrets/staging"},
+				BatchSize:   100,
+				SlashEscSeq: "--slash--",
+			},
+		},
+		{
+			name:     "no API key",
+			input:    Configuration{BatchSize: 100, SlashEscSeq: "--slash--"},
+			expected: "no API key provided: set HETZNER_API_KEY, HETZNER_API_KEY_FILE, HETZNER_API_KEYS or HETZNER_API_KEY_FILES",
+		},
+		{
+			name:     "API key and file",
+			input:    Configuration{APIKey: "secret", APIKeyFile: "/run/secrets/token", BatchSize: 100, SlashEscSeq: "--slash--"},
+			expected: "only one of HETZNER_API_KEY and HETZNER_API_KEY_FILE can be set",
+		},
+		{
+			name:     "invalid projects",
+			input:    Configuration{APIKeys: []string{"prod"}, BatchSize: 100, SlashEscSeq: "--slash--"},
+			expected: "invalid entry in HETZNER_API_KEYS: expected name=value",
+		},
+		{
+			name:     "empty slash escape sequence",
+			input:    Configuration{APIKey: "secret", BatchSize: 100},
+			expected: `invalid SLASH_ESC_SEQ "": it cannot be empty or contain '/'`,
+		},
+		{
+			name:     "slash escape sequence with a slash",
+			input:    Configuration{APIKey: "secret", BatchSize: 100, SlashEscSeq: "-/-"},
+			expected: `invalid SLASH_ESC_SEQ "-/-": it cannot be empty or contain '/'`,
+		},
+		{
+			name:  "discouraged slash escape sequence",
+			input: Configuration{APIKey: "secret", BatchSize: 100, SlashEscSeq: "-"},
+		},
+		{
+			name:  "custom slash escape sequence",
+			input: Configuration{APIKey: "secret", BatchSize: 100, SlashEscSeq: "_s_"},
+		},
+		{
+			name:  "API endpoint",
+			input: Configuration{APIKey: "secret", BatchSize: 100, SlashEscSeq: "--slash--", APIEndpoint: "http://127.0.0.1:8080/v1"},
+		},
+		{
+			name:     "invalid API endpoint",
+			input:    Configuration{APIKey: "secret", BatchSize: 100, SlashEscSeq: "--slash--", APIEndpoint: "127.0.0.1:8080"},
+			expected: `invalid HETZNER_API_ENDPOINT "127.0.0.1:8080": expected an http or https URL`,
+		},
+		{
+			name:     "drift correction without checks",
+			input:    Configuration{APIKey: "secret", BatchSize: 100, SlashEscSeq: "--slash--", DriftAutoCorrect: true},
+			expected: "DRIFT_AUTO_CORRECT requires a positive DRIFT_CHECK_INTERVAL",
+		},
+		{
+			name:  "drift correction",
+			input: Configuration{APIKey: "secret", BatchSize: 100, SlashEscSeq: "--slash--", DriftCheckInterval: 300, DriftAutoCorrect: true},
+		},
+		{
+			name: "all errors",
+			input: Configuration{
+				APIKey:      "secret",
+				APIKeyFile:  "/run/secrets/token",
+				BatchSize:   0,
+				SlashEscSeq: "/",
+			},
+			expected: "BATCH_SIZE must be between 1 and 100, got 0\n" +
+				"only one of HETZNER_API_KEY and HETZNER_API_KEY_FILE can be set\n" +
+				`invalid SLASH_ESC_SEQ "/": it cannot be empty or contain '/'`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_slashEscSeqWarning tests slashEscSeqWarning().
+func Test_slashEscSeqWarning(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		assert.Equal(t, tc.expected, slashEscSeqWarning(tc.input))
+	}
+
+	testCases := []testCase{
+		{name: "default", input: "--slash--"},
+		{name: "custom", input: "_s_"},
+		{
+			name:     "invalid characters",
+			input:    "~s~",
+			expected: "only letters, digits, '-', '_' and '.' are allowed in label names",
+		},
+		{
+			name:     "single character",
+			input:    "-",
+			expected: "use at least 3 characters, mixing letters or digits with '-', '_' or '.'",
+		},
+		{
+			name:     "separators only",
+			input:    "___",
+			expected: "use at least 3 characters, mixing letters or digits with '-', '_' or '.'",
+		},
+		{
+			name:     "alphanumeric",
+			input:    "slash",
+			expected: "use at least 3 characters, mixing letters or digits with '-', '_' or '.'",
+		},
+		{
+			name:     "short",
+			input:    ".s",
+			expected: "use at least 3 characters, mixing letters or digits with '-', '_' or '.'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
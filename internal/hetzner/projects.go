@@ -0,0 +1,91 @@
+/*
+ * Projects - Hetzner Cloud projects and their API keys.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetzner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultProject is the name of the project whose API key is set with
+// HETZNER_API_KEY or HETZNER_API_KEY_FILE.
+const DefaultProject = "default"
+
+// Project is a Hetzner Cloud project with its API key. Only one of APIKey and
+// APIKeyFile is set.
+type Project struct {
+	// Name of the project, used in logs and metrics
+	Name string
+	// API key of the project
+	APIKey string
+	// File containing the API key of the project
+	APIKeyFile string
+	// Base URL of the Hetzner Cloud API, empty for the default one
+	Endpoint string
+}
+
+// appendProjects parses a list of name=value pairs of a variable and appends
+// the projects. The values are API key files if files is true and API keys
+// otherwise.
+func appendProjects(projects []Project, variable string, pairs []string, files bool) ([]Project, error) {
+	for _, pair := range pairs {
+		name, value, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !found || name == "" || value == "" {
+			return nil, fmt.Errorf("invalid entry in %s: expected name=value", variable)
+		}
+		for _, p := range projects {
+			if p.Name == name {
+				return nil, fmt.Errorf("duplicate project %q in %s", name, variable)
+			}
+		}
+		project := Project{Name: name}
+		if files {
+			project.APIKeyFile = value
+		} else {
+			project.APIKey = value
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+// GetProjects returns the configured projects. The default project comes
+// first, followed by the projects of HETZNER_API_KEYS and
+// HETZNER_API_KEY_FILES in order. The default project is always returned when
+// no other project is configured, even without an API key. All the projects
+// use the API endpoint of HETZNER_API_ENDPOINT.
+func (c Configuration) GetProjects() ([]Project, error) {
+	projects := make([]Project, 0, 1+len(c.APIKeys)+len(c.APIKeyFiles))
+	if c.APIKey != "" || c.APIKeyFile != "" || len(c.APIKeys)+len(c.APIKeyFiles) == 0 {
+		projects = append(projects, Project{Name: DefaultProject, APIKey: c.APIKey, APIKeyFile: c.APIKeyFile})
+	}
+	projects, err := appendProjects(projects, "HETZNER_API_KEYS", c.APIKeys, false)
+	if err != nil {
+		return nil, err
+	}
+	projects, err = appendProjects(projects, "HETZNER_API_KEY_FILES", c.APIKeyFiles, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := range projects {
+		projects[i].Endpoint = c.APIEndpoint
+	}
+	return projects, nil
+}
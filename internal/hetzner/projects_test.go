@@ -0,0 +1,167 @@
+/*
+ * Projects - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package hetzner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Configuration_GetProjects(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    Configuration
+		expected struct {
+			projects []Project
+			err      bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		projects, err := tc.input.GetProjects()
+		assert.Equal(t, exp.err, err != nil)
+		assert.Equal(t, exp.projects, projects)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "single api key",
+			input: Configuration{APIKey: "key"},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				projects: []Project{{Name: DefaultProject, APIKey: "key"}},
+			},
+		},
+		{
+			name:  "nothing configured",
+			input: Configuration{},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				projects: []Project{{Name: DefaultProject}},
+			},
+		},
+		{
+			name: "named projects only",
+			input: Configuration{
+				APIKeys:     []string{"prod=key1", " staging = key2 "},
+				APIKeyFiles: []string{"dev=/secrets/dev"},
+			},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				projects: []Project{
+					{Name: "prod", APIKey: "key1"},
+					{Name: "staging", APIKey: "key2"},
+					{Name: "dev", APIKeyFile: "/secrets/dev"},
+				},
+			},
+		},
+		{
+			name: "default and named projects",
+			input: Configuration{
+				APIKeyFile: "/secrets/default",
+				APIKeys:    []string{"prod=key1"},
+			},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				projects: []Project{
+					{Name: DefaultProject, APIKeyFile: "/secrets/default"},
+					{Name: "prod", APIKey: "key1"},
+				},
+			},
+		},
+		{
+			name: "custom endpoint",
+			input: Configuration{
+				APIKey:      "key",
+				APIKeys:     []string{"prod=key1"},
+				APIEndpoint: "http://127.0.0.1:8080/v1",
+			},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				projects: []Project{
+					{Name: DefaultProject, APIKey: "key", Endpoint: "http://127.0.0.1:8080/v1"},
+					{Name: "prod", APIKey: "key1", Endpoint: "http://127.0.0.1:8080/v1"},
+				},
+			},
+		},
+		{
+			name:  "missing value",
+			input: Configuration{APIKeys: []string{"prod="}},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				err: true,
+			},
+		},
+		{
+			name:  "missing name",
+			input: Configuration{APIKeyFiles: []string{"/secrets/prod"}},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				err: true,
+			},
+		},
+		{
+			name: "duplicate project",
+			input: Configuration{
+				APIKeys:     []string{"prod=key1"},
+				APIKeyFiles: []string{"prod=/secrets/prod"},
+			},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				err: true,
+			},
+		},
+		{
+			name: "duplicate default project",
+			input: Configuration{
+				APIKey:  "key",
+				APIKeys: []string{"default=key1"},
+			},
+			expected: struct {
+				projects []Project
+				err      bool
+			}{
+				err: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * Logging - subsystem loggers with configurable format and levels.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Provider subsystem: zones, records and endpoints
+	Provider = "provider"
+	// Changes subsystem: planning and applying the changes
+	Changes = "changes"
+	// Zonefile subsystem: backups and restores
+	Zonefile = "zonefile"
+	// API client subsystem: calls to the Hetzner API
+	APIClient = "api"
+
+	// Field containing the subsystem name
+	subsystemField = "subsystem"
+	// Name of the default level in Levels
+	defaultName = "default"
+)
+
+// state contains the loggers and the configured levels.
+type state struct {
+	m          sync.Mutex
+	loggers    map[string]*logrus.Logger
+	level      logrus.Level
+	subsystems map[string]logrus.Level
+	debug      bool
+}
+
+// current is the logging state of the application.
+var current = newState()
+
+// newState creates the initial state, using the level of the standard logger.
+func newState() *state {
+	return &state{
+		loggers:    make(map[string]*logrus.Logger),
+		level:      logrus.GetLevel(),
+		subsystems: make(map[string]logrus.Level),
+	}
+}
+
+// levelOf returns the effective level of a subsystem. The standard logger is
+// identified by an empty name.
+func (s *state) levelOf(name string) logrus.Level {
+	if s.debug {
+		return logrus.DebugLevel
+	}
+	if level, found := s.subsystems[name]; found && name != "" {
+		return level
+	}
+	return s.level
+}
+
+// apply applies the levels and the formatter of the standard logger to all the
+// loggers.
+func (s *state) apply() {
+	std := logrus.StandardLogger()
+	std.SetLevel(s.levelOf(""))
+	for name, logger := range s.loggers {
+		logger.SetFormatter(std.Formatter)
+		logger.SetOutput(std.Out)
+		logger.SetLevel(s.levelOf(name))
+	}
+}
+
+// logger returns the logger of a subsystem, creating it if required.
+func (s *state) logger(name string) *logrus.Entry {
+	s.m.Lock()
+	defer s.m.Unlock()
+	logger, found := s.loggers[name]
+	if !found {
+		logger = logrus.New()
+		s.loggers[name] = logger
+		s.apply()
+	}
+	return logger.WithField(subsystemField, name)
+}
+
+// configure sets the formatter and the levels.
+func (s *state) configure(options Options) error {
+	formatter, err := options.GetFormatter()
+	if err != nil {
+		return err
+	}
+	level, err := options.GetLevel()
+	if err != nil {
+		return err
+	}
+	subsystems, err := options.GetSubsystemLevels()
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	logrus.SetFormatter(formatter)
+	s.level = level
+	s.subsystems = subsystems
+	s.apply()
+	return nil
+}
+
+// toggleDebug switches all the loggers to the debug level, or back to the
+// configured levels.
+func (s *state) toggleDebug() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.debug = !s.debug
+	s.apply()
+	return s.debug
+}
+
+// levels returns the effective levels of the default logger and of the
+// subsystems.
+func (s *state) levels() map[string]string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	levels := map[string]string{defaultName: s.levelOf("").String()}
+	for _, name := range []string{Provider, Changes, Zonefile, APIClient} {
+		levels[name] = s.levelOf(name).String()
+	}
+	return levels
+}
+
+// Logger returns the logger of a subsystem. Its entries contain the subsystem
+// name in the "subsystem" field.
+func Logger(subsystem string) *logrus.Entry {
+	return current.logger(subsystem)
+}
+
+// Configure sets the log format, the default level and the levels of the
+// subsystems.
+func Configure(options Options) error {
+	return current.configure(options)
+}
+
+// ToggleDebug switches all the loggers to the debug level, or back to the
+// configured levels if they were already switched. It returns true if the
+// debug level is now forced.
+func ToggleDebug() bool {
+	return current.toggleDebug()
+}
+
+// Levels returns the effective levels of the default logger and of the
+// subsystems.
+func Levels() map[string]string {
+	return current.levels()
+}
@@ -0,0 +1,117 @@
+/*
+ * Logging - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// useTestState replaces the current state and the standard logger settings
+// until the end of the test. The logs are written to the returned buffer.
+func useTestState(t *testing.T) *bytes.Buffer {
+	std := logrus.StandardLogger()
+	bkpState, bkpOut, bkpFormatter, bkpLevel := current, std.Out, std.Formatter, std.Level
+	t.Cleanup(func() {
+		current = bkpState
+		logrus.SetOutput(bkpOut)
+		logrus.SetFormatter(bkpFormatter)
+		logrus.SetLevel(bkpLevel)
+	})
+	buf := &bytes.Buffer{}
+	logrus.SetOutput(buf)
+	logrus.SetLevel(logrus.InfoLevel)
+	current = newState()
+	return buf
+}
+
+func Test_Logger(t *testing.T) {
+	buf := useTestState(t)
+	logger := Logger(Changes)
+	assert.Same(t, logger.Logger, Logger(Changes).Logger)
+	assert.NotSame(t, logger.Logger, Logger(Provider).Logger)
+
+	logger.Debug("hidden")
+	logger.Info("shown")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "subsystem=changes")
+	assert.Contains(t, buf.String(), "shown")
+}
+
+func Test_Configure(t *testing.T) {
+	buf := useTestState(t)
+	providerLog := Logger(Provider)
+	changesLog := Logger(Changes)
+
+	err := Configure(Options{Format: FormatJSON, Level: "warn", ChangesLevel: "debug"})
+	assert.Nil(t, err)
+	assert.Equal(t, logrus.WarnLevel, logrus.GetLevel())
+	assert.Equal(t, logrus.WarnLevel, providerLog.Logger.GetLevel())
+	assert.Equal(t, logrus.DebugLevel, changesLog.Logger.GetLevel())
+	// Loggers created after the configuration use it too.
+	assert.Equal(t, logrus.WarnLevel, Logger(APIClient).Logger.GetLevel())
+
+	changesLog.Debug("test message")
+	entry := map[string]string{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "test message", entry["msg"])
+	assert.Equal(t, Changes, entry[subsystemField])
+
+	// An invalid configuration is not applied.
+	err = Configure(Options{Format: "xml"})
+	assert.NotNil(t, err)
+	err = Configure(Options{Level: "verbose"})
+	assert.NotNil(t, err)
+	err = Configure(Options{ZonefileLevel: "verbose"})
+	assert.NotNil(t, err)
+	assert.Equal(t, logrus.WarnLevel, logrus.GetLevel())
+}
+
+func Test_ToggleDebug(t *testing.T) {
+	useTestState(t)
+	providerLog := Logger(Provider)
+	err := Configure(Options{Level: "error", ProviderLevel: "warn"})
+	assert.Nil(t, err)
+
+	assert.True(t, ToggleDebug())
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+	assert.Equal(t, logrus.DebugLevel, providerLog.Logger.GetLevel())
+	assert.Equal(t, "debug", Levels()[Zonefile])
+
+	assert.False(t, ToggleDebug())
+	assert.Equal(t, logrus.ErrorLevel, logrus.GetLevel())
+	assert.Equal(t, logrus.WarnLevel, providerLog.Logger.GetLevel())
+}
+
+func Test_Levels(t *testing.T) {
+	useTestState(t)
+	err := Configure(Options{ZonefileLevel: "debug"})
+	assert.Nil(t, err)
+	expected := map[string]string{
+		defaultName: "info",
+		Provider:    "info",
+		Changes:     "info",
+		Zonefile:    "debug",
+		APIClient:   "info",
+	}
+	assert.Equal(t, expected, Levels())
+}
@@ -0,0 +1,122 @@
+/*
+ * Options - logging configuration.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging
+
+import (
+	"fmt"
+
+	"github.com/codingconcepts/env"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Human readable text, colored when writing to a terminal
+	FormatText = "text"
+	// One JSON object per line
+	FormatJSON = "json"
+	// Key=value pairs, never colored
+	FormatLogfmt = "logfmt"
+)
+
+// Options contains the logging configuration passed as environment variables.
+type Options struct {
+	// Log format: text, json or logfmt
+	Format string `env:"LOG_FORMAT" default:"text"`
+	// Default log level. If empty, it is debug when HETZNER_DEBUG is set and
+	// info otherwise.
+	Level string `env:"LOG_LEVEL" default:""`
+	// Enable debugging logs (legacy setting)
+	Debug bool `env:"HETZNER_DEBUG" default:"false"`
+	// Log level of the provider, empty to use the default level
+	ProviderLevel string `env:"LOG_LEVEL_PROVIDER" default:""`
+	// Log level of the changes, empty to use the default level
+	ChangesLevel string `env:"LOG_LEVEL_CHANGES" default:""`
+	// Log level of the zonefile handling, empty to use the default level
+	ZonefileLevel string `env:"LOG_LEVEL_ZONEFILE" default:""`
+	// Log level of the API client, empty to use the default level
+	APILevel string `env:"LOG_LEVEL_API" default:""`
+}
+
+// NewOptions reads the logging options from the environment.
+func NewOptions() (*Options, error) {
+	options := &Options{}
+	if err := env.Set(options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// Validate checks the format and the levels.
+func (o Options) Validate() error {
+	if _, err := o.GetFormatter(); err != nil {
+		return err
+	}
+	if _, err := o.GetLevel(); err != nil {
+		return err
+	}
+	_, err := o.GetSubsystemLevels()
+	return err
+}
+
+// GetFormatter returns the formatter for the configured format.
+func (o Options) GetFormatter() (logrus.Formatter, error) {
+	switch o.Format {
+	case FormatText, "":
+		return &logrus.TextFormatter{}, nil
+	case FormatJSON:
+		return &logrus.JSONFormatter{}, nil
+	case FormatLogfmt:
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %s", o.Format)
+	}
+}
+
+// GetLevel returns the default log level.
+func (o Options) GetLevel() (logrus.Level, error) {
+	if o.Level != "" {
+		return logrus.ParseLevel(o.Level)
+	}
+	if o.Debug {
+		return logrus.DebugLevel, nil
+	}
+	return logrus.InfoLevel, nil
+}
+
+// GetSubsystemLevels returns the levels configured for the subsystems. The
+// subsystems without a level use the default one and are not included.
+func (o Options) GetSubsystemLevels() (map[string]logrus.Level, error) {
+	configured := map[string]string{
+		Provider:  o.ProviderLevel,
+		Changes:   o.ChangesLevel,
+		Zonefile:  o.ZonefileLevel,
+		APIClient: o.APILevel,
+	}
+	levels := make(map[string]logrus.Level)
+	for name, value := range configured {
+		if value == "" {
+			continue
+		}
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level for %s: %w", name, err)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
@@ -0,0 +1,245 @@
+/*
+ * Options - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewOptions(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_LEVEL_CHANGES", "debug")
+	options, err := NewOptions()
+	assert.Nil(t, err)
+	assert.Equal(t, &Options{Format: FormatJSON, ChangesLevel: "debug"}, options)
+}
+
+func Test_Options_Validate(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    Options
+		expected bool
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		err := tc.input.Validate()
+		assert.Equal(t, tc.expected, err == nil)
+	}
+
+	testCases := []testCase{
+		{name: "valid", input: Options{Format: "json", Level: "warn", APILevel: "debug"}, expected: true},
+		{name: "invalid format", input: Options{Format: "xml"}, expected: false},
+		{name: "invalid level", input: Options{Format: "text", Level: "loud"}, expected: false},
+		{name: "invalid subsystem level", input: Options{Format: "text", ChangesLevel: "loud"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_Options_GetFormatter(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected struct {
+			formatter logrus.Formatter
+			err       bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		formatter, err := Options{Format: tc.input}.GetFormatter()
+		assert.Equal(t, exp.err, err != nil)
+		assert.Equal(t, exp.formatter, formatter)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "text",
+			input: FormatText,
+			expected: struct {
+				formatter logrus.Formatter
+				err       bool
+			}{formatter: &logrus.TextFormatter{}},
+		},
+		{
+			name: "empty",
+			expected: struct {
+				formatter logrus.Formatter
+				err       bool
+			}{formatter: &logrus.TextFormatter{}},
+		},
+		{
+			name:  "json",
+			input: FormatJSON,
+			expected: struct {
+				formatter logrus.Formatter
+				err       bool
+			}{formatter: &logrus.JSONFormatter{}},
+		},
+		{
+			name:  "logfmt",
+			input: FormatLogfmt,
+			expected: struct {
+				formatter logrus.Formatter
+				err       bool
+			}{formatter: &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}},
+		},
+		{
+			name:  "unsupported",
+			input: "xml",
+			expected: struct {
+				formatter logrus.Formatter
+				err       bool
+			}{err: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_Options_GetLevel(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    Options
+		expected struct {
+			level logrus.Level
+			err   bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		level, err := tc.input.GetLevel()
+		assert.Equal(t, exp.err, err != nil)
+		assert.Equal(t, exp.level, level)
+	}
+
+	testCases := []testCase{
+		{
+			name: "default",
+			expected: struct {
+				level logrus.Level
+				err   bool
+			}{level: logrus.InfoLevel},
+		},
+		{
+			name:  "legacy debug",
+			input: Options{Debug: true},
+			expected: struct {
+				level logrus.Level
+				err   bool
+			}{level: logrus.DebugLevel},
+		},
+		{
+			name:  "level has precedence",
+			input: Options{Level: "warn", Debug: true},
+			expected: struct {
+				level logrus.Level
+				err   bool
+			}{level: logrus.WarnLevel},
+		},
+		{
+			name:  "invalid level",
+			input: Options{Level: "verbose"},
+			expected: struct {
+				level logrus.Level
+				err   bool
+			}{level: logrus.PanicLevel, err: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_Options_GetSubsystemLevels(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    Options
+		expected struct {
+			levels map[string]logrus.Level
+			err    bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		levels, err := tc.input.GetSubsystemLevels()
+		assert.Equal(t, exp.err, err != nil)
+		assert.Equal(t, exp.levels, levels)
+	}
+
+	testCases := []testCase{
+		{
+			name: "no levels",
+			expected: struct {
+				levels map[string]logrus.Level
+				err    bool
+			}{levels: map[string]logrus.Level{}},
+		},
+		{
+			name: "all levels",
+			input: Options{
+				ProviderLevel: "debug",
+				ChangesLevel:  "info",
+				ZonefileLevel: "warn",
+				APILevel:      "trace",
+			},
+			expected: struct {
+				levels map[string]logrus.Level
+				err    bool
+			}{
+				levels: map[string]logrus.Level{
+					Provider:  logrus.DebugLevel,
+					Changes:   logrus.InfoLevel,
+					Zonefile:  logrus.WarnLevel,
+					APIClient: logrus.TraceLevel,
+				},
+			},
+		},
+		{
+			name:  "invalid level",
+			input: Options{APILevel: "verbose"},
+			expected: struct {
+				levels map[string]logrus.Level
+				err    bool
+			}{err: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
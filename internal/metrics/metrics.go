@@ -19,15 +19,16 @@ package metrics
 
 import (
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	log "github.com/sirupsen/logrus"
 )
 
-// metrics instance
-var metrics *OpenMetrics
-
-// OpenMetrics is the instance that holds all the metrics infromation.
+// OpenMetrics is the instance that holds all the metrics infromation. All the
+// methods can be called on a nil instance, which disables the metrics.
 type OpenMetrics struct {
 	registry *prometheus.Registry
 
@@ -38,118 +39,511 @@ type OpenMetrics struct {
 	skippedRecords   *prometheus.GaugeVec
 	apiDelayHist     *prometheus.HistogramVec
 
-	rateLimitLimit        prometheus.Gauge
-	rateLimitRemaining    prometheus.Gauge
-	rateLimitResetSeconds prometheus.Gauge
-}
-
-// GetOpenMetricsInstance returns the current OpenMetrics instance or creates a
-// new one if required.
-func GetOpenMetricsInstance() *OpenMetrics {
-	if metrics == nil {
-		reg := prometheus.NewRegistry()
-		metrics = &OpenMetrics{
-			registry: reg,
-			successfulApiCallsTotal: prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: "successful_api_calls_total",
-					Help: "The number of successful Hetzner API calls",
-				},
-				[]string{"action"},
-			),
-			failedApiCallsTotal: prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: "failed_api_calls_total",
-					Help: "The number of Hetzner API calls that returned an error",
-				},
-				[]string{"action"},
-			),
-			filteredOutZones: prometheus.NewGauge(prometheus.GaugeOpts{
-				Name: "filtered_out_zones",
-				Help: "The number of zones excluded by the domain filter",
-			}),
-			skippedRecords: prometheus.NewGaugeVec(
-				prometheus.GaugeOpts{
-					Name: "skipped_records",
-					Help: "The number of skipped records per domain",
-				},
-				[]string{"zone"},
-			),
-			apiDelayHist: prometheus.NewHistogramVec(
-				prometheus.HistogramOpts{
-					Name:    "api_delay_hist",
-					Help:    "Histogram of the delay in milliseconds when calling the Hetzner API",
-					Buckets: []float64{10, 100, 250, 500, 1000, 1500, 2000},
-				},
-				[]string{"action"},
-			),
-			rateLimitLimit: prometheus.NewGauge(prometheus.GaugeOpts{
+	rateLimitLimit             *prometheus.GaugeVec
+	rateLimitRemaining         *prometheus.GaugeVec
+	rateLimitResetSeconds      *prometheus.GaugeVec
+	rateLimitConsumedTotal     *prometheus.CounterVec
+	rateLimitExhaustionSeconds *prometheus.GaugeVec
+	rateLimitExhaustionWarning *prometheus.GaugeVec
+	rateLimitStatus            *atomic.Pointer[RateLimitStatus]
+	rateLimitPredictor         *rateLimitPredictor
+
+	bulkConflictsTotal *prometheus.CounterVec
+
+	healthCheckLastSuccessSeconds  prometheus.Gauge
+	healthCheckLastErrorSeconds    prometheus.Gauge
+	healthCheckConsecutiveFailures prometheus.Gauge
+	healthCheckFailuresTotal       prometheus.Counter
+
+	authRejectedTotal *prometheus.CounterVec
+
+	recordChangesTotal        *prometheus.CounterVec
+	managedRRSets             *prometheus.GaugeVec
+	applyChangesDuration      prometheus.Histogram
+	recordsDuration           prometheus.Histogram
+	lastSuccessfulSyncSeconds prometheus.Gauge
+
+	configReloadsTotal      *prometheus.CounterVec
+	configLastReloadSeconds prometheus.Gauge
+
+	driftedRRSets         *prometheus.GaugeVec
+	driftChecksTotal      *prometheus.CounterVec
+	driftLastCheckSeconds prometheus.Gauge
+	driftCorrectionsTotal *prometheus.CounterVec
+}
+
+// NewOpenMetrics creates a new OpenMetrics instance with its own registry. The
+// registry also contains the Go runtime and process collectors. All the
+// metrics carry the constant labels, while only the metrics of the webhook are
+// prefixed with the namespace.
+func NewOpenMetrics(options Options) (*OpenMetrics, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	reg := prometheus.NewRegistry()
+	m := &OpenMetrics{
+		registry: reg,
+		successfulApiCallsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "successful_api_calls_total",
+				Help: "The number of successful Hetzner API calls",
+			},
+			[]string{"project", "action"},
+		),
+		failedApiCallsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "failed_api_calls_total",
+				Help: "The number of Hetzner API calls that returned an error",
+			},
+			[]string{"project", "action"},
+		),
+		filteredOutZones: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "filtered_out_zones",
+			Help: "The number of zones excluded by the domain filter",
+		}),
+		skippedRecords: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "skipped_records",
+				Help: "The number of skipped records per domain",
+			},
+			[]string{"zone"},
+		),
+		apiDelayHist: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "api_delay_hist",
+				Help:    "Histogram of the delay in milliseconds when calling the Hetzner API",
+				Buckets: options.APIDelayBuckets,
+			},
+			[]string{"project", "action"},
+		),
+		rateLimitLimit: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
 				Name: "ratelimit_limit",
 				Help: "The maximum number of API calls available in one hour",
-			}),
-			rateLimitRemaining: prometheus.NewGauge(prometheus.GaugeOpts{
+			},
+			[]string{"project", "token", "action"},
+		),
+		rateLimitRemaining: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
 				Name: "ratelimit_remaining",
 				Help: "The remaining number of API calls available in the current timeframe",
-			}),
-			rateLimitResetSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			},
+			[]string{"project", "token", "action"},
+		),
+		rateLimitResetSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
 				Name: "ratelimit_reset_seconds",
 				Help: "UNIX timestamp of the next rate limit reset",
-			}),
-		}
-		reg.MustRegister(metrics.successfulApiCallsTotal)
-		reg.MustRegister(metrics.failedApiCallsTotal)
-		reg.MustRegister(metrics.filteredOutZones)
-		reg.MustRegister(metrics.skippedRecords)
-		reg.MustRegister(metrics.apiDelayHist)
-		reg.MustRegister(metrics.rateLimitLimit)
-		reg.MustRegister(metrics.rateLimitRemaining)
-		reg.MustRegister(metrics.rateLimitResetSeconds)
-	}
-	return metrics
-}
-
-// GetRegistry returns the current prometheus registry.
-func (m OpenMetrics) GetRegistry() *prometheus.Registry {
+			},
+			[]string{"project", "token", "action"},
+		),
+		rateLimitConsumedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ratelimit_consumed_total",
+				Help: "The number of API calls consumed from the rate limit",
+			},
+			[]string{"project", "token", "action"},
+		),
+		rateLimitExhaustionSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ratelimit_exhaustion_seconds",
+				Help: "The predicted number of seconds until the rate limit runs out",
+			},
+			[]string{"project", "token"},
+		),
+		rateLimitExhaustionWarning: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ratelimit_exhaustion_warning",
+				Help: "1 if the rate limit is predicted to run out within the warning horizon",
+			},
+			[]string{"project", "token"},
+		),
+		rateLimitStatus:    &atomic.Pointer[RateLimitStatus]{},
+		rateLimitPredictor: newRateLimitPredictor(0),
+		bulkConflictsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "bulk_conflicts_total",
+				Help: "The number of concurrent zone modifications detected in bulk mode",
+			},
+			[]string{"zone"},
+		),
+		healthCheckLastSuccessSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "health_check_last_success_seconds",
+			Help: "UNIX timestamp of the last successful Hetzner API health check",
+		}),
+		healthCheckLastErrorSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "health_check_last_error_seconds",
+			Help: "UNIX timestamp of the last failed Hetzner API health check",
+		}),
+		healthCheckConsecutiveFailures: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "health_check_consecutive_failures",
+			Help: "The number of consecutive failed Hetzner API health checks",
+		}),
+		healthCheckFailuresTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "health_check_failures_total",
+			Help: "The number of failed Hetzner API health checks",
+		}),
+		authRejectedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "webhook_auth_rejected_total",
+				Help: "The number of webhook requests rejected by the authentication",
+			},
+			[]string{"reason"},
+		),
+		recordChangesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "record_changes_total",
+				Help: "The number of applied record changes per zone and operation",
+			},
+			[]string{"zone", "operation"},
+		),
+		managedRRSets: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "managed_rrsets",
+				Help: "The number of managed RRSets per zone and type",
+			},
+			[]string{"zone", "type"},
+		),
+		applyChangesDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "apply_changes_duration_seconds",
+			Help:    "Histogram of the duration in seconds of ApplyChanges",
+			Buckets: options.DurationBuckets,
+		}),
+		recordsDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "records_duration_seconds",
+			Help:    "Histogram of the duration in seconds of Records",
+			Buckets: options.DurationBuckets,
+		}),
+		lastSuccessfulSyncSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "last_successful_sync_seconds",
+			Help: "UNIX timestamp of the last successful synchronization",
+		}),
+		configReloadsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "config_reloads_total",
+				Help: "The number of configuration file reloads per result",
+			},
+			[]string{"result"},
+		),
+		configLastReloadSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "config_last_reload_seconds",
+			Help: "UNIX timestamp of the last successful configuration file reload",
+		}),
+		driftedRRSets: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "drifted_rrsets_since_start",
+				Help: "The number of RRSets applied since the webhook started that were changed outside the webhook, per zone and kind",
+			},
+			[]string{"zone", "kind"},
+		),
+		driftChecksTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "drift_checks_total",
+				Help: "The number of drift checks per result",
+			},
+			[]string{"result"},
+		),
+		driftLastCheckSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "drift_last_check_seconds",
+			Help: "UNIX timestamp of the last successful drift check",
+		}),
+		driftCorrectionsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "drift_corrections_total",
+				Help: "The number of drifted RRSets added to the changes for correction, per zone",
+			},
+			[]string{"zone"},
+		),
+	}
+	labelled := prometheus.WrapRegistererWith(options.constLabels(), reg)
+	labelled.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	webhook := labelled
+	if options.Namespace != "" {
+		webhook = prometheus.WrapRegistererWithPrefix(options.Namespace+"_", labelled)
+	}
+	webhook.MustRegister(
+		m.successfulApiCallsTotal,
+		m.failedApiCallsTotal,
+		m.filteredOutZones,
+		m.skippedRecords,
+		m.apiDelayHist,
+		m.rateLimitLimit,
+		m.rateLimitRemaining,
+		m.rateLimitResetSeconds,
+		m.rateLimitConsumedTotal,
+		m.rateLimitExhaustionSeconds,
+		m.rateLimitExhaustionWarning,
+		m.bulkConflictsTotal,
+		m.healthCheckLastSuccessSeconds,
+		m.healthCheckLastErrorSeconds,
+		m.healthCheckConsecutiveFailures,
+		m.healthCheckFailuresTotal,
+		m.authRejectedTotal,
+		m.recordChangesTotal,
+		m.managedRRSets,
+		m.applyChangesDuration,
+		m.recordsDuration,
+		m.lastSuccessfulSyncSeconds,
+		m.configReloadsTotal,
+		m.configLastReloadSeconds,
+		m.driftedRRSets,
+		m.driftChecksTotal,
+		m.driftLastCheckSeconds,
+		m.driftCorrectionsTotal,
+	)
+	return m, nil
+}
+
+// GetRegistry returns the prometheus registry, or nil if the metrics are
+// disabled.
+func (m *OpenMetrics) GetRegistry() *prometheus.Registry {
+	if m == nil {
+		return nil
+	}
 	return m.registry
 }
 
 // IncSuccessfulApiCallsTotal increments the successful_api_calls_total counter.
-func (m *OpenMetrics) IncSuccessfulApiCallsTotal(action string) {
-	label := prometheus.Labels{"action": action}
+func (m *OpenMetrics) IncSuccessfulApiCallsTotal(project, action string) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"project": project, "action": action}
 	m.successfulApiCallsTotal.With(label).Inc()
 }
 
 // IncFailedApiCallsTotal increments the failed_api_calls_total counter.
-func (m *OpenMetrics) IncFailedApiCallsTotal(action string) {
-	label := prometheus.Labels{"action": action}
+func (m *OpenMetrics) IncFailedApiCallsTotal(project, action string) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"project": project, "action": action}
 	m.failedApiCallsTotal.With(label).Inc()
 }
 
 // SetFilteredOutZones sets the value for the filtered_out_zones gauge.
 func (m *OpenMetrics) SetFilteredOutZones(num int) {
+	if m == nil {
+		return
+	}
 	m.filteredOutZones.Set(float64(num))
 }
 
 // SetSkippedRecords sets the value for the skipped_records gauge.
 func (m *OpenMetrics) SetSkippedRecords(zone string, num int) {
+	if m == nil {
+		return
+	}
 	label := prometheus.Labels{"zone": zone}
 	m.skippedRecords.With(label).Set(float64(num))
 }
 
 // AddApiDelayHist adds a value to the api_delay_hist histogram.
-func (m *OpenMetrics) AddApiDelayHist(action string, delay int64) {
-	label := prometheus.Labels{"action": action}
+func (m *OpenMetrics) AddApiDelayHist(project, action string, delay int64) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"project": project, "action": action}
 	m.apiDelayHist.With(label).Observe(float64(delay))
 }
 
-// SetRateLimitStats sets the rate limits stats.
-func (m *OpenMetrics) SetRateLimitStats(action string, h http.Header) {
+// SetRateLimitStats sets the rate limits stats of the token of a project after
+// an API call and updates the exhaustion prediction.
+func (m *OpenMetrics) SetRateLimitStats(project, token, action string, h http.Header) {
+	if m == nil {
+		return
+	}
 	rl, err := parseRateLimit(h)
 	if err != nil {
 		log.Debugf("Action %s provoked rate limit error: %v", action, err)
 		return
 	}
-	m.rateLimitLimit.Set(float64(rl.limit))
-	m.rateLimitRemaining.Set(float64(rl.remaining))
-	m.rateLimitResetSeconds.Set(float64(rl.reset))
+	label := prometheus.Labels{"project": project, "token": token, "action": action}
+	m.rateLimitLimit.With(label).Set(float64(rl.limit))
+	m.rateLimitRemaining.With(label).Set(float64(rl.remaining))
+	m.rateLimitResetSeconds.With(label).Set(float64(rl.reset))
+	m.rateLimitConsumedTotal.With(label).Inc()
+	now := time.Now()
+	m.rateLimitStatus.Store(&RateLimitStatus{
+		Project:   project,
+		Token:     token,
+		Action:    action,
+		Limit:     rl.limit,
+		Remaining: rl.remaining,
+		Reset:     time.Unix(int64(rl.reset), 0).UTC(),
+		Updated:   now.UTC(),
+	})
+
+	prediction, warning, changed := m.rateLimitPredictor.add(rateLimitKey{project, token}, now, rl.remaining)
+	tokenLabel := prometheus.Labels{"project": project, "token": token}
+	m.rateLimitExhaustionSeconds.With(tokenLabel).Set(prediction)
+	if warning {
+		m.rateLimitExhaustionWarning.With(tokenLabel).Set(1)
+	} else {
+		m.rateLimitExhaustionWarning.With(tokenLabel).Set(0)
+	}
+	if changed && warning {
+		log.Warnf("The rate limit of token %s of project %s is predicted to run out in %.0f seconds (%d of %d calls remaining).",
+			token, project, prediction, rl.remaining, rl.limit)
+	} else if changed {
+		log.Infof("The rate limit of token %s of project %s is no longer predicted to run out soon.", token, project)
+	}
+}
+
+// SetRateLimitWarningHorizon sets the horizon below which the predicted
+// exhaustion of the rate limit raises a warning. 0 disables the warnings.
+func (m *OpenMetrics) SetRateLimitWarningHorizon(horizon time.Duration) {
+	if m == nil {
+		return
+	}
+	m.rateLimitPredictor.setHorizon(horizon)
+}
+
+// GetRateLimitStatus returns the last rate limit information received from
+// the API, or nil if none was received yet.
+func (m *OpenMetrics) GetRateLimitStatus() *RateLimitStatus {
+	if m == nil {
+		return nil
+	}
+	return m.rateLimitStatus.Load()
+}
+
+// IncBulkConflictsTotal increments the bulk_conflicts_total counter.
+func (m *OpenMetrics) IncBulkConflictsTotal(zone string) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"zone": zone}
+	m.bulkConflictsTotal.With(label).Inc()
+}
+
+// SetHealthCheckSuccess records a successful health check.
+func (m *OpenMetrics) SetHealthCheckSuccess(t time.Time) {
+	if m == nil {
+		return
+	}
+	m.healthCheckLastSuccessSeconds.Set(float64(t.Unix()))
+	m.healthCheckConsecutiveFailures.Set(0)
+}
+
+// SetHealthCheckFailure records a failed health check and the current number
+// of consecutive failures.
+func (m *OpenMetrics) SetHealthCheckFailure(t time.Time, failures int) {
+	if m == nil {
+		return
+	}
+	m.healthCheckLastErrorSeconds.Set(float64(t.Unix()))
+	m.healthCheckConsecutiveFailures.Set(float64(failures))
+	m.healthCheckFailuresTotal.Inc()
+}
+
+// IncAuthRejectedTotal increments the webhook_auth_rejected_total counter.
+func (m *OpenMetrics) IncAuthRejectedTotal(reason string) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"reason": reason}
+	m.authRejectedTotal.With(label).Inc()
+}
+
+// AddRecordChangesTotal adds the applied changes of an operation (create,
+// update or delete) to the record_changes_total counter.
+func (m *OpenMetrics) AddRecordChangesTotal(zone, operation string, num int) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"zone": zone, "operation": operation}
+	m.recordChangesTotal.With(label).Add(float64(num))
+}
+
+// SetManagedRRSets sets the managed_rrsets gauge of a zone from the number of
+// RRSets per type. The types that are no longer present are removed.
+func (m *OpenMetrics) SetManagedRRSets(zone string, counts map[string]int) {
+	if m == nil {
+		return
+	}
+	m.managedRRSets.DeletePartialMatch(prometheus.Labels{"zone": zone})
+	for rrType, num := range counts {
+		label := prometheus.Labels{"zone": zone, "type": rrType}
+		m.managedRRSets.With(label).Set(float64(num))
+	}
+}
+
+// ObserveApplyChangesDuration adds a value to the
+// apply_changes_duration_seconds histogram.
+func (m *OpenMetrics) ObserveApplyChangesDuration(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.applyChangesDuration.Observe(d.Seconds())
+}
+
+// ObserveRecordsDuration adds a value to the records_duration_seconds
+// histogram.
+func (m *OpenMetrics) ObserveRecordsDuration(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.recordsDuration.Observe(d.Seconds())
+}
+
+// SetLastSuccessfulSync sets the last_successful_sync_seconds gauge.
+func (m *OpenMetrics) SetLastSuccessfulSync(t time.Time) {
+	if m == nil {
+		return
+	}
+	m.lastSuccessfulSyncSeconds.Set(float64(t.Unix()))
+}
+
+// IncConfigReloadsTotal increments the config_reloads_total counter. The
+// result is either "success" or "failure". A successful reload also sets the
+// config_last_reload_seconds gauge.
+func (m *OpenMetrics) IncConfigReloadsTotal(result string, t time.Time) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"result": result}
+	m.configReloadsTotal.With(label).Inc()
+	if result == "success" {
+		m.configLastReloadSeconds.Set(float64(t.Unix()))
+	}
+}
+
+// SetDriftedRRSets sets the drifted_rrsets_since_start gauge of a zone from
+// the number of drifted RRSets per kind. A nil map removes the zone.
+func (m *OpenMetrics) SetDriftedRRSets(zone string, counts map[string]int) {
+	if m == nil {
+		return
+	}
+	m.driftedRRSets.DeletePartialMatch(prometheus.Labels{"zone": zone})
+	for kind, num := range counts {
+		label := prometheus.Labels{"zone": zone, "kind": kind}
+		m.driftedRRSets.With(label).Set(float64(num))
+	}
+}
+
+// IncDriftChecksTotal increments the drift_checks_total counter. The result
+// is either "success" or "failure". A successful check also sets the
+// drift_last_check_seconds gauge.
+func (m *OpenMetrics) IncDriftChecksTotal(result string, t time.Time) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"result": result}
+	m.driftChecksTotal.With(label).Inc()
+	if result == "success" {
+		m.driftLastCheckSeconds.Set(float64(t.Unix()))
+	}
+}
+
+// IncDriftCorrectionsTotal increments the drift_corrections_total counter.
+func (m *OpenMetrics) IncDriftCorrectionsTotal(zone string) {
+	if m == nil {
+		return
+	}
+	label := prometheus.Labels{"zone": zone}
+	m.driftCorrectionsTotal.With(label).Inc()
 }
@@ -18,41 +18,116 @@
 package metrics
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
-	testAction = "test_action"
-	testZone   = "alpha.com"
+	testProject = "default"
+	testToken   = "0123abcd"
+	testAction  = "test_action"
+	testZone    = "alpha.com"
 )
 
-func Test_GetOpenMetricsInstance(t *testing.T) {
+// newTestMetrics returns a new instance with the default options.
+func newTestMetrics(t *testing.T) *OpenMetrics {
+	m, err := NewOpenMetrics(DefaultOptions())
+	assert.NoError(t, err)
+	return m
+}
+
+func Test_NewOpenMetrics(t *testing.T) {
 	type testCase struct {
-		name    string
-		metrics *OpenMetrics
+		name     string
+		input    Options
+		expected struct {
+			names  []string
+			labels map[string]string
+			err    error
+		}
 	}
 
 	run := func(t *testing.T, tc testCase) {
-		actual := GetOpenMetricsInstance()
-		if tc.metrics != nil {
-			assert.EqualValues(t, metrics, actual)
-		} else {
-			assert.NotNil(t, metrics)
+		exp := tc.expected
+		m, err := NewOpenMetrics(tc.input)
+		if exp.err != nil {
+			assert.Equal(t, exp.err, err)
+			assert.Nil(t, m)
+			return
+		}
+		assert.NoError(t, err)
+		m.IncSuccessfulApiCallsTotal(testProject, testAction)
+		families, err := m.GetRegistry().Gather()
+		assert.NoError(t, err)
+		names := map[string]bool{}
+		for _, mf := range families {
+			names[mf.GetName()] = true
+			for _, metric := range mf.GetMetric() {
+				labels := map[string]string{}
+				for _, lp := range metric.GetLabel() {
+					labels[lp.GetName()] = lp.GetValue()
+				}
+				for k, v := range exp.labels {
+					assert.Equal(t, v, labels[k], mf.GetName())
+				}
+			}
+		}
+		for _, name := range exp.names {
+			assert.True(t, names[name], name)
 		}
 	}
 
 	testCases := []testCase{
 		{
-			name:    "new instance required",
-			metrics: nil,
+			name:  "default options",
+			input: DefaultOptions(),
+			expected: struct {
+				names  []string
+				labels map[string]string
+				err    error
+			}{
+				names: []string{"successful_api_calls_total", "go_goroutines"},
+			},
 		},
 		{
-			name:    "existing instance",
-			metrics: &OpenMetrics{},
+			name: "namespace and constant labels",
+			input: Options{
+				Namespace:       "hetzner_webhook",
+				Cluster:         "prod",
+				Instance:        "webhook-0",
+				APIDelayBuckets: []float64{100, 1000},
+				DurationBuckets: []float64{1, 10},
+			},
+			expected: struct {
+				names  []string
+				labels map[string]string
+				err    error
+			}{
+				names:  []string{"hetzner_webhook_successful_api_calls_total", "go_goroutines"},
+				labels: map[string]string{"cluster": "prod", "instance": "webhook-0"},
+			},
+		},
+		{
+			name: "invalid options",
+			input: Options{
+				Namespace:       "hetzner-webhook",
+				APIDelayBuckets: []float64{100, 1000},
+				DurationBuckets: []float64{1, 10},
+			},
+			expected: struct {
+				names  []string
+				labels map[string]string
+				err    error
+			}{
+				err: errors.New("invalid metrics namespace hetzner-webhook"),
+			},
 		},
 	}
 
@@ -63,50 +138,61 @@ func Test_GetOpenMetricsInstance(t *testing.T) {
 	}
 }
 
+func Test_OpenMetrics_nil(t *testing.T) {
+	var m *OpenMetrics
+	assert.NotPanics(t, func() {
+		m.IncSuccessfulApiCallsTotal(testProject, testAction)
+		m.SetRateLimitStats(testProject, testToken, testAction, http.Header{})
+		m.SetManagedRRSets(testZone, map[string]int{"A": 1})
+	})
+	assert.Nil(t, m.GetRegistry())
+	assert.Nil(t, m.GetRateLimitStatus())
+}
+
 func Test_OpenMetrics_IncSuccessfulApiCallsTotal(t *testing.T) {
-	metrics = nil
+	m := newTestMetrics(t)
 	expected := float64(1)
 
-	GetOpenMetricsInstance().IncSuccessfulApiCallsTotal(testAction)
-	actual := testutil.ToFloat64(metrics.successfulApiCallsTotal)
+	m.IncSuccessfulApiCallsTotal(testProject, testAction)
+	actual := testutil.ToFloat64(m.successfulApiCallsTotal)
 
 	assert.Equal(t, expected, actual)
 }
 
 func Test_OpenMetrics_IncFailedApiCallsTotal(t *testing.T) {
-	metrics = nil
+	m := newTestMetrics(t)
 	expected := float64(1)
 
-	GetOpenMetricsInstance().IncFailedApiCallsTotal(testAction)
-	actual := testutil.ToFloat64(metrics.failedApiCallsTotal)
+	m.IncFailedApiCallsTotal(testProject, testAction)
+	actual := testutil.ToFloat64(m.failedApiCallsTotal)
 
 	assert.Equal(t, expected, actual)
 }
 
 func Test_OpenMetrics_SetFilteredOutZones(t *testing.T) {
-	metrics = nil
+	m := newTestMetrics(t)
 	const val = 5
 	expected := float64(val)
 
-	GetOpenMetricsInstance().SetFilteredOutZones(val)
-	actual := testutil.ToFloat64(metrics.filteredOutZones)
+	m.SetFilteredOutZones(val)
+	actual := testutil.ToFloat64(m.filteredOutZones)
 
 	assert.Equal(t, expected, actual)
 }
 
 func Test_OpenMetrics_SetSkippedRecords(t *testing.T) {
-	metrics = nil
+	m := newTestMetrics(t)
 	const val = 5
 	expected := float64(val)
 
-	GetOpenMetricsInstance().SetSkippedRecords(testZone, val)
-	actual := testutil.ToFloat64(metrics.skippedRecords)
+	m.SetSkippedRecords(testZone, val)
+	actual := testutil.ToFloat64(m.skippedRecords)
 
 	assert.Equal(t, expected, actual)
 }
 
 func Test_OpenMetrics_SetRateLimitStats(t *testing.T) {
-	metrics = nil
+	m := newTestMetrics(t)
 	val := http.Header{
 		"Ratelimit-Limit":     {"1000"},
 		"Ratelimit-Remaining": {"500"},
@@ -116,12 +202,209 @@ func Test_OpenMetrics_SetRateLimitStats(t *testing.T) {
 	expRemaining := float64(500)
 	expReset := float64(1771370227)
 
-	GetOpenMetricsInstance().SetRateLimitStats(testAction, val)
-	actLimit := testutil.ToFloat64(metrics.rateLimitLimit)
-	actRemaining := testutil.ToFloat64(metrics.rateLimitRemaining)
-	actReset := testutil.ToFloat64(metrics.rateLimitResetSeconds)
+	m.SetRateLimitStats(testProject, testToken, testAction, val)
+	actLimit := testutil.ToFloat64(m.rateLimitLimit.WithLabelValues(testProject, testToken, testAction))
+	actRemaining := testutil.ToFloat64(m.rateLimitRemaining.WithLabelValues(testProject, testToken, testAction))
+	actReset := testutil.ToFloat64(m.rateLimitResetSeconds.WithLabelValues(testProject, testToken, testAction))
 
 	assert.Equal(t, expLimit, actLimit)
 	assert.Equal(t, expRemaining, actRemaining)
 	assert.Equal(t, expReset, actReset)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.rateLimitConsumedTotal))
+	assert.True(t, math.IsInf(testutil.ToFloat64(m.rateLimitExhaustionSeconds), 1))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.rateLimitExhaustionWarning))
+}
+
+func Test_OpenMetrics_SetRateLimitStats_warning(t *testing.T) {
+	m := newTestMetrics(t)
+	m.SetRateLimitWarningHorizon(time.Hour)
+	header := func(remaining string) http.Header {
+		return http.Header{
+			"Ratelimit-Limit":     {"3600"},
+			"Ratelimit-Remaining": {remaining},
+			"Ratelimit-Reset":     {"1771370227"},
+		}
+	}
+	// Simulate an older sample to have a consumption rate.
+	m.rateLimitPredictor.add(rateLimitKey{testProject, testToken}, time.Now().Add(-time.Minute), 1000)
+
+	m.SetRateLimitStats(testProject, testToken, testAction, header("400"))
+	prediction := testutil.ToFloat64(m.rateLimitExhaustionSeconds)
+	assert.InDelta(t, float64(40), prediction, 1)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.rateLimitExhaustionWarning))
+}
+
+func Test_OpenMetrics_GetRateLimitStatus(t *testing.T) {
+	m := newTestMetrics(t)
+	assert.Nil(t, m.GetRateLimitStatus())
+
+	// Invalid headers do not change the status.
+	m.SetRateLimitStats(testProject, testToken, testAction, http.Header{})
+	assert.Nil(t, m.GetRateLimitStatus())
+
+	m.SetRateLimitStats(testProject, testToken, testAction, http.Header{
+		"Ratelimit-Limit":     {"1000"},
+		"Ratelimit-Remaining": {"500"},
+		"Ratelimit-Reset":     {"1771370227"},
+	})
+	status := m.GetRateLimitStatus()
+	assert.NotNil(t, status)
+	assert.Equal(t, testProject, status.Project)
+	assert.Equal(t, testToken, status.Token)
+	assert.Equal(t, testAction, status.Action)
+	assert.Equal(t, 1000, status.Limit)
+	assert.Equal(t, 500, status.Remaining)
+	assert.Equal(t, int64(1771370227), status.Reset.Unix())
+	assert.False(t, status.Updated.IsZero())
+}
+
+func Test_OpenMetrics_IncBulkConflictsTotal(t *testing.T) {
+	m := newTestMetrics(t)
+	expected := float64(1)
+
+	m.IncBulkConflictsTotal(testZone)
+	actual := testutil.ToFloat64(m.bulkConflictsTotal)
+
+	assert.Equal(t, expected, actual)
+}
+
+func Test_OpenMetrics_SetHealthCheckSuccess(t *testing.T) {
+	m := newTestMetrics(t)
+	ts := time.Unix(1771370227, 0)
+
+	m.SetHealthCheckFailure(ts, 2)
+	m.SetHealthCheckSuccess(ts)
+
+	assert.Equal(t, float64(1771370227), testutil.ToFloat64(m.healthCheckLastSuccessSeconds))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.healthCheckConsecutiveFailures))
+}
+
+func Test_OpenMetrics_SetHealthCheckFailure(t *testing.T) {
+	m := newTestMetrics(t)
+	ts := time.Unix(1771370227, 0)
+
+	m.SetHealthCheckFailure(ts, 3)
+
+	assert.Equal(t, float64(1771370227), testutil.ToFloat64(m.healthCheckLastErrorSeconds))
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.healthCheckConsecutiveFailures))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.healthCheckFailuresTotal))
+}
+
+func Test_OpenMetrics_IncAuthRejectedTotal(t *testing.T) {
+	m := newTestMetrics(t)
+	expected := float64(1)
+
+	m.IncAuthRejectedTotal("invalid")
+	actual := testutil.ToFloat64(m.authRejectedTotal)
+
+	assert.Equal(t, expected, actual)
+}
+
+func Test_OpenMetrics_AddRecordChangesTotal(t *testing.T) {
+	m := newTestMetrics(t)
+
+	m.AddRecordChangesTotal(testZone, "create", 2)
+	m.AddRecordChangesTotal(testZone, "create", 1)
+	m.AddRecordChangesTotal(testZone, "delete", 1)
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.recordChangesTotal.WithLabelValues(testZone, "create")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.recordChangesTotal.WithLabelValues(testZone, "delete")))
+}
+
+func Test_OpenMetrics_SetManagedRRSets(t *testing.T) {
+	m := newTestMetrics(t)
+
+	m.SetManagedRRSets(testZone, map[string]int{"A": 3, "TXT": 2})
+	m.SetManagedRRSets("beta.com", map[string]int{"A": 1})
+	assert.Equal(t, 3, testutil.CollectAndCount(m.managedRRSets))
+
+	// Types no longer present are removed.
+	m.SetManagedRRSets(testZone, map[string]int{"A": 4})
+	assert.Equal(t, 2, testutil.CollectAndCount(m.managedRRSets))
+	assert.Equal(t, float64(4), testutil.ToFloat64(m.managedRRSets.WithLabelValues(testZone, "A")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.managedRRSets.WithLabelValues("beta.com", "A")))
+}
+
+func Test_OpenMetrics_ObserveDurations(t *testing.T) {
+	m := newTestMetrics(t)
+
+	m.ObserveApplyChangesDuration(1500 * time.Millisecond)
+	m.ObserveRecordsDuration(200 * time.Millisecond)
+	m.ObserveRecordsDuration(300 * time.Millisecond)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.applyChangesDuration))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.recordsDuration))
+	expected := `
+# HELP records_duration_seconds Histogram of the duration in seconds of Records
+# TYPE records_duration_seconds histogram
+records_duration_seconds_bucket{le="0.1"} 0
+records_duration_seconds_bucket{le="0.25"} 1
+records_duration_seconds_bucket{le="0.5"} 2
+records_duration_seconds_bucket{le="1"} 2
+records_duration_seconds_bucket{le="2.5"} 2
+records_duration_seconds_bucket{le="5"} 2
+records_duration_seconds_bucket{le="10"} 2
+records_duration_seconds_bucket{le="30"} 2
+records_duration_seconds_bucket{le="60"} 2
+records_duration_seconds_bucket{le="+Inf"} 2
+records_duration_seconds_sum 0.5
+records_duration_seconds_count 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(m.recordsDuration, strings.NewReader(expected)))
+}
+
+func Test_OpenMetrics_SetLastSuccessfulSync(t *testing.T) {
+	m := newTestMetrics(t)
+	ts := time.Unix(1771370227, 0)
+
+	m.SetLastSuccessfulSync(ts)
+
+	assert.Equal(t, float64(1771370227), testutil.ToFloat64(m.lastSuccessfulSyncSeconds))
+}
+
+func Test_OpenMetrics_IncConfigReloadsTotal(t *testing.T) {
+	m := newTestMetrics(t)
+	ts := time.Unix(1771370227, 0)
+
+	m.IncConfigReloadsTotal("failure", ts)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.configReloadsTotal.WithLabelValues("failure")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.configLastReloadSeconds))
+
+	m.IncConfigReloadsTotal("success", ts)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.configReloadsTotal.WithLabelValues("success")))
+	assert.Equal(t, float64(1771370227), testutil.ToFloat64(m.configLastReloadSeconds))
+}
+
+func Test_OpenMetrics_SetDriftedRRSets(t *testing.T) {
+	m := newTestMetrics(t)
+
+	m.SetDriftedRRSets(testZone, map[string]int{"missing": 1, "modified": 2})
+	m.SetDriftedRRSets("beta.com", map[string]int{"missing": 0, "modified": 0})
+	assert.Equal(t, 4, testutil.CollectAndCount(m.driftedRRSets))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.driftedRRSets.WithLabelValues(testZone, "modified")))
+
+	// A nil map removes the zone.
+	m.SetDriftedRRSets(testZone, nil)
+	assert.Equal(t, 2, testutil.CollectAndCount(m.driftedRRSets))
+}
+
+func Test_OpenMetrics_IncDriftChecksTotal(t *testing.T) {
+	m := newTestMetrics(t)
+	ts := time.Unix(1771370227, 0)
+
+	m.IncDriftChecksTotal("failure", ts)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.driftChecksTotal.WithLabelValues("failure")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.driftLastCheckSeconds))
+
+	m.IncDriftChecksTotal("success", ts)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.driftChecksTotal.WithLabelValues("success")))
+	assert.Equal(t, float64(1771370227), testutil.ToFloat64(m.driftLastCheckSeconds))
+}
+
+func Test_OpenMetrics_IncDriftCorrectionsTotal(t *testing.T) {
+	m := newTestMetrics(t)
+
+	m.IncDriftCorrectionsTotal(testZone)
+	m.IncDriftCorrectionsTotal(testZone)
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.driftCorrectionsTotal.WithLabelValues(testZone)))
 }
@@ -0,0 +1,95 @@
+/*
+ * Metrics - options.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/codingconcepts/env"
+)
+
+// namespaceRegex matches the valid metric namespaces.
+var namespaceRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Options contains the metrics configuration passed as environment variables.
+type Options struct {
+	// Prefix of the metric names, without the trailing underscore
+	Namespace string `env:"METRICS_NAMESPACE" default:""`
+	// Value of the cluster label added to every metric
+	Cluster string `env:"METRICS_CLUSTER" default:""`
+	// Value of the instance label added to every metric
+	Instance string `env:"METRICS_INSTANCE" default:""`
+	// Buckets of the API delay histogram in milliseconds
+	APIDelayBuckets []float64 `env:"METRICS_API_DELAY_BUCKETS" default:"10,100,250,500,1000,1500,2000"`
+	// Buckets of the ApplyChanges and Records duration histograms in seconds
+	DurationBuckets []float64 `env:"METRICS_DURATION_BUCKETS" default:"0.1,0.25,0.5,1,2.5,5,10,30,60"`
+}
+
+// DefaultOptions returns the default options.
+func DefaultOptions() Options {
+	return Options{
+		APIDelayBuckets: []float64{10, 100, 250, 500, 1000, 1500, 2000},
+		DurationBuckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}
+}
+
+// NewOptions reads the metrics options from the environment.
+func NewOptions() (*Options, error) {
+	options := &Options{}
+	if err := env.Set(options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// validateBuckets checks that the buckets are not empty and sorted in
+// increasing order.
+func validateBuckets(name string, buckets []float64) error {
+	if len(buckets) == 0 {
+		return fmt.Errorf("%s cannot be empty", name)
+	}
+	if !slices.IsSorted(buckets) || len(slices.Compact(slices.Clone(buckets))) != len(buckets) {
+		return fmt.Errorf("%s must be in increasing order", name)
+	}
+	return nil
+}
+
+// Validate checks the options.
+func (o Options) Validate() error {
+	if o.Namespace != "" && !namespaceRegex.MatchString(o.Namespace) {
+		return fmt.Errorf("invalid metrics namespace %s", o.Namespace)
+	}
+	if err := validateBuckets("METRICS_API_DELAY_BUCKETS", o.APIDelayBuckets); err != nil {
+		return err
+	}
+	return validateBuckets("METRICS_DURATION_BUCKETS", o.DurationBuckets)
+}
+
+// constLabels returns the labels added to every metric.
+func (o Options) constLabels() map[string]string {
+	labels := map[string]string{}
+	if o.Cluster != "" {
+		labels["cluster"] = o.Cluster
+	}
+	if o.Instance != "" {
+		labels["instance"] = o.Instance
+	}
+	return labels
+}
@@ -0,0 +1,86 @@
+/*
+ * Metrics - options unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewOptions(t *testing.T) {
+	t.Setenv("METRICS_NAMESPACE", "hetzner_webhook")
+	t.Setenv("METRICS_CLUSTER", "prod")
+	t.Setenv("METRICS_DURATION_BUCKETS", "0.5,1,5")
+	options, err := NewOptions()
+	assert.Nil(t, err)
+	expected := &Options{
+		Namespace:       "hetzner_webhook",
+		Cluster:         "prod",
+		APIDelayBuckets: DefaultOptions().APIDelayBuckets,
+		DurationBuckets: []float64{0.5, 1, 5},
+	}
+	assert.Equal(t, expected, options)
+}
+
+func Test_Options_Validate(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    Options
+		expected bool
+	}
+
+	valid := DefaultOptions()
+	withNamespace := func(namespace string) Options {
+		options := DefaultOptions()
+		options.Namespace = namespace
+		return options
+	}
+	withBuckets := func(buckets []float64) Options {
+		options := DefaultOptions()
+		options.APIDelayBuckets = buckets
+		return options
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		err := tc.input.Validate()
+		assert.Equal(t, tc.expected, err == nil)
+	}
+
+	testCases := []testCase{
+		{name: "defaults", input: valid, expected: true},
+		{name: "namespace", input: withNamespace("hetzner_webhook"), expected: true},
+		{name: "invalid namespace", input: withNamespace("hetzner-webhook"), expected: false},
+		{name: "namespace starting with a digit", input: withNamespace("1webhook"), expected: false},
+		{name: "empty buckets", input: withBuckets(nil), expected: false},
+		{name: "unsorted buckets", input: withBuckets([]float64{10, 5}), expected: false},
+		{name: "duplicate buckets", input: withBuckets([]float64{5, 5, 10}), expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_Options_constLabels(t *testing.T) {
+	assert.Equal(t, map[string]string{}, Options{}.constLabels())
+	assert.Equal(t, map[string]string{"cluster": "prod", "instance": "webhook-0"},
+		Options{Cluster: "prod", Instance: "webhook-0"}.constLabels())
+}
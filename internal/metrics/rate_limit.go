@@ -19,8 +19,11 @@ package metrics
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 )
 
 const (
@@ -30,8 +33,31 @@ const (
 	rlLimit     = "Ratelimit-Limit"
 	rlRemaining = "Ratelimit-Remaining"
 	rlReset     = "Ratelimit-Reset"
+
+	// predictionWindow is the period used for computing the consumption rate.
+	predictionWindow = 5 * time.Minute
+	// minPredictionSpan is the minimum period needed for a prediction.
+	minPredictionSpan = 10 * time.Second
 )
 
+// RateLimitStatus is the last rate limit information received from the API.
+type RateLimitStatus struct {
+	// Project of the token that received the information
+	Project string `json:"project"`
+	// Fingerprint of the token that received the information
+	Token string `json:"token"`
+	// Action that received the information
+	Action string `json:"action"`
+	// Total API calls that can be performed in a hour
+	Limit int `json:"limit"`
+	// Remaining API calls until the next reset
+	Remaining int `json:"remaining"`
+	// Time of the next reset
+	Reset time.Time `json:"reset"`
+	// Time when the information was received
+	Updated time.Time `json:"updated"`
+}
+
 // rateLimit holds the rate limit information.
 type rateLimit struct {
 	limit     int
@@ -99,3 +125,70 @@ func parseRateLimit(h http.Header) (*rateLimit, error) {
 		reset:     reset,
 	}, nil
 }
+
+// rateLimitSample is the remaining number of calls at a given time.
+type rateLimitSample struct {
+	time      time.Time
+	remaining int
+}
+
+// rateLimitKey identifies a token of a project, like the labels of the rate
+// limit gauges.
+type rateLimitKey struct {
+	project string
+	token   string
+}
+
+// rateLimitPredictor predicts when the rate limit of each token of each
+// project runs out from the remaining calls received in the prediction window.
+type rateLimitPredictor struct {
+	m       sync.Mutex
+	horizon time.Duration
+	samples map[rateLimitKey][]rateLimitSample
+	warning map[rateLimitKey]bool
+}
+
+// newRateLimitPredictor creates a new predictor. A horizon of 0 disables the
+// warnings.
+func newRateLimitPredictor(horizon time.Duration) *rateLimitPredictor {
+	return &rateLimitPredictor{
+		horizon: horizon,
+		samples: map[rateLimitKey][]rateLimitSample{},
+		warning: map[rateLimitKey]bool{},
+	}
+}
+
+// setHorizon sets the warning horizon.
+func (p *rateLimitPredictor) setHorizon(horizon time.Duration) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.horizon = horizon
+}
+
+// add adds a sample for a token of a project and returns the predicted seconds until the
+// limit runs out, whether the prediction is below the warning horizon and
+// whether the warning state changed. The prediction is +Inf when the
+// remaining calls are not decreasing.
+func (p *rateLimitPredictor) add(key rateLimitKey, now time.Time, remaining int) (float64, bool, bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	samples := append(p.samples[key], rateLimitSample{time: now, remaining: remaining})
+	first := 0
+	for first < len(samples)-1 && now.Sub(samples[first].time) > predictionWindow {
+		first++
+	}
+	samples = samples[first:]
+	p.samples[key] = samples
+
+	prediction := math.Inf(1)
+	oldest := samples[0]
+	span := now.Sub(oldest.time)
+	if consumed := oldest.remaining - remaining; span >= minPredictionSpan && consumed > 0 {
+		rate := float64(consumed) / span.Seconds()
+		prediction = float64(remaining) / rate
+	}
+	warning := p.horizon > 0 && prediction < p.horizon.Seconds()
+	changed := warning != p.warning[key]
+	p.warning[key] = warning
+	return prediction, warning, changed
+}
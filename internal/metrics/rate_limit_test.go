@@ -19,8 +19,10 @@ package metrics
 
 import (
 	"errors"
+	"math"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -308,3 +310,194 @@ func Test_parseRateLimit(t *testing.T) {
 		})
 	}
 }
+
+// Test_rateLimitPredictor_add tests rateLimitPredictor.add().
+func Test_rateLimitPredictor_add(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			horizon time.Duration
+			samples []rateLimitSample
+		}
+		expected struct {
+			prediction float64
+			warning    bool
+			changed    bool
+		}
+	}
+
+	start := time.Unix(1771370227, 0)
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		p := newRateLimitPredictor(inp.horizon)
+		var (
+			prediction       float64
+			warning, changed bool
+		)
+		for _, sample := range inp.samples {
+			prediction, warning, changed = p.add(rateLimitKey{testProject, testToken}, sample.time, sample.remaining)
+		}
+		if math.IsInf(exp.prediction, 1) {
+			assert.True(t, math.IsInf(prediction, 1))
+		} else {
+			assert.InDelta(t, exp.prediction, prediction, 0.001)
+		}
+		assert.Equal(t, exp.warning, warning)
+		assert.Equal(t, exp.changed, changed)
+	}
+
+	testCases := []testCase{
+		{
+			name: "single sample",
+			input: struct {
+				horizon time.Duration
+				samples []rateLimitSample
+			}{
+				horizon: time.Hour,
+				samples: []rateLimitSample{{start, 1000}},
+			},
+			expected: struct {
+				prediction float64
+				warning    bool
+				changed    bool
+			}{math.Inf(1), false, false},
+		},
+		{
+			name: "span too short",
+			input: struct {
+				horizon time.Duration
+				samples []rateLimitSample
+			}{
+				horizon: time.Hour,
+				samples: []rateLimitSample{{start, 1000}, {start.Add(time.Second), 900}},
+			},
+			expected: struct {
+				prediction float64
+				warning    bool
+				changed    bool
+			}{math.Inf(1), false, false},
+		},
+		{
+			name: "not decreasing",
+			input: struct {
+				horizon time.Duration
+				samples []rateLimitSample
+			}{
+				horizon: time.Hour,
+				samples: []rateLimitSample{{start, 1000}, {start.Add(time.Minute), 1010}},
+			},
+			expected: struct {
+				prediction float64
+				warning    bool
+				changed    bool
+			}{math.Inf(1), false, false},
+		},
+		{
+			name: "above the horizon",
+			input: struct {
+				horizon time.Duration
+				samples []rateLimitSample
+			}{
+				horizon: 10 * time.Minute,
+				samples: []rateLimitSample{{start, 1000}, {start.Add(time.Minute), 940}},
+			},
+			expected: struct {
+				prediction float64
+				warning    bool
+				changed    bool
+			}{940, false, false},
+		},
+		{
+			name: "warning raised",
+			input: struct {
+				horizon time.Duration
+				samples []rateLimitSample
+			}{
+				horizon: 30 * time.Minute,
+				samples: []rateLimitSample{{start, 1000}, {start.Add(time.Minute), 940}},
+			},
+			expected: struct {
+				prediction float64
+				warning    bool
+				changed    bool
+			}{940, true, true},
+		},
+		{
+			name: "warning disabled",
+			input: struct {
+				horizon time.Duration
+				samples []rateLimitSample
+			}{
+				samples: []rateLimitSample{{start, 1000}, {start.Add(time.Minute), 940}},
+			},
+			expected: struct {
+				prediction float64
+				warning    bool
+				changed    bool
+			}{940, false, false},
+		},
+		{
+			name: "warning cleared",
+			input: struct {
+				horizon time.Duration
+				samples []rateLimitSample
+			}{
+				horizon: 30 * time.Minute,
+				samples: []rateLimitSample{
+					{start, 1000},
+					{start.Add(time.Minute), 940},
+					{start.Add(10 * time.Minute), 1000},
+				},
+			},
+			expected: struct {
+				prediction float64
+				warning    bool
+				changed    bool
+			}{math.Inf(1), false, true},
+		},
+		{
+			name: "old samples discarded",
+			input: struct {
+				horizon time.Duration
+				samples []rateLimitSample
+			}{
+				horizon: time.Hour,
+				samples: []rateLimitSample{
+					{start, 3000},
+					{start.Add(4 * time.Minute), 1000},
+					{start.Add(8 * time.Minute), 980},
+				},
+			},
+			expected: struct {
+				prediction float64
+				warning    bool
+				changed    bool
+			}{11760, false, true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_rateLimitPredictor_projects tests that the projects sharing a token
+// get separate predictions.
+func Test_rateLimitPredictor_projects(t *testing.T) {
+	start := time.Unix(1771370227, 0)
+	p := newRateLimitPredictor(time.Hour)
+	prod := rateLimitKey{"prod", testToken}
+	staging := rateLimitKey{"staging", testToken}
+	p.add(prod, start, 1000)
+	p.add(staging, start, 1000)
+	prediction, warning, _ := p.add(prod, start.Add(time.Minute), 400)
+	assert.InDelta(t, float64(40), prediction, 0.001)
+	assert.True(t, warning)
+	prediction, warning, _ = p.add(staging, start.Add(time.Minute), 1000)
+	assert.True(t, math.IsInf(prediction, 1))
+	assert.False(t, warning)
+}
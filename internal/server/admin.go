@@ -0,0 +1,113 @@
+/*
+ * Admin - read-only admin API for runtime inspection.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"external-dns-hetzner-webhook/internal/logging"
+	"external-dns-hetzner-webhook/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Inspector exposes the runtime state of the provider. The returned values are
+// encoded as JSON.
+type Inspector interface {
+	// InspectZones returns the zones known to the provider.
+	InspectZones() any
+	// InspectFailures returns the current fail count.
+	InspectFailures() any
+	// InspectLastApply returns the summary of the last applied changes.
+	InspectLastApply() any
+}
+
+// admin serves the read-only admin API. The inspector and the configuration
+// are set once the provider is available.
+type admin struct {
+	m         sync.RWMutex
+	inspector Inspector
+	config    any
+	metrics   *metrics.OpenMetrics
+}
+
+// set sets the inspector and the configuration shown by the admin API.
+func (a *admin) set(inspector Inspector, config any) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.inspector = inspector
+	a.config = config
+}
+
+// get returns the inspector and the configuration.
+func (a *admin) get() (Inspector, any) {
+	a.m.RLock()
+	defer a.m.RUnlock()
+	return a.inspector, a.config
+}
+
+// writeJSON writes a value as JSON with the given status code.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Warnf("Could not answer to an admin request: %s", err.Error())
+	}
+}
+
+// handler returns a read-only handler that writes the value returned by
+// value. The provider must be available if needsProvider is true.
+func (a *admin) handler(needsProvider bool, value func(Inspector, any) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		inspector, config := a.get()
+		if needsProvider && inspector == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "provider not available"})
+			return
+		}
+		writeJSON(w, http.StatusOK, value(inspector, config))
+	}
+}
+
+// register registers the admin endpoints on the mux.
+func (a *admin) register(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/zones", a.handler(true, func(i Inspector, _ any) any {
+		return i.InspectZones()
+	}))
+	mux.HandleFunc("/admin/config", a.handler(true, func(_ Inspector, c any) any {
+		return c
+	}))
+	mux.HandleFunc("/admin/failures", a.handler(true, func(i Inspector, _ any) any {
+		return i.InspectFailures()
+	}))
+	mux.HandleFunc("/admin/last-apply", a.handler(true, func(i Inspector, _ any) any {
+		return i.InspectLastApply()
+	}))
+	mux.HandleFunc("/admin/ratelimit", a.handler(false, func(_ Inspector, _ any) any {
+		return a.metrics.GetRateLimitStatus()
+	}))
+	mux.HandleFunc("/admin/loglevel", a.handler(false, func(_ Inspector, _ any) any {
+		return logging.Levels()
+	}))
+}
@@ -0,0 +1,243 @@
+/*
+ * Admin - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testAdminPort is the test port for the admin API on localhost.
+const testAdminPort = testPort + 4
+
+// mockInspector returns fixed values.
+type mockInspector struct{}
+
+// InspectZones returns a fixed list of zones.
+func (i mockInspector) InspectZones() any {
+	return []map[string]any{{"id": 1, "name": "alpha.com"}}
+}
+
+// InspectFailures returns a fixed fail count.
+func (i mockInspector) InspectFailures() any {
+	return map[string]int{"failCount": 2}
+}
+
+// InspectLastApply returns no summary.
+func (i mockInspector) InspectLastApply() any {
+	return nil
+}
+
+func Test_admin_handlers(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			inspector Inspector
+			method    string
+			path      string
+		}
+		expected struct {
+			status int
+			body   string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		a := &admin{}
+		if inp.inspector != nil {
+			a.set(inp.inspector, map[string]string{"APIKey": "REDACTED"})
+		}
+		mux := http.NewServeMux()
+		a.register(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(inp.method, inp.path, nil))
+		assert.Equal(t, exp.status, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Equal(t, exp.body+"\n", rec.Body.String())
+	}
+
+	testCases := []testCase{
+		{
+			name: "zones",
+			input: struct {
+				inspector Inspector
+				method    string
+				path      string
+			}{mockInspector{}, http.MethodGet, "/admin/zones"},
+			expected: struct {
+				status int
+				body   string
+			}{http.StatusOK, `[{"id":1,"name":"alpha.com"}]`},
+		},
+		{
+			name: "config",
+			input: struct {
+				inspector Inspector
+				method    string
+				path      string
+			}{mockInspector{}, http.MethodGet, "/admin/config"},
+			expected: struct {
+				status int
+				body   string
+			}{http.StatusOK, `{"APIKey":"REDACTED"}`},
+		},
+		{
+			name: "failures",
+			input: struct {
+				inspector Inspector
+				method    string
+				path      string
+			}{mockInspector{}, http.MethodGet, "/admin/failures"},
+			expected: struct {
+				status int
+				body   string
+			}{http.StatusOK, `{"failCount":2}`},
+		},
+		{
+			name: "last apply",
+			input: struct {
+				inspector Inspector
+				method    string
+				path      string
+			}{mockInspector{}, http.MethodGet, "/admin/last-apply"},
+			expected: struct {
+				status int
+				body   string
+			}{http.StatusOK, `null`},
+		},
+		{
+			name: "provider not available",
+			input: struct {
+				inspector Inspector
+				method    string
+				path      string
+			}{nil, http.MethodGet, "/admin/zones"},
+			expected: struct {
+				status int
+				body   string
+			}{http.StatusServiceUnavailable, `{"error":"provider not available"}`},
+		},
+		{
+			name: "rate limit without provider",
+			input: struct {
+				inspector Inspector
+				method    string
+				path      string
+			}{nil, http.MethodGet, "/admin/ratelimit"},
+			expected: struct {
+				status int
+				body   string
+			}{http.StatusOK, `null`},
+		},
+		{
+			name: "log levels",
+			input: struct {
+				inspector Inspector
+				method    string
+				path      string
+			}{nil, http.MethodGet, "/admin/loglevel"},
+			expected: struct {
+				status int
+				body   string
+			}{http.StatusOK, `{"api":"info","changes":"info","default":"info","provider":"info","zonefile":"info"}`},
+		},
+		{
+			name: "method not allowed",
+			input: struct {
+				inspector Inspector
+				method    string
+				path      string
+			}{mockInspector{}, http.MethodPost, "/admin/zones"},
+			expected: struct {
+				status int
+				body   string
+			}{http.StatusMethodNotAllowed, `{"error":"method not allowed"}`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_MetricsSocket_admin(t *testing.T) {
+	run := func(t *testing.T, enabled bool, authMode string, token string) string {
+		options := SocketOptions{
+			MetricsHost:     testHost,
+			MetricsPort:     testAdminPort,
+			AdminAPIEnabled: enabled,
+			WebhookAuthMode: authMode,
+		}
+		if authMode != "" {
+			// The exemption of the local clients does not apply to the admin
+			// API.
+			options.WebhookAuthAllowLocal = true
+			options.WebhookAuthSecretFile = filepath.Join(t.TempDir(), "secret")
+			assert.Nil(t, os.WriteFile(options.WebhookAuthSecretFile, []byte("TEST_TOKEN"), 0o600))
+		}
+		metricsSocket := NewMetricsSocket(&Status{ready: mutexedBool{v: true}}, nil)
+		metricsSocket.SetInspector(mockInspector{}, nil)
+		startedChan := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- metricsSocket.Start(startedChan, options)
+		}()
+		<-startedChan
+		defer func() {
+			assert.Nil(t, metricsSocket.Shutdown(context.Background()))
+			assert.Nil(t, <-done)
+		}()
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d/admin/failures", testHost, testAdminPort), nil)
+		assert.Nil(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		assert.Equal(t, "{\"failCount\":2}\n", run(t, true, "", ""))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		// The request falls back to the readiness handler.
+		assert.Equal(t, http.StatusText(http.StatusOK), run(t, false, "", ""))
+	})
+	t.Run("authenticated", func(t *testing.T) {
+		assert.Equal(t, "{\"failCount\":2}\n", run(t, true, authModeBearer, "TEST_TOKEN"))
+	})
+	t.Run("not authenticated", func(t *testing.T) {
+		assert.Equal(t, http.StatusText(http.StatusUnauthorized)+"\n", run(t, true, authModeBearer, ""))
+	})
+}
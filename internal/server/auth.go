@@ -0,0 +1,255 @@
+/*
+ * Auth - authentication of the webhook requests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Bearer token authentication
+	authModeBearer = "bearer"
+	// HMAC signed requests authentication
+	authModeHMAC = "hmac"
+
+	// Header containing the request timestamp for HMAC authentication
+	headerTimestamp = "X-Webhook-Timestamp"
+	// Header containing the request signature for HMAC authentication
+	headerSignature = "X-Webhook-Signature"
+	// Prefix of the signature
+	signaturePrefix = "sha256="
+	// Maximum difference between the request timestamp and the current time
+	maxClockSkew = 5 * time.Minute
+	// Maximum size of a signed request body
+	maxSignedBodySize = 32 << 20
+
+	// Rejection reasons used as metric labels
+	reasonMissing = "missing"
+	reasonInvalid = "invalid"
+	reasonExpired = "expired"
+	reasonError   = "error"
+)
+
+// secretFile contains a secret read from a file. The file is read again when
+// its modification time changes.
+type secretFile struct {
+	path    string
+	m       sync.Mutex
+	modTime time.Time
+	secret  []byte
+}
+
+// newSecretFile creates a new secretFile, reading the secret.
+func newSecretFile(path string) (*secretFile, error) {
+	f := &secretFile{path: path}
+	if _, err := f.get(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// get returns the current secret, reading it again if the file changed. If
+// the file cannot be read after a change, the previous secret is kept.
+func (f *secretFile) get() ([]byte, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	info, err := os.Stat(f.path)
+	if err == nil && info.ModTime().Equal(f.modTime) {
+		return f.secret, nil
+	}
+	var data []byte
+	if err == nil {
+		data, err = os.ReadFile(f.path)
+	}
+	if err == nil && len(bytes.TrimSpace(data)) == 0 {
+		err = fmt.Errorf("empty secret in %s", f.path)
+	}
+	if err != nil {
+		if f.secret != nil {
+			log.Warnf("Cannot reload the webhook secret, keeping the current one: %v", err)
+			return f.secret, nil
+		}
+		return nil, fmt.Errorf("cannot read webhook secret: %w", err)
+	}
+	if f.secret != nil {
+		log.Info("Webhook secret reloaded.")
+	}
+	f.secret = bytes.TrimSpace(data)
+	f.modTime = info.ModTime()
+	return f.secret, nil
+}
+
+// authenticator checks the webhook requests before passing them to the
+// handlers.
+type authenticator struct {
+	mode    string
+	secret  *secretFile
+	now     func() time.Time
+	metrics *metrics.OpenMetrics
+	// Accept the requests of local peers without authentication
+	allowLocal bool
+}
+
+// validateAuth checks the authentication mode and the secret file.
+func validateAuth(mode string, secretPath string) error {
+	switch mode {
+	case "":
+		return nil
+	case authModeBearer, authModeHMAC:
+	default:
+		return fmt.Errorf("unsupported authentication mode %s", mode)
+	}
+	if secretPath == "" {
+		return errors.New("a secret file is required for authentication")
+	}
+	return nil
+}
+
+// newAuthenticator creates a new authenticator. It returns nil if the
+// authentication is disabled.
+func newAuthenticator(mode string, secretPath string, m *metrics.OpenMetrics) (*authenticator, error) {
+	if err := validateAuth(mode, secretPath); err != nil {
+		return nil, err
+	}
+	if mode == "" {
+		return nil, nil
+	}
+	secret, err := newSecretFile(secretPath)
+	if err != nil {
+		return nil, err
+	}
+	return &authenticator{
+		mode:    mode,
+		secret:  secret,
+		now:     time.Now,
+		metrics: m,
+	}, nil
+}
+
+// checkBearer checks the bearer token in the Authorization header.
+func (a *authenticator) checkBearer(r *http.Request, secret []byte) string {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return reasonMissing
+	}
+	if subtle.ConstantTimeCompare([]byte(token), secret) != 1 {
+		return reasonInvalid
+	}
+	return ""
+}
+
+// signature returns the hex encoded HMAC-SHA256 of the timestamp, the method,
+// the path with the query and the body, separated by newlines.
+func signature(secret []byte, timestamp string, r *http.Request, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", timestamp, r.Method, r.URL.RequestURI())
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkHMAC checks the request signature. The body is restored so that it can
+// be read again by the handlers.
+func (a *authenticator) checkHMAC(r *http.Request, secret []byte) string {
+	timestamp := r.Header.Get(headerTimestamp)
+	sig, found := strings.CutPrefix(r.Header.Get(headerSignature), signaturePrefix)
+	if timestamp == "" || !found {
+		return reasonMissing
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return reasonInvalid
+	}
+	skew := a.now().Sub(time.Unix(ts, 0))
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		return reasonExpired
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize))
+	if err != nil {
+		return reasonError
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	expected := signature(secret, timestamp, r, body)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return reasonInvalid
+	}
+	return ""
+}
+
+// localPeer returns true if the request comes from a unix domain socket or
+// from a loopback address.
+func localPeer(r *http.Request) bool {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// wrap returns a handler that authenticates the requests before passing them
+// to next. If allowLocal is set, the requests of local peers are passed
+// without authentication.
+func (a *authenticator) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.allowLocal && localPeer(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		secret, err := a.secret.get()
+		reason := reasonError
+		if err == nil {
+			if a.mode == authModeBearer {
+				reason = a.checkBearer(r, secret)
+			} else {
+				reason = a.checkHMAC(r, secret)
+			}
+		}
+		if reason != "" {
+			a.metrics.IncAuthRejectedTotal(reason)
+			log.Warnf("Rejected webhook request %s %s from %s: %s authentication.",
+				r.Method, r.URL.Path, r.RemoteAddr, reason)
+			if a.mode == authModeBearer {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,464 @@
+/*
+ * Auth - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeSecret writes a secret file with the given modification time.
+func writeSecret(t *testing.T, path, secret string, modTime time.Time) {
+	assert.Nil(t, os.WriteFile(path, []byte(secret), 0o600))
+	assert.Nil(t, os.Chtimes(path, modTime, modTime))
+}
+
+func Test_secretFile_get(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	now := time.Now()
+
+	_, err := newSecretFile(path)
+	assert.NotNil(t, err)
+
+	writeSecret(t, path, " \n", now.Add(-2*time.Minute))
+	_, err = newSecretFile(path)
+	assert.NotNil(t, err)
+
+	writeSecret(t, path, "first\n", now.Add(-time.Minute))
+	f, err := newSecretFile(path)
+	assert.Nil(t, err)
+	secret, _ := f.get()
+	assert.Equal(t, "first", string(secret))
+
+	// A broken rotation keeps the current secret.
+	writeSecret(t, path, "", now)
+	secret, err = f.get()
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(secret))
+
+	writeSecret(t, path, "second", now.Add(time.Minute))
+	secret, _ = f.get()
+	assert.Equal(t, "second", string(secret))
+}
+
+func Test_newAuthenticator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	writeSecret(t, path, "secret", time.Now())
+
+	type testCase struct {
+		name  string
+		input struct {
+			mode string
+			path string
+		}
+		expected struct {
+			enabled bool
+			err     bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		auth, err := newAuthenticator(inp.mode, inp.path, nil)
+		assert.Equal(t, exp.err, err != nil)
+		assert.Equal(t, exp.enabled, auth != nil)
+	}
+
+	testCases := []testCase{
+		{
+			name: "disabled",
+		},
+		{
+			name: "bearer",
+			input: struct {
+				mode string
+				path string
+			}{authModeBearer, path},
+			expected: struct {
+				enabled bool
+				err     bool
+			}{enabled: true},
+		},
+		{
+			name: "hmac",
+			input: struct {
+				mode string
+				path string
+			}{authModeHMAC, path},
+			expected: struct {
+				enabled bool
+				err     bool
+			}{enabled: true},
+		},
+		{
+			name: "unsupported mode",
+			input: struct {
+				mode string
+				path string
+			}{"basic", path},
+			expected: struct {
+				enabled bool
+				err     bool
+			}{err: true},
+		},
+		{
+			name: "missing secret file setting",
+			input: struct {
+				mode string
+				path string
+			}{authModeBearer, ""},
+			expected: struct {
+				enabled bool
+				err     bool
+			}{err: true},
+		},
+		{
+			name: "unreadable secret file",
+			input: struct {
+				mode string
+				path string
+			}{authModeHMAC, path + ".missing"},
+			expected: struct {
+				enabled bool
+				err     bool
+			}{err: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// echoHandler returns the request body.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	_, _ = w.Write(body)
+})
+
+func Test_authenticator_bearer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	writeSecret(t, path, "token", time.Now())
+	auth, err := newAuthenticator(authModeBearer, path, nil)
+	assert.Nil(t, err)
+	handler := auth.wrap(echoHandler)
+
+	type testCase struct {
+		name       string
+		input      string
+		remoteAddr string
+		expected   int
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		req := httptest.NewRequest(http.MethodGet, "/records", nil)
+		if tc.remoteAddr != "" {
+			req.RemoteAddr = tc.remoteAddr
+		}
+		if tc.input != "" {
+			req.Header.Set("Authorization", tc.input)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, tc.expected, rec.Code)
+		if tc.expected == http.StatusUnauthorized {
+			assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	testCases := []testCase{
+		{name: "valid token", input: "Bearer token", expected: http.StatusOK},
+		{name: "missing header", expected: http.StatusUnauthorized},
+		{name: "local peer", remoteAddr: "127.0.0.1:1234", expected: http.StatusUnauthorized},
+		{name: "wrong scheme", input: "Basic token", expected: http.StatusUnauthorized},
+		{name: "wrong token", input: "Bearer other", expected: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_authenticator_hmac(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	writeSecret(t, path, "key", time.Now())
+	auth, err := newAuthenticator(authModeHMAC, path, nil)
+	assert.Nil(t, err)
+	now := time.Unix(1700000000, 0)
+	auth.now = func() time.Time { return now }
+	handler := auth.wrap(echoHandler)
+
+	const body = `{"Create":[]}`
+	sign := func(key string, ts time.Time, body string) (string, string) {
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/records", nil)
+		return timestamp, signaturePrefix + signature([]byte(key), timestamp, req, []byte(body))
+	}
+
+	type testCase struct {
+		name  string
+		input struct {
+			timestamp string
+			signature string
+		}
+		expected int
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		req := httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(body))
+		if inp.timestamp != "" {
+			req.Header.Set(headerTimestamp, inp.timestamp)
+		}
+		if inp.signature != "" {
+			req.Header.Set(headerSignature, inp.signature)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, tc.expected, rec.Code)
+		if tc.expected == http.StatusOK {
+			// The handler still receives the whole body.
+			assert.Equal(t, body, rec.Body.String())
+		}
+	}
+
+	validTS, validSig := sign("key", now, body)
+	oldTS, oldSig := sign("key", now.Add(-maxClockSkew-time.Second), body)
+	_, wrongKeySig := sign("other", now, body)
+	_, wrongBodySig := sign("key", now, "{}")
+
+	testCases := []testCase{
+		{
+			name: "valid signature",
+			input: struct {
+				timestamp string
+				signature string
+			}{validTS, validSig},
+			expected: http.StatusOK,
+		},
+		{
+			name:     "missing headers",
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name: "missing signature prefix",
+			input: struct {
+				timestamp string
+				signature string
+			}{validTS, strings.TrimPrefix(validSig, signaturePrefix)},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name: "invalid timestamp",
+			input: struct {
+				timestamp string
+				signature string
+			}{"yesterday", validSig},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name: "expired timestamp",
+			input: struct {
+				timestamp string
+				signature string
+			}{oldTS, oldSig},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong key",
+			input: struct {
+				timestamp string
+				signature string
+			}{validTS, wrongKeySig},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name: "tampered body",
+			input: struct {
+				timestamp string
+				signature string
+			}{validTS, wrongBodySig},
+			expected: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_WebhookSocket_auth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	writeSecret(t, path, "token", time.Now())
+	options := SocketOptions{
+		WebhookHost:           testHost,
+		WebhookPort:           testWebhookPort,
+		WebhookAuthMode:       authModeBearer,
+		WebhookAuthSecretFile: path,
+	}
+	startedChan := make(chan struct{})
+	m := newTestMetrics(t, "")
+	webhookSocket := NewWebhookSocket(mockProvider{}, m)
+	done := make(chan error)
+	go func() {
+		done <- webhookSocket.Start(startedChan, options)
+	}()
+	<-startedChan
+	defer func() {
+		assert.Nil(t, webhookSocket.Shutdown(context.Background()))
+		assert.Nil(t, <-done)
+	}()
+
+	url := "http://" + options.GetWebhookAddress() + "/records"
+	res, err := http.Get(url)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, float64(1), metricSum(t, m, "webhook_auth_rejected_total"))
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Authorization", "Bearer token")
+	res, err = http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func Test_WebhookSocket_authAllowLocal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	writeSecret(t, path, "token", time.Now())
+	options := SocketOptions{
+		WebhookHost:           testHost,
+		WebhookPort:           testWebhookPort,
+		WebhookAuthMode:       authModeBearer,
+		WebhookAuthSecretFile: path,
+		WebhookAuthAllowLocal: true,
+	}
+	startedChan := make(chan struct{})
+	m := newTestMetrics(t, "")
+	webhookSocket := NewWebhookSocket(mockProvider{}, m)
+	done := make(chan error)
+	go func() {
+		done <- webhookSocket.Start(startedChan, options)
+	}()
+	<-startedChan
+	defer func() {
+		assert.Nil(t, webhookSocket.Shutdown(context.Background()))
+		assert.Nil(t, <-done)
+	}()
+
+	res, err := http.Get("http://" + options.GetWebhookAddress() + "/records")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, float64(0), metricSum(t, m, "webhook_auth_rejected_total"))
+}
+
+func Test_localPeer(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			remoteAddr string
+			localAddr  net.Addr
+		}
+		expected bool
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		req := httptest.NewRequest(http.MethodGet, "/records", nil)
+		req.RemoteAddr = inp.remoteAddr
+		if inp.localAddr != nil {
+			req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, inp.localAddr))
+		}
+		assert.Equal(t, tc.expected, localPeer(req))
+	}
+
+	testCases := []testCase{
+		{
+			name: "remote address",
+			input: struct {
+				remoteAddr string
+				localAddr  net.Addr
+			}{remoteAddr: "192.0.2.1:1234"},
+		},
+		{
+			name: "IPv4 loopback",
+			input: struct {
+				remoteAddr string
+				localAddr  net.Addr
+			}{remoteAddr: "127.0.0.1:1234"},
+			expected: true,
+		},
+		{
+			name: "IPv6 loopback",
+			input: struct {
+				remoteAddr string
+				localAddr  net.Addr
+			}{remoteAddr: "[::1]:1234"},
+			expected: true,
+		},
+		{
+			name: "unix domain socket",
+			input: struct {
+				remoteAddr string
+				localAddr  net.Addr
+			}{remoteAddr: "@", localAddr: &net.UnixAddr{Name: "/run/webhook.sock", Net: "unix"}},
+			expected: true,
+		},
+		{
+			name: "invalid address",
+			input: struct {
+				remoteAddr string
+				localAddr  net.Addr
+			}{remoteAddr: "invalid"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_WebhookSocket_invalidAuth(t *testing.T) {
+	options := SocketOptions{
+		WebhookHost:     testHost,
+		WebhookPort:     testWebhookPort,
+		WebhookAuthMode: "basic",
+	}
+	webhookSocket := NewWebhookSocket(mockProvider{}, nil)
+	err := webhookSocket.Start(make(chan struct{}, 1), options)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,100 @@
+/*
+ * Health checker - checks the reachability of the Hetzner API.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Pinger performs a cheap authenticated call to the DNS API.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthChecker periodically pings the DNS API and removes the readiness flag
+// after too many consecutive failures. The flag is set again after the first
+// successful check.
+type HealthChecker struct {
+	pinger      Pinger
+	status      *Status
+	interval    time.Duration
+	maxFailures int
+	unready     bool
+	metrics     *metrics.OpenMetrics
+}
+
+// NewHealthChecker creates a new HealthChecker instance.
+func NewHealthChecker(pinger Pinger, status *Status, options SocketOptions, m *metrics.OpenMetrics) *HealthChecker {
+	return &HealthChecker{
+		pinger:      pinger,
+		status:      status,
+		interval:    options.GetHealthCheckInterval(),
+		maxFailures: options.HealthCheckFailures,
+		metrics:     m,
+	}
+}
+
+// check pings the API once and updates the status.
+func (h *HealthChecker) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, h.interval)
+	defer cancel()
+	err := h.pinger.Ping(ctx)
+	now := time.Now()
+	failures := h.status.recordCheck(err, now)
+	m := h.metrics
+	if err == nil {
+		m.SetHealthCheckSuccess(now)
+		if h.unready {
+			log.Info("Hetzner API reachable again: the webhook is ready.")
+			h.status.SetReady(true)
+			h.unready = false
+		}
+		return
+	}
+	m.SetHealthCheckFailure(now, failures)
+	log.Warnf("Hetzner API health check failed (%d consecutive failures): %v", failures, err)
+	if !h.unready && h.maxFailures > 0 && failures >= h.maxFailures {
+		log.Errorf("Hetzner API unreachable after %d checks: the webhook is not ready.", failures)
+		h.status.SetReady(false)
+		h.unready = true
+	}
+}
+
+// Run checks the API at every interval until the context is cancelled.
+func (h *HealthChecker) Run(ctx context.Context) {
+	if h.interval <= 0 {
+		log.Info("Hetzner API health checks disabled.")
+		return
+	}
+	log.Infof("Checking Hetzner API health every %s.", h.interval)
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Health checker - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockPinger returns the configured errors in sequence.
+type mockPinger struct {
+	m      sync.Mutex
+	errs   []error
+	called int
+}
+
+// Ping returns the next error in the sequence.
+func (p *mockPinger) Ping(ctx context.Context) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	var err error
+	if p.called < len(p.errs) {
+		err = p.errs[p.called]
+	}
+	p.called++
+	return err
+}
+
+// Test_HealthChecker_check tests HealthChecker.check().
+func Test_HealthChecker_check(t *testing.T) {
+	type testCase struct {
+		name        string
+		errs        []error
+		maxFailures int
+		expected    struct {
+			ready    []bool
+			failures int
+		}
+	}
+
+	testErr := errors.New("test ping error")
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		status := &Status{ready: mutexedBool{v: true}}
+		checker := NewHealthChecker(&mockPinger{errs: tc.errs}, status, SocketOptions{
+			HealthCheckInterval: 1,
+			HealthCheckFailures: tc.maxFailures,
+		}, nil)
+		ready := make([]bool, len(tc.errs))
+		for i := range tc.errs {
+			checker.check(context.Background())
+			ready[i] = status.IsReady()
+		}
+		assert.Equal(t, exp.ready, ready)
+		assert.Equal(t, exp.failures, status.Report().ConsecutiveFailures)
+	}
+
+	testCases := []testCase{
+		{
+			name:        "always successful",
+			errs:        []error{nil, nil},
+			maxFailures: 2,
+			expected: struct {
+				ready    []bool
+				failures int
+			}{
+				ready: []bool{true, true},
+			},
+		},
+		{
+			name:        "not ready after failures",
+			errs:        []error{testErr, testErr, testErr},
+			maxFailures: 2,
+			expected: struct {
+				ready    []bool
+				failures int
+			}{
+				ready:    []bool{true, false, false},
+				failures: 3,
+			},
+		},
+		{
+			name:        "ready again after success",
+			errs:        []error{testErr, testErr, nil},
+			maxFailures: 2,
+			expected: struct {
+				ready    []bool
+				failures int
+			}{
+				ready: []bool{true, false, true},
+			},
+		},
+		{
+			name:        "readiness never changed",
+			errs:        []error{testErr, testErr, testErr},
+			maxFailures: 0,
+			expected: struct {
+				ready    []bool
+				failures int
+			}{
+				ready:    []bool{true, true, true},
+				failures: 3,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_HealthChecker_Run tests HealthChecker.Run().
+func Test_HealthChecker_Run(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		pinger := &mockPinger{}
+		checker := NewHealthChecker(pinger, &Status{}, SocketOptions{}, nil)
+		checker.Run(context.Background())
+		assert.Equal(t, 0, pinger.called)
+	})
+	t.Run("stops when cancelled", func(t *testing.T) {
+		pinger := &mockPinger{}
+		status := &Status{}
+		m := newTestMetrics(t, "")
+		checker := NewHealthChecker(pinger, status, SocketOptions{HealthCheckInterval: 1}, m)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			checker.Run(ctx)
+			close(done)
+		}()
+		assert.Eventually(t, func() bool {
+			return status.Report().LastSuccess != nil
+		}, time.Second, 10*time.Millisecond)
+		cancel()
+		<-done
+		assert.Greater(t, metricSum(t, m, "health_check_last_success_seconds"), float64(0))
+	})
+}
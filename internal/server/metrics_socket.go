@@ -16,11 +16,14 @@
 package server
 
 import (
-	"net"
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"external-dns-hetzner-webhook/internal/metrics"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 )
@@ -28,19 +31,31 @@ import (
 // MetricsSocket represents the socket that serves the Open Metrics, as well as
 // the liveness and readiness probes.
 type MetricsSocket struct {
-	status *Status
+	socket
+	status  *Status
+	metrics *metrics.OpenMetrics
+	admin   admin
 }
 
-// NewMetricsSocket initializes a new MetricsSocket intance.
-func NewMetricsSocket(status *Status) *MetricsSocket {
+// NewMetricsSocket initializes a new MetricsSocket intance, serving the
+// registry of the given metrics.
+func NewMetricsSocket(status *Status, m *metrics.OpenMetrics) *MetricsSocket {
 	return &MetricsSocket{
-		status: status,
+		status:  status,
+		metrics: m,
+		admin:   admin{metrics: m},
 	}
 }
 
+// SetInspector sets the provider inspector and the effective configuration
+// shown by the admin API.
+func (s *MetricsSocket) SetInspector(inspector Inspector, config any) {
+	s.admin.set(inspector, config)
+}
+
 // livenessHandler checks if the server is healthy. It writes 200/OK if the
 // healthy flag is set to "true" and 503/Service Unavailable otherwise.
-func (s MetricsSocket) livenessHandler(w http.ResponseWriter, r *http.Request) {
+func (s *MetricsSocket) livenessHandler(w http.ResponseWriter, r *http.Request) {
 	healthy := s.status.IsHealthy()
 	var err error
 	if healthy {
@@ -56,7 +71,7 @@ func (s MetricsSocket) livenessHandler(w http.ResponseWriter, r *http.Request) {
 
 // readinessHandler checks if the server is ready. It writes 200/OK if the
 // healthy flag is set to "true" and 503/Service Unavailable otherwise.
-func (s MetricsSocket) readinessHandler(w http.ResponseWriter, r *http.Request) {
+func (s *MetricsSocket) readinessHandler(w http.ResponseWriter, r *http.Request) {
 	ready := s.status.IsReady()
 	var err error
 	if ready {
@@ -72,27 +87,28 @@ func (s MetricsSocket) readinessHandler(w http.ResponseWriter, r *http.Request)
 
 // healthzHandler checks if the server is live AND ready. It writes 200/OK if
 // both the healthy and the ready flags are set to "true" and 503/Service
-// Unavailable otherwise. It is provided to ensure compatibility with
-// ExternalDNS Webhook requirements:
+// Unavailable otherwise. The body contains the status in JSON format, including
+// the results of the Hetzner API health checks. It is provided to ensure
+// compatibility with ExternalDNS Webhook requirements:
 // https://github.com/kubernetes-sigs/external-dns/blob/master/docs/tutorials/webhook-provider.md
-func (s MetricsSocket) healthzHandler(w http.ResponseWriter, r *http.Request) {
-	healthz := s.status.IsHealthy() && s.status.IsReady()
-	var err error
-	if healthz {
-		_, err = w.Write([]byte(http.StatusText(http.StatusOK)))
-	} else {
+func (s *MetricsSocket) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	report := s.status.Report()
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy || !report.Ready {
 		w.WriteHeader(http.StatusServiceUnavailable)
-		_, err = w.Write([]byte(http.StatusText(http.StatusServiceUnavailable)))
 	}
-	if err != nil {
+	if err := json.NewEncoder(w).Encode(report); err != nil {
 		log.Warnf("Could not answer to a healthz probe: %s", err.Error())
 	}
 }
 
-// Start starts the exposed endpoints server.
-func (s *MetricsSocket) Start(startedChan chan struct{}, options SocketOptions) {
-	metrics := metrics.GetOpenMetricsInstance()
-	reg := metrics.GetRegistry()
+// Start starts the exposed endpoints server. It returns when the server is
+// shut down or fails.
+func (s *MetricsSocket) Start(startedChan chan struct{}, options SocketOptions) error {
+	reg := s.metrics.GetRegistry()
+	if reg == nil {
+		reg = prometheus.NewRegistry()
+	}
 	metricsFuncHandler := promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
 
 	mux := http.NewServeMux()
@@ -102,26 +118,42 @@ func (s *MetricsSocket) Start(startedChan chan struct{}, options SocketOptions)
 	mux.HandleFunc("/health", s.livenessHandler)
 	mux.HandleFunc("/healthz", s.healthzHandler)
 	mux.Handle("/metrics", metricsFuncHandler)
-
-	address := options.GetMetricsAddress()
-
-	srv := &http.Server{
-		Addr:         address,
-		Handler:      mux,
-		ReadTimeout:  options.GetReadTimeout(),
-		WriteTimeout: options.GetWriteTimeout(),
+	if options.AdminAPIEnabled {
+		adminMux := http.NewServeMux()
+		s.admin.register(adminMux)
+		var adminHandler http.Handler = adminMux
+		auth, err := newAuthenticator(options.WebhookAuthMode, options.WebhookAuthSecretFile, s.metrics)
+		if err != nil {
+			return fmt.Errorf("cannot configure the admin API authentication: %w", err)
+		}
+		if auth != nil {
+			// WEBHOOK_AUTH_ALLOW_LOCAL only applies to the webhook socket: the
+			// local clients must authenticate too.
+			auth.allowLocal = false
+			adminHandler = auth.wrap(adminHandler)
+		}
+		log.Warn("Admin API enabled on the metrics socket.")
+		mux.Handle("/admin/", adminHandler)
 	}
 
-	l, err := net.Listen("tcp", address)
+	certs, err := newSocketCerts(options.GetMetricsTLS(), options)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("cannot configure TLS for the metrics socket: %w", err)
 	}
-
-	if startedChan != nil {
-		startedChan <- struct{}{}
+	unixMode, err := options.GetUnixSocketMode()
+	if err != nil {
+		return err
 	}
 
-	if err := srv.Serve(l); err != nil {
-		log.Fatal(err)
+	srv := &http.Server{
+		Addr:         options.GetMetricsAddress(),
+		Handler:      mux,
+		ReadTimeout:  options.GetReadTimeout(),
+		WriteTimeout: options.GetWriteTimeout(),
+		TLSConfig:    certs.TLSConfig(),
 	}
+	ctx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	go certs.watch(ctx, certCheckInterval)
+	return s.serve(startedChan, srv, unixMode)
 }
@@ -17,10 +17,15 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/metrics"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -31,6 +36,31 @@ const (
 	testHost = "localhost"
 )
 
+// newTestMetrics returns a new metrics instance with the given namespace.
+func newTestMetrics(t *testing.T, namespace string) *metrics.OpenMetrics {
+	options := metrics.DefaultOptions()
+	options.Namespace = namespace
+	m, err := metrics.NewOpenMetrics(options)
+	assert.NoError(t, err)
+	return m
+}
+
+// metricSum returns the sum of the values of a counter or gauge.
+func metricSum(t *testing.T, m *metrics.OpenMetrics, name string) float64 {
+	families, err := m.GetRegistry().Gather()
+	assert.NoError(t, err)
+	sum := float64(0)
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			sum += metric.GetCounter().GetValue() + metric.GetGauge().GetValue()
+		}
+	}
+	return sum
+}
+
 func testHandlerArgs() (*httptest.ResponseRecorder, *http.Request) {
 	text := bytes.NewBuffer(make([]byte, 0))
 	w := &httptest.ResponseRecorder{Body: text}
@@ -156,6 +186,73 @@ func Test_MetricsSocket_readinessHandler(t *testing.T) {
 	}
 }
 
+func Test_MetricsSocket_healthzHandler(t *testing.T) {
+	type testCase struct {
+		name     string
+		instance *MetricsSocket
+		expected struct {
+			status int
+			text   string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		obj := tc.instance
+		exp := tc.expected
+		w := httptest.NewRecorder()
+		obj.healthzHandler(w, &http.Request{})
+		assert.Equal(t, exp.status, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, exp.text, w.Body.String())
+	}
+
+	testCases := []testCase{
+		{
+			name: "server is healthy and ready",
+			instance: &MetricsSocket{
+				status: &Status{
+					healthy: mutexedBool{v: true},
+					ready:   mutexedBool{v: true},
+				},
+			},
+			expected: struct {
+				status int
+				text   string
+			}{
+				status: http.StatusOK,
+				text:   `{"healthy":true,"ready":true,"consecutiveFailures":0}`,
+			},
+		},
+		{
+			name: "server is not ready",
+			instance: &MetricsSocket{
+				status: &Status{
+					healthy: mutexedBool{v: true},
+					checks: checkResults{
+						lastError:     "test error",
+						lastErrorTime: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+						failures:      3,
+					},
+				},
+			},
+			expected: struct {
+				status int
+				text   string
+			}{
+				status: http.StatusServiceUnavailable,
+				text: `{"healthy":true,"ready":false,"lastError":"test error",` +
+					`"lastErrorTime":"2026-01-01T10:00:00Z","consecutiveFailures":3}`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
 func Test_Start(t *testing.T) {
 	status := &Status{
 		healthy: mutexedBool{v: true},
@@ -181,4 +278,38 @@ func Test_Start(t *testing.T) {
 
 	assert.Nil(t, err)
 	assert.Equal(t, res.StatusCode, http.StatusOK)
+
+	err = metricsSocket.Shutdown(context.Background())
+	assert.Nil(t, err)
+
+	_, err = http.Get(url)
+	assert.NotNil(t, err)
+}
+
+func Test_MetricsSocket_metrics(t *testing.T) {
+	options := SocketOptions{
+		MetricsHost: testHost,
+		MetricsPort: testPort,
+	}
+	m := newTestMetrics(t, "webhook")
+	m.IncAuthRejectedTotal("missing")
+	metricsSocket := NewMetricsSocket(&Status{}, m)
+	startedChan := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- metricsSocket.Start(startedChan, options)
+	}()
+	<-startedChan
+	defer func() {
+		assert.Nil(t, metricsSocket.Shutdown(context.Background()))
+		assert.Nil(t, <-done)
+	}()
+
+	res, err := http.Get(fmt.Sprintf("http://%s:%d/metrics", testHost, testPort))
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	assert.Contains(t, string(body), `webhook_webhook_auth_rejected_total{reason="missing"} 1`)
+	assert.Contains(t, string(body), "go_goroutines")
+	assert.Contains(t, string(body), "process_")
 }
@@ -18,8 +18,13 @@
 package server
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/codingconcepts/env"
@@ -31,16 +36,21 @@ const (
 	envDeprecatedMetricsHost = "HEALTH_HOST"
 	// Deprecated socket environment variable for metrics port
 	envDeprecatedMetricsPort = "HEALTH_PORT"
+	// Prefix of the unix domain socket addresses
+	unixPrefix = "unix://"
+	// Default file permissions of the unix domain sockets
+	defaultUnixSocketMode os.FileMode = 0o660
 )
 
 // SocketOptions contains the argument passed as environment variables that
 // influence the socket configuration.
 type SocketOptions struct {
-	// Webhook host
+	// Webhook host, or unix:///path/to/socket for a unix domain socket
 	WebhookHost string `env:"WEBHOOK_HOST" default:"localhost"`
 	// Webhook port
 	WebhookPort uint16 `env:"WEBHOOK_PORT" default:"8888"`
-	// Readiness and liveness probe host
+	// Readiness and liveness probe host, or unix:///path/to/socket for a unix
+	// domain socket
 	MetricsHost string `env:"METRICS_HOST" default:"0.0.0.0"`
 	// Readiness and liveness probe port
 	MetricsPort uint16 `env:"METRICS_PORT" default:"8080"`
@@ -48,6 +58,44 @@ type SocketOptions struct {
 	ReadTimeout int `env:"READ_TIMEOUT" default:"60000"`
 	// Write timeout in milliseconds
 	WriteTimeout int `env:"WRITE_TIMEOUT" default:"60000"`
+	// Interval between the Hetzner API health checks in seconds. A negative or
+	// 0 value disables the checks.
+	HealthCheckInterval int `env:"HEALTH_CHECK_INTERVAL" default:"0"`
+	// Consecutive failed health checks before the webhook is not ready. A
+	// negative or 0 value never changes the readiness.
+	HealthCheckFailures int `env:"HEALTH_CHECK_FAILURES" default:"3"`
+	// Maximum time in milliseconds for draining the in-flight requests when
+	// shutting down.
+	ShutdownTimeout int `env:"SHUTDOWN_TIMEOUT" default:"30000"`
+	// Webhook TLS certificate file. TLS is enabled if both certificate and key
+	// are provided.
+	WebhookTLSCert string `env:"WEBHOOK_TLS_CERT" default:""`
+	// Webhook TLS key file
+	WebhookTLSKey string `env:"WEBHOOK_TLS_KEY" default:""`
+	// CA certificates file for verifying the webhook clients (mutual TLS)
+	WebhookTLSClientCA string `env:"WEBHOOK_TLS_CLIENT_CA" default:""`
+	// Metrics TLS certificate file. TLS is enabled if both certificate and key
+	// are provided.
+	MetricsTLSCert string `env:"METRICS_TLS_CERT" default:""`
+	// Metrics TLS key file
+	MetricsTLSKey string `env:"METRICS_TLS_KEY" default:""`
+	// CA certificates file for verifying the metrics clients (mutual TLS)
+	MetricsTLSClientCA string `env:"METRICS_TLS_CLIENT_CA" default:""`
+	// Minimum TLS version: 1.2 or 1.3
+	TLSMinVersion string `env:"TLS_MIN_VERSION" default:"1.2"`
+	// File permissions of the unix domain sockets, in octal notation
+	UnixSocketMode string `env:"UNIX_SOCKET_MODE" default:"0660"`
+	// Authentication of the webhook requests: bearer, hmac or empty to disable
+	WebhookAuthMode string `env:"WEBHOOK_AUTH_MODE" default:""`
+	// File containing the token or the HMAC key for the webhook authentication
+	WebhookAuthSecretFile string `env:"WEBHOOK_AUTH_SECRET_FILE" default:""`
+	// Accept the webhook requests of loopback and unix domain socket clients
+	// without authentication
+	WebhookAuthAllowLocal bool `env:"WEBHOOK_AUTH_ALLOW_LOCAL" default:"false"`
+	// Enable the read-only admin API on the metrics socket. It requires the
+	// webhook authentication, unless only local clients or clients with a
+	// certificate can reach the metrics socket.
+	AdminAPIEnabled bool `env:"ADMIN_API_ENABLED" default:"false"`
 }
 
 // NewSocketOptions returns a pointer to a new SocketOptions instance. This
@@ -78,14 +126,35 @@ func NewSocketOptions() (*SocketOptions, error) {
 	return opt, nil
 }
 
+// socketAddress returns the socket address for the host and port. Unix domain
+// socket addresses are returned unchanged, as the port is not used.
+func socketAddress(host string, port uint16) string {
+	if strings.HasPrefix(host, unixPrefix) {
+		return host
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
 // GetWebhookAddress returns the webhook socket address.
 func (o SocketOptions) GetWebhookAddress() string {
-	return fmt.Sprintf("%s:%d", o.WebhookHost, o.WebhookPort)
+	return socketAddress(o.WebhookHost, o.WebhookPort)
 }
 
 // GetHealthAddress returns the metrics socket address.
 func (o SocketOptions) GetMetricsAddress() string {
-	return fmt.Sprintf("%s:%d", o.MetricsHost, o.MetricsPort)
+	return socketAddress(o.MetricsHost, o.MetricsPort)
+}
+
+// GetUnixSocketMode returns the file permissions of the unix domain sockets.
+func (o SocketOptions) GetUnixSocketMode() (os.FileMode, error) {
+	if o.UnixSocketMode == "" {
+		return defaultUnixSocketMode, nil
+	}
+	mode, err := strconv.ParseUint(o.UnixSocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid unix socket mode %s", o.UnixSocketMode)
+	}
+	return os.FileMode(mode), nil
 }
 
 // GetReadTimeout returns the read timeout in milliseconds.
@@ -97,3 +166,95 @@ func (o SocketOptions) GetReadTimeout() time.Duration {
 func (o SocketOptions) GetWriteTimeout() time.Duration {
 	return time.Duration(o.WriteTimeout) * time.Millisecond
 }
+
+// GetHealthCheckInterval returns the interval between the health checks.
+func (o SocketOptions) GetHealthCheckInterval() time.Duration {
+	return time.Duration(o.HealthCheckInterval) * time.Second
+}
+
+// GetShutdownTimeout returns the maximum time for draining the requests.
+func (o SocketOptions) GetShutdownTimeout() time.Duration {
+	return time.Duration(o.ShutdownTimeout) * time.Millisecond
+}
+
+// GetWebhookTLS returns the TLS files for the webhook socket.
+func (o SocketOptions) GetWebhookTLS() TLSFiles {
+	return TLSFiles{
+		Cert:     o.WebhookTLSCert,
+		Key:      o.WebhookTLSKey,
+		ClientCA: o.WebhookTLSClientCA,
+	}
+}
+
+// GetMetricsTLS returns the TLS files for the metrics socket.
+func (o SocketOptions) GetMetricsTLS() TLSFiles {
+	return TLSFiles{
+		Cert:     o.MetricsTLSCert,
+		Key:      o.MetricsTLSKey,
+		ClientCA: o.MetricsTLSClientCA,
+	}
+}
+
+// GetTLSMinVersion returns the minimum TLS version.
+func (o SocketOptions) GetTLSMinVersion() (uint16, error) {
+	switch o.TLSMinVersion {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %s", o.TLSMinVersion)
+	}
+}
+
+// isLoopback returns true if the host only accepts local connections.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// metricsRestricted returns true if only local clients or clients with a
+// certificate can reach the metrics socket.
+func (o SocketOptions) metricsRestricted() bool {
+	if strings.HasPrefix(o.MetricsHost, unixPrefix) || isLoopback(o.MetricsHost) {
+		return true
+	}
+	metricsTLS := o.GetMetricsTLS()
+	return metricsTLS.Enabled() && metricsTLS.ClientCA != ""
+}
+
+// Validate checks the options and returns all the problems found. The TLS and
+// secret files are not read.
+func (o SocketOptions) Validate() error {
+	errs := make([]error, 0)
+	if o.GetWebhookAddress() == o.GetMetricsAddress() {
+		errs = append(errs, fmt.Errorf("webhook and metrics sockets cannot share the address %s", o.GetWebhookAddress()))
+	}
+	if webhookTLS := o.GetWebhookTLS(); webhookTLS.Enabled() {
+		if err := webhookTLS.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if metricsTLS := o.GetMetricsTLS(); metricsTLS.Enabled() {
+		if err := metricsTLS.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("metrics: %w", err))
+		}
+	}
+	if _, err := o.GetTLSMinVersion(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := o.GetUnixSocketMode(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateAuth(o.WebhookAuthMode, o.WebhookAuthSecretFile); err != nil {
+		errs = append(errs, err)
+	}
+	if o.AdminAPIEnabled && o.WebhookAuthMode == "" && !o.metricsRestricted() {
+		errs = append(errs, errors.New("the admin API requires the webhook authentication, "+
+			"a metrics socket on a loopback address or a unix domain socket, or mutual TLS on the metrics socket"))
+	}
+	return errors.Join(errs...)
+}
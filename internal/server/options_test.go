@@ -16,6 +16,9 @@
 package server
 
 import (
+	"crypto/tls"
+	"errors"
+	"os"
 	"testing"
 	"time"
 
@@ -52,6 +55,14 @@ func Test_SocketOptions_GetWebhookAddress(t *testing.T) {
 			},
 			expected: "localhost:8888",
 		},
+		{
+			name: "webhook address with unix socket",
+			options: SocketOptions{
+				WebhookHost: "unix:///var/run/webhook.sock",
+				WebhookPort: 8888,
+			},
+			expected: "unix:///var/run/webhook.sock",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -91,6 +102,14 @@ func Test_SocketOptions_GetMetricsAddress(t *testing.T) {
 			},
 			expected: "broadcast:8080",
 		},
+		{
+			name: "metrics address with unix socket",
+			options: SocketOptions{
+				MetricsHost: "unix:///var/run/metrics.sock",
+				MetricsPort: 8080,
+			},
+			expected: "unix:///var/run/metrics.sock",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -114,3 +133,293 @@ func Test_SocketOptions_timeouts(t *testing.T) {
 	assert.Equal(t, r, testReadTimeout)
 	assert.Equal(t, w, testWriteTimeout)
 }
+
+func Test_SocketOptions_GetHealthCheckInterval(t *testing.T) {
+	s := SocketOptions{HealthCheckInterval: 30}
+
+	assert.Equal(t, 30*time.Second, s.GetHealthCheckInterval())
+}
+
+func Test_SocketOptions_GetShutdownTimeout(t *testing.T) {
+	s := SocketOptions{ShutdownTimeout: 5000}
+
+	assert.Equal(t, 5*time.Second, s.GetShutdownTimeout())
+}
+
+func Test_SocketOptions_GetTLS(t *testing.T) {
+	s := SocketOptions{
+		WebhookTLSCert:     "webhook.crt",
+		WebhookTLSKey:      "webhook.key",
+		MetricsTLSCert:     "metrics.crt",
+		MetricsTLSKey:      "metrics.key",
+		MetricsTLSClientCA: "ca.crt",
+	}
+
+	assert.Equal(t, TLSFiles{Cert: "webhook.crt", Key: "webhook.key"}, s.GetWebhookTLS())
+	assert.Equal(t, TLSFiles{Cert: "metrics.crt", Key: "metrics.key", ClientCA: "ca.crt"}, s.GetMetricsTLS())
+}
+
+func Test_SocketOptions_GetTLSMinVersion(t *testing.T) {
+	type testCase struct {
+		name     string
+		version  string
+		expected struct {
+			version uint16
+			err     error
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		s := SocketOptions{TLSMinVersion: tc.version}
+		actual, err := s.GetTLSMinVersion()
+		assert.Equal(t, exp.version, actual)
+		assert.Equal(t, exp.err, err)
+	}
+
+	testCases := []testCase{
+		{
+			name:    "default",
+			version: "",
+			expected: struct {
+				version uint16
+				err     error
+			}{
+				version: tls.VersionTLS12,
+			},
+		},
+		{
+			name:    "TLS 1.1",
+			version: "1.1",
+			expected: struct {
+				version uint16
+				err     error
+			}{
+				err: errors.New("unsupported TLS version 1.1"),
+			},
+		},
+		{
+			name:    "TLS 1.3",
+			version: "1.3",
+			expected: struct {
+				version uint16
+				err     error
+			}{
+				version: tls.VersionTLS13,
+			},
+		},
+		{
+			name:    "unsupported",
+			version: "2.0",
+			expected: struct {
+				version uint16
+				err     error
+			}{
+				err: errors.New("unsupported TLS version 2.0"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_SocketOptions_GetUnixSocketMode(t *testing.T) {
+	type testCase struct {
+		name     string
+		mode     string
+		expected struct {
+			mode os.FileMode
+			err  error
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		s := SocketOptions{UnixSocketMode: tc.mode}
+		actual, err := s.GetUnixSocketMode()
+		assert.Equal(t, exp.mode, actual)
+		assert.Equal(t, exp.err, err)
+	}
+
+	testCases := []testCase{
+		{
+			name: "default",
+			mode: "",
+			expected: struct {
+				mode os.FileMode
+				err  error
+			}{
+				mode: 0o660,
+			},
+		},
+		{
+			name: "custom mode",
+			mode: "0600",
+			expected: struct {
+				mode os.FileMode
+				err  error
+			}{
+				mode: 0o600,
+			},
+		},
+		{
+			name: "not octal",
+			mode: "0698",
+			expected: struct {
+				mode os.FileMode
+				err  error
+			}{
+				err: errors.New("invalid unix socket mode 0698"),
+			},
+		},
+		{
+			name: "too large",
+			mode: "7777",
+			expected: struct {
+				mode os.FileMode
+				err  error
+			}{
+				err: errors.New("invalid unix socket mode 7777"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_SocketOptions_Validate(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    SocketOptions
+		expected string
+	}
+
+	valid := SocketOptions{
+		WebhookHost: "localhost",
+		WebhookPort: 8888,
+		MetricsHost: "0.0.0.0",
+		MetricsPort: 8080,
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		err := tc.input.Validate()
+		if tc.expected == "" {
+			assert.Nil(t, err)
+			return
+		}
+		assert.EqualError(t, err, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "valid",
+			input: valid,
+		},
+		{
+			name: "valid TLS and authentication",
+			input: SocketOptions{
+				WebhookHost:           "unix:///run/webhook.sock",
+				MetricsHost:           "0.0.0.0",
+				MetricsPort:           8080,
+				WebhookTLSCert:        "tls.crt",
+				WebhookTLSKey:         "tls.key",
+				TLSMinVersion:         "1.3",
+				UnixSocketMode:        "0600",
+				WebhookAuthMode:       "hmac",
+				WebhookAuthSecretFile: "secret",
+			},
+		},
+		{
+			name: "admin API on a loopback address",
+			input: SocketOptions{
+				WebhookHost:     "localhost",
+				WebhookPort:     8888,
+				MetricsHost:     "127.0.0.1",
+				MetricsPort:     8080,
+				AdminAPIEnabled: true,
+			},
+		},
+		{
+			name: "admin API with mutual TLS",
+			input: SocketOptions{
+				WebhookHost:        "localhost",
+				WebhookPort:        8888,
+				MetricsHost:        "0.0.0.0",
+				MetricsPort:        8080,
+				MetricsTLSCert:     "tls.crt",
+				MetricsTLSKey:      "tls.key",
+				MetricsTLSClientCA: "ca.crt",
+				AdminAPIEnabled:    true,
+			},
+		},
+		{
+			name: "admin API with authentication",
+			input: SocketOptions{
+				WebhookHost:           "localhost",
+				WebhookPort:           8888,
+				MetricsHost:           "0.0.0.0",
+				MetricsPort:           8080,
+				WebhookAuthMode:       "bearer",
+				WebhookAuthSecretFile: "secret",
+				AdminAPIEnabled:       true,
+			},
+		},
+		{
+			name: "admin API without protection",
+			input: SocketOptions{
+				WebhookHost:     "localhost",
+				WebhookPort:     8888,
+				MetricsHost:     "0.0.0.0",
+				MetricsPort:     8080,
+				MetricsTLSCert:  "tls.crt",
+				MetricsTLSKey:   "tls.key",
+				AdminAPIEnabled: true,
+			},
+			expected: "the admin API requires the webhook authentication, a metrics socket on a loopback address " +
+				"or a unix domain socket, or mutual TLS on the metrics socket",
+		},
+		{
+			name: "shared address",
+			input: SocketOptions{
+				WebhookHost: "localhost",
+				WebhookPort: 8080,
+				MetricsHost: "localhost",
+				MetricsPort: 8080,
+			},
+			expected: "webhook and metrics sockets cannot share the address localhost:8080",
+		},
+		{
+			name: "all errors",
+			input: SocketOptions{
+				WebhookHost:     "localhost",
+				WebhookPort:     8888,
+				MetricsHost:     "0.0.0.0",
+				MetricsPort:     8080,
+				WebhookTLSCert:  "tls.crt",
+				MetricsTLSKey:   "tls.key",
+				TLSMinVersion:   "2.0",
+				UnixSocketMode:  "0999",
+				WebhookAuthMode: "bearer",
+			},
+			expected: "webhook: both TLS certificate and key must be provided\n" +
+				"metrics: both TLS certificate and key must be provided\n" +
+				"unsupported TLS version 2.0\n" +
+				"invalid unix socket mode 0999\n" +
+				"a secret file is required for authentication",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
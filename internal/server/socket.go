@@ -0,0 +1,110 @@
+/*
+ * Socket - lifecycle of the HTTP servers.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// socket manages the lifecycle of an HTTP server, so that it can be shut down
+// gracefully.
+type socket struct {
+	m      sync.Mutex
+	srv    *http.Server
+	closed bool
+}
+
+// listen creates the listener for the address. Addresses starting with unix://
+// are unix domain sockets: a stale socket file is removed before listening and
+// the permissions are set to mode.
+func listen(address string, mode os.FileMode) (net.Listener, error) {
+	path, isUnix := strings.CutPrefix(address, unixPrefix)
+	if !isUnix {
+		return net.Listen("tcp", address)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		log.Debugf("Removing stale socket %s", path)
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// serve listens on the server address and serves the requests until the
+// server is shut down. If the server has a TLS configuration, the connections
+// use TLS. It returns nil after a shutdown and an error if the
+// server cannot listen or fails.
+func (s *socket) serve(startedChan chan struct{}, srv *http.Server, unixMode os.FileMode) error {
+	s.m.Lock()
+	if s.closed {
+		s.m.Unlock()
+		log.Debugf("Socket %s already shut down.", srv.Addr)
+		return nil
+	}
+	s.srv = srv
+	s.m.Unlock()
+
+	l, err := listen(srv.Addr, unixMode)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %w", srv.Addr, err)
+	}
+	if srv.TLSConfig != nil {
+		l = tls.NewListener(l, srv.TLSConfig)
+	}
+
+	if startedChan != nil {
+		startedChan <- struct{}{}
+	}
+
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error while serving on %s: %w", srv.Addr, err)
+	}
+	return nil
+}
+
+// Shutdown stops accepting new connections and waits for the in-flight
+// requests to complete, until the context expires.
+func (s *socket) Shutdown(ctx context.Context) error {
+	s.m.Lock()
+	srv := s.srv
+	s.closed = true
+	s.m.Unlock()
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
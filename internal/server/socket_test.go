@@ -0,0 +1,130 @@
+/*
+ * Socket - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSocketPort is the test port for the generic socket on localhost.
+const testSocketPort = testPort + 2
+
+func Test_socket_serve(t *testing.T) {
+	address := fmt.Sprintf("%s:%d", testHost, testSocketPort)
+
+	t.Run("shut down before start", func(t *testing.T) {
+		s := &socket{}
+		assert.Nil(t, s.Shutdown(context.Background()))
+		err := s.serve(nil, &http.Server{Addr: address}, 0)
+		assert.Nil(t, err)
+	})
+
+	t.Run("address in use", func(t *testing.T) {
+		l, err := net.Listen("tcp", address)
+		assert.Nil(t, err)
+		defer l.Close()
+		s := &socket{}
+		err = s.serve(nil, &http.Server{Addr: address}, 0)
+		assert.ErrorContains(t, err, "cannot listen on "+address)
+	})
+
+	t.Run("served until shut down", func(t *testing.T) {
+		s := &socket{}
+		startedChan := make(chan struct{})
+		result := make(chan error)
+		go func() {
+			result <- s.serve(startedChan, &http.Server{Addr: address, Handler: http.NotFoundHandler()}, 0)
+		}()
+		<-startedChan
+		assert.Nil(t, s.Shutdown(context.Background()))
+		assert.Nil(t, <-result)
+	})
+}
+
+func Test_listen_unix(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("stale socket removed", func(t *testing.T) {
+		path := filepath.Join(dir, "stale.sock")
+		stale, err := net.Listen("unix", path)
+		assert.Nil(t, err)
+		// Keep the file, as it happens when the process is killed.
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		l, err := listen(unixPrefix+path, 0o600)
+		assert.Nil(t, err)
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		l.Close()
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("regular file not removed", func(t *testing.T) {
+		path := filepath.Join(dir, "regular")
+		assert.Nil(t, os.WriteFile(path, []byte{}, 0o600))
+		_, err := listen(unixPrefix+path, 0o600)
+		assert.NotNil(t, err)
+		assert.FileExists(t, path)
+	})
+}
+
+func Test_MetricsSocket_unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.sock")
+	options := SocketOptions{
+		MetricsHost:    unixPrefix + path,
+		UnixSocketMode: "0600",
+	}
+	status := &Status{ready: mutexedBool{v: true}}
+	metricsSocket := NewMetricsSocket(status, nil)
+	startedChan := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		result <- metricsSocket.Start(startedChan, options)
+	}()
+	<-startedChan
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	res, err := client.Get("http://unix/ready")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	assert.Nil(t, metricsSocket.Shutdown(context.Background()))
+	assert.Nil(t, <-result)
+	assert.NoFileExists(t, path)
+}
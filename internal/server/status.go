@@ -17,10 +17,50 @@
  */
 package server
 
-// Status contains the health and ready statuses for the webhook.
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Degradable is implemented by the components that can enter a degraded
+// state, after which the webhook must be shut down. The channel receives the
+// reason of the degradation.
+type Degradable interface {
+	Degraded() <-chan error
+}
+
+// Drainable is implemented by the components that must complete their
+// running operations before the webhook exits.
+type Drainable interface {
+	Drain(ctx context.Context) error
+}
+
+// Status contains the health and ready statuses for the webhook, together with
+// the results of the Hetzner API health checks.
 type Status struct {
 	healthy mutexedBool
 	ready   mutexedBool
+	checks  checkResults
+}
+
+// checkResults contains the results of the health checks.
+type checkResults struct {
+	m             sync.Mutex
+	lastSuccess   time.Time
+	lastError     string
+	lastErrorTime time.Time
+	failures      int
+}
+
+// StatusReport is the JSON representation of the status.
+type StatusReport struct {
+	Healthy             bool       `json:"healthy"`
+	Ready               bool       `json:"ready"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorTime       *time.Time `json:"lastErrorTime,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
 }
 
 // SetHealthy sets the health status.
@@ -42,3 +82,42 @@ func (s *Status) IsHealthy() bool {
 func (s *Status) IsReady() bool {
 	return s.ready.Get()
 }
+
+// recordCheck stores the result of a health check and returns the number of
+// consecutive failures.
+func (s *Status) recordCheck(err error, t time.Time) int {
+	c := &s.checks
+	c.m.Lock()
+	defer c.m.Unlock()
+	if err == nil {
+		c.lastSuccess = t
+		c.failures = 0
+	} else {
+		c.lastError = err.Error()
+		c.lastErrorTime = t
+		c.failures++
+	}
+	return c.failures
+}
+
+// Report returns the current status.
+func (s *Status) Report() StatusReport {
+	c := &s.checks
+	c.m.Lock()
+	defer c.m.Unlock()
+	r := StatusReport{
+		Healthy:             s.IsHealthy(),
+		Ready:               s.IsReady(),
+		LastError:           c.lastError,
+		ConsecutiveFailures: c.failures,
+	}
+	if !c.lastSuccess.IsZero() {
+		t := c.lastSuccess
+		r.LastSuccess = &t
+	}
+	if !c.lastErrorTime.IsZero() {
+		t := c.lastErrorTime
+		r.LastErrorTime = &t
+	}
+	return r
+}
@@ -16,7 +16,9 @@
 package server
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -174,3 +176,26 @@ func Test_Status_IsReady(t *testing.T) {
 		})
 	}
 }
+
+func Test_Status_Report(t *testing.T) {
+	okTime := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	errTime := okTime.Add(time.Minute)
+	s := &Status{
+		healthy: mutexedBool{v: true},
+		ready:   mutexedBool{v: true},
+	}
+
+	assert.Equal(t, StatusReport{Healthy: true, Ready: true}, s.Report())
+
+	assert.Equal(t, 0, s.recordCheck(nil, okTime))
+	assert.Equal(t, 1, s.recordCheck(errors.New("test error"), errTime))
+	assert.Equal(t, 2, s.recordCheck(errors.New("test error"), errTime))
+	assert.Equal(t, StatusReport{
+		Healthy:             true,
+		Ready:               true,
+		LastSuccess:         &okTime,
+		LastError:           "test error",
+		LastErrorTime:       &errTime,
+		ConsecutiveFailures: 2,
+	}, s.Report())
+}
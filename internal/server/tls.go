@@ -0,0 +1,225 @@
+/*
+ * TLS - TLS configuration with certificate reload.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// certCheckInterval is the interval between the checks of the certificate
+// files.
+const certCheckInterval = 10 * time.Second
+
+// TLSFiles contains the files used for the TLS configuration of a socket.
+type TLSFiles struct {
+	// Certificate file
+	Cert string
+	// Key file
+	Key string
+	// Client CA certificates file, enables the mutual TLS
+	ClientCA string
+}
+
+// Enabled returns true if TLS is configured.
+func (f TLSFiles) Enabled() bool {
+	return f.Cert != "" || f.Key != "" || f.ClientCA != ""
+}
+
+// validate checks that the configuration is complete.
+func (f TLSFiles) validate() error {
+	if f.Cert == "" || f.Key == "" {
+		return errors.New("both TLS certificate and key must be provided")
+	}
+	return nil
+}
+
+// certReloader keeps the certificates loaded from the files and reloads them
+// when the files change on disk, so that rotated certificates are picked up
+// without restarting the webhook. The files are checked periodically by
+// watch, not on every handshake.
+type certReloader struct {
+	files      TLSFiles
+	minVersion uint16
+	m          sync.Mutex
+	modTimes   []time.Time
+	config     *tls.Config
+}
+
+// newCertReloader creates a new certReloader, loading the certificates.
+func newCertReloader(files TLSFiles, minVersion uint16) (*certReloader, error) {
+	if err := files.validate(); err != nil {
+		return nil, err
+	}
+	r := &certReloader{
+		files:      files,
+		minVersion: minVersion,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// paths returns the files to be watched.
+func (r *certReloader) paths() []string {
+	paths := []string{r.files.Cert, r.files.Key}
+	if r.files.ClientCA != "" {
+		paths = append(paths, r.files.ClientCA)
+	}
+	return paths
+}
+
+// readModTimes returns the modification times of the watched files.
+func (r *certReloader) readModTimes() ([]time.Time, error) {
+	paths := r.paths()
+	modTimes := make([]time.Time, len(paths))
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// changed returns true if the modification times differ.
+func changed(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads the certificates and builds the TLS configuration.
+func (r *certReloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.files.Cert, r.files.Key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   r.minVersion,
+	}
+	if r.files.ClientCA != "" {
+		pem, err := os.ReadFile(r.files.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in client CA %s", r.files.ClientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// reload loads the certificates again if any of the files changed. If the new
+// certificates cannot be loaded, the previous ones are kept.
+func (r *certReloader) reload() error {
+	modTimes, err := r.readModTimes()
+	if err != nil {
+		return fmt.Errorf("cannot read TLS files: %w", err)
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.config != nil && !changed(r.modTimes, modTimes) {
+		return nil
+	}
+	config, err := r.load()
+	if err != nil {
+		return err
+	}
+	if r.config != nil {
+		log.Infof("TLS certificates reloaded from %s.", r.files.Cert)
+	}
+	r.config = config
+	r.modTimes = modTimes
+	return nil
+}
+
+// watch checks the files for changes every interval until the context is
+// done. A reload error is reported only once until the files can be loaded
+// again.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.reload()
+			if err != nil && !failed {
+				log.Warnf("Cannot reload TLS certificates, keeping the current ones: %v", err)
+			}
+			failed = err != nil
+		}
+	}
+}
+
+// getConfigForClient returns the current TLS configuration.
+func (r *certReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.config, nil
+}
+
+// TLSConfig returns the server TLS configuration, or nil if r is nil.
+func (r *certReloader) TLSConfig() *tls.Config {
+	if r == nil {
+		return nil
+	}
+	return &tls.Config{
+		MinVersion:         r.minVersion,
+		GetConfigForClient: r.getConfigForClient,
+	}
+}
+
+// newSocketCerts returns the certificates of a socket, or nil if TLS is not
+// enabled.
+func newSocketCerts(files TLSFiles, options SocketOptions) (*certReloader, error) {
+	if !files.Enabled() {
+		return nil, nil
+	}
+	minVersion, err := options.GetTLSMinVersion()
+	if err != nil {
+		return nil, err
+	}
+	return newCertReloader(files, minVersion)
+}
@@ -0,0 +1,326 @@
+/*
+ * TLS - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTLSPort is the test port for the TLS server on localhost.
+const testTLSPort = testPort + 3
+
+// testCert is a certificate generated for the tests.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert creates a certificate signed by the parent, or a self-signed CA
+// if parent is nil.
+func newTestCert(t *testing.T, cn string, serial int64, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{testHost},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeTestCert writes the certificate and the key, setting the modification
+// time.
+func writeTestCert(t *testing.T, c *testCert, certFile, keyFile string, modTime time.Time) {
+	assert.Nil(t, os.WriteFile(certFile, c.certPEM, 0o600))
+	assert.Nil(t, os.WriteFile(keyFile, c.keyPEM, 0o600))
+	assert.Nil(t, os.Chtimes(certFile, modTime, modTime))
+	assert.Nil(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func Test_TLSFiles_Enabled(t *testing.T) {
+	assert.False(t, TLSFiles{}.Enabled())
+	assert.True(t, TLSFiles{Cert: "cert.pem", Key: "key.pem"}.Enabled())
+	assert.True(t, TLSFiles{ClientCA: "ca.pem"}.Enabled())
+}
+
+func Test_newCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	ca := newTestCert(t, "test CA", 1, nil)
+	writeTestCert(t, newTestCert(t, "server", 2, ca), certFile, keyFile, time.Now())
+	assert.Nil(t, os.WriteFile(caFile, ca.certPEM, 0o600))
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	assert.Nil(t, os.WriteFile(invalidFile, []byte("invalid"), 0o600))
+
+	type testCase struct {
+		name     string
+		files    TLSFiles
+		expected struct {
+			clientAuth tls.ClientAuthType
+			err        string
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		r, err := newCertReloader(tc.files, tls.VersionTLS12)
+		if exp.err != "" {
+			assert.ErrorContains(t, err, exp.err)
+			return
+		}
+		assert.Nil(t, err)
+		config, err := r.getConfigForClient(nil)
+		assert.Nil(t, err)
+		assert.Len(t, config.Certificates, 1)
+		assert.Equal(t, exp.clientAuth, config.ClientAuth)
+		assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "server TLS",
+			files: TLSFiles{Cert: certFile, Key: keyFile},
+		},
+		{
+			name:  "mutual TLS",
+			files: TLSFiles{Cert: certFile, Key: keyFile, ClientCA: caFile},
+			expected: struct {
+				clientAuth tls.ClientAuthType
+				err        string
+			}{
+				clientAuth: tls.RequireAndVerifyClientCert,
+			},
+		},
+		{
+			name:  "missing key",
+			files: TLSFiles{Cert: certFile},
+			expected: struct {
+				clientAuth tls.ClientAuthType
+				err        string
+			}{
+				err: "both TLS certificate and key must be provided",
+			},
+		},
+		{
+			name:  "missing file",
+			files: TLSFiles{Cert: certFile, Key: filepath.Join(dir, "missing.key")},
+			expected: struct {
+				clientAuth tls.ClientAuthType
+				err        string
+			}{
+				err: "cannot read TLS files",
+			},
+		},
+		{
+			name:  "invalid certificate",
+			files: TLSFiles{Cert: invalidFile, Key: keyFile},
+			expected: struct {
+				clientAuth tls.ClientAuthType
+				err        string
+			}{
+				err: "cannot load TLS certificate",
+			},
+		},
+		{
+			name:  "invalid client CA",
+			files: TLSFiles{Cert: certFile, Key: keyFile, ClientCA: invalidFile},
+			expected: struct {
+				clientAuth tls.ClientAuthType
+				err        string
+			}{
+				err: "no valid certificates found in client CA",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_certReloader_reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	ca := newTestCert(t, "test CA", 1, nil)
+	first := newTestCert(t, "first", 2, ca)
+	second := newTestCert(t, "second", 3, ca)
+	now := time.Now()
+	writeTestCert(t, first, certFile, keyFile, now.Add(-time.Minute))
+
+	r, err := newCertReloader(TLSFiles{Cert: certFile, Key: keyFile}, tls.VersionTLS12)
+	assert.Nil(t, err)
+	config, _ := r.getConfigForClient(nil)
+	assert.Equal(t, first.cert.Raw, config.Certificates[0].Certificate[0])
+
+	// A broken rotation keeps the current certificate.
+	assert.Nil(t, os.WriteFile(keyFile, []byte("invalid"), 0o600))
+	assert.NotNil(t, r.reload())
+	config, _ = r.getConfigForClient(nil)
+	assert.Equal(t, first.cert.Raw, config.Certificates[0].Certificate[0])
+
+	// The files are not checked on the handshakes.
+	writeTestCert(t, second, certFile, keyFile, now)
+	config, _ = r.getConfigForClient(nil)
+	assert.Equal(t, first.cert.Raw, config.Certificates[0].Certificate[0])
+	assert.Nil(t, r.reload())
+	config, _ = r.getConfigForClient(nil)
+	assert.Equal(t, second.cert.Raw, config.Certificates[0].Certificate[0])
+}
+
+func Test_certReloader_watch(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	ca := newTestCert(t, "test CA", 1, nil)
+	first := newTestCert(t, "first", 2, ca)
+	second := newTestCert(t, "second", 3, ca)
+	now := time.Now()
+	writeTestCert(t, first, certFile, keyFile, now.Add(-time.Minute))
+
+	r, err := newCertReloader(TLSFiles{Cert: certFile, Key: keyFile}, tls.VersionTLS12)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	writeTestCert(t, second, certFile, keyFile, now)
+	assert.Eventually(t, func() bool {
+		config, _ := r.getConfigForClient(nil)
+		return bytes.Equal(second.cert.Raw, config.Certificates[0].Certificate[0])
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	// A nil reloader has nothing to watch.
+	var none *certReloader
+	none.watch(ctx, time.Millisecond)
+	assert.Nil(t, none.TLSConfig())
+}
+
+func Test_MetricsSocket_mutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	ca := newTestCert(t, "test CA", 1, nil)
+	writeTestCert(t, newTestCert(t, "server", 2, ca), certFile, keyFile, time.Now())
+	assert.Nil(t, os.WriteFile(caFile, ca.certPEM, 0o600))
+	client := newTestCert(t, "client", 3, ca)
+	clientCert, err := tls.X509KeyPair(client.certPEM, client.keyPEM)
+	assert.Nil(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	options := SocketOptions{
+		MetricsHost:        testHost,
+		MetricsPort:        testTLSPort,
+		MetricsTLSCert:     certFile,
+		MetricsTLSKey:      keyFile,
+		MetricsTLSClientCA: caFile,
+		TLSMinVersion:      "1.3",
+	}
+	status := &Status{ready: mutexedBool{v: true}}
+	metricsSocket := NewMetricsSocket(status, nil)
+	startedChan := make(chan struct{})
+	go func() {
+		assert.Nil(t, metricsSocket.Start(startedChan, options))
+	}()
+	<-startedChan
+	defer func() {
+		assert.Nil(t, metricsSocket.Shutdown(context.Background()))
+	}()
+
+	url := fmt.Sprintf("https://%s:%d/ready", testHost, testTLSPort)
+	get := func(certs []tls.Certificate) error {
+		c := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+			},
+		}
+		res, err := c.Get(url)
+		if err == nil {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			res.Body.Close()
+		}
+		return err
+	}
+
+	assert.Nil(t, get([]tls.Certificate{clientCert}))
+	assert.NotNil(t, get(nil))
+}
+
+func Test_MetricsSocket_invalidTLS(t *testing.T) {
+	options := SocketOptions{
+		MetricsHost:    testHost,
+		MetricsPort:    testTLSPort,
+		MetricsTLSCert: "missing.crt",
+	}
+	err := NewMetricsSocket(&Status{}, nil).Start(nil, options)
+	assert.EqualError(t, err, "cannot configure TLS for the metrics socket: both TLS certificate and key must be provided")
+}
@@ -0,0 +1,120 @@
+/*
+ * Tracing - tracing of the webhook requests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"external-dns-hetzner-webhook/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/webhook/api"
+)
+
+// requestProvider calls the provider with the context of the webhook request,
+// so that the provider spans are children of the request span. The ExternalDNS
+// handlers call the provider with context.Background(). The cancellation of
+// the request is not propagated, to avoid interrupting the changes half way.
+type requestProvider struct {
+	provider.Provider
+	ctx context.Context
+}
+
+// newRequestProvider creates a requestProvider for the request.
+func newRequestProvider(p provider.Provider, r *http.Request) requestProvider {
+	return requestProvider{
+		Provider: p,
+		ctx:      context.WithoutCancel(r.Context()),
+	}
+}
+
+// Records returns the records using the request context.
+func (p requestProvider) Records(_ context.Context) ([]*endpoint.Endpoint, error) {
+	return p.Provider.Records(p.ctx)
+}
+
+// ApplyChanges applies the changes using the request context.
+func (p requestProvider) ApplyChanges(_ context.Context, changes *plan.Changes) error {
+	return p.Provider.ApplyChanges(p.ctx, changes)
+}
+
+// statusRecorder records the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it.
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body, recording the implicit 200 status code.
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// requestRoute returns the webhook route matching the path. The paths that do
+// not match an endpoint are served by the root route.
+func requestRoute(path string) string {
+	switch path {
+	case api.UrlRecords, api.UrlAdjustEndpoints:
+		return path
+	default:
+		return "/"
+	}
+}
+
+// traceRequests returns a handler that creates a span for every request. The
+// span continues the trace propagated by the client, if any. The span is named
+// after the route, to keep the number of span names bounded.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := requestRoute(r.URL.Path)
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.StartKind(ctx, trace.SpanKindServer, fmt.Sprintf("webhook %s %s", r.Method, route),
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(r.URL.Path))
+		defer span.End()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
@@ -0,0 +1,210 @@
+/*
+ * Tracing - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// useSpanRecorder records the spans until the end of the test.
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	bkpProvider := otel.GetTracerProvider()
+	bkpPropagator := otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(bkpProvider)
+		otel.SetTextMapPropagator(bkpPropagator)
+	})
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+}
+
+// contextProvider records the span of the context received by the provider.
+type contextProvider struct {
+	mockProvider
+	span *trace.SpanContext
+}
+
+// Records records the span and returns an empty list of endpoints.
+func (p contextProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	*p.span = trace.SpanContextFromContext(ctx)
+	return p.mockProvider.Records(ctx)
+}
+
+// ApplyChanges records the span and does nothing.
+func (p contextProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	*p.span = trace.SpanContextFromContext(ctx)
+	return p.mockProvider.ApplyChanges(ctx, changes)
+}
+
+func Test_traceRequests(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			path        string
+			status      int
+			traceparent string
+		}
+		expected struct {
+			name   string
+			status int
+			code   codes.Code
+		}
+	}
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	run := func(t *testing.T, tc testCase) {
+		inp := tc.input
+		exp := tc.expected
+		recorder := useSpanRecorder(t)
+		handler := traceRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.True(t, trace.SpanContextFromContext(r.Context()).IsValid())
+			if inp.status != 0 {
+				w.WriteHeader(inp.status)
+			}
+		}))
+		req := httptest.NewRequest(http.MethodGet, inp.path, nil)
+		if inp.traceparent != "" {
+			req.Header.Set("traceparent", inp.traceparent)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 1)
+		span := spans[0]
+		assert.Equal(t, exp.name, span.Name())
+		assert.Contains(t, span.Attributes(), semconv.URLPath(inp.path))
+		assert.Contains(t, span.Attributes(), semconv.HTTPRoute(exp.name[len("webhook GET "):]))
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(exp.status))
+		assert.Equal(t, exp.code, span.Status().Code)
+		if inp.traceparent != "" {
+			assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name: "implicit status",
+			input: struct {
+				path        string
+				status      int
+				traceparent string
+			}{path: "/records"},
+			expected: struct {
+				name   string
+				status int
+				code   codes.Code
+			}{"webhook GET /records", http.StatusOK, codes.Unset},
+		},
+		{
+			name: "server error",
+			input: struct {
+				path        string
+				status      int
+				traceparent string
+			}{path: "/records", status: http.StatusInternalServerError},
+			expected: struct {
+				name   string
+				status int
+				code   codes.Code
+			}{"webhook GET /records", http.StatusInternalServerError, codes.Error},
+		},
+		{
+			name: "propagated trace",
+			input: struct {
+				path        string
+				status      int
+				traceparent string
+			}{path: "/records", status: http.StatusNoContent, traceparent: "00-" + traceID + "-00f067aa0ba902b7-01"},
+			expected: struct {
+				name   string
+				status int
+				code   codes.Code
+			}{"webhook GET /records", http.StatusNoContent, codes.Unset},
+		},
+		{
+			name: "adjust endpoints",
+			input: struct {
+				path        string
+				status      int
+				traceparent string
+			}{path: "/adjustendpoints"},
+			expected: struct {
+				name   string
+				status int
+				code   codes.Code
+			}{"webhook GET /adjustendpoints", http.StatusOK, codes.Unset},
+		},
+		{
+			name: "unknown path",
+			input: struct {
+				path        string
+				status      int
+				traceparent string
+			}{path: "/unknown/a1b2c3", status: http.StatusNotFound},
+			expected: struct {
+				name   string
+				status int
+				code   codes.Code
+			}{"webhook GET /", http.StatusNotFound, codes.Unset},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_WebhookSocket_handler_context(t *testing.T) {
+	recorder := useSpanRecorder(t)
+	var span trace.SpanContext
+	webhookSocket := NewWebhookSocket(contextProvider{span: &span}, nil)
+	handler := traceRequests(webhookSocket.handler())
+
+	run := func(method string, body string) {
+		span = trace.SpanContext{}
+		req := httptest.NewRequest(method, "/records", strings.NewReader(body))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		spans := recorder.Ended()
+		// The provider receives the context of the request span.
+		assert.Equal(t, spans[len(spans)-1].SpanContext().SpanID(), span.SpanID())
+	}
+
+	run(http.MethodGet, "")
+	run(http.MethodPost, `{"Create":[]}`)
+}
@@ -0,0 +1,216 @@
+/*
+ * Webhook end-to-end - tests of the webhook API against the fake Hetzner API.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"external-dns-hetzner-webhook/internal/fakeapi"
+	"external-dns-hetzner-webhook/internal/hetzner"
+	hetznercloud "external-dns-hetzner-webhook/internal/hetzner/cloud"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/webhook/api"
+)
+
+const e2eToken = "e2e-token"
+
+// e2eEnv is a webhook connected to a fake Hetzner API.
+type e2eEnv struct {
+	fake    *fakeapi.Server
+	webhook *httptest.Server
+}
+
+// newE2EEnv starts a fake Hetzner API with the alpha.com zone and a webhook
+// using it.
+func newE2EEnv(t *testing.T, bulkMode bool) *e2eEnv {
+	fake := fakeapi.NewServer(e2eToken)
+	t.Cleanup(fake.Close)
+	fake.AddZone("alpha.com", 3600)
+	assert.Nil(t, fake.SetRRSet("alpha.com", fakeapi.RRSet{Name: "www", Type: "A", TTL: 300, Records: []string{"127.0.0.1"}}))
+	assert.Nil(t, fake.SetRRSet("alpha.com", fakeapi.RRSet{Name: "ftp", Type: "A", TTL: 300, Records: []string{"127.0.0.2"}}))
+
+	p, err := hetznercloud.NewHetznerProvider(&hetzner.Configuration{
+		APIKey:                  e2eToken,
+		APIEndpoint:             fake.URL,
+		BatchSize:               2,
+		SlashEscSeq:             "--slash--",
+		MaxFailCount:            -1,
+		BulkMode:                bulkMode,
+		BulkModeConflictRetries: 1,
+	}, nil)
+	assert.Nil(t, err)
+	webhook := httptest.NewServer(NewWebhookSocket(p, nil).handler())
+	t.Cleanup(webhook.Close)
+	return &e2eEnv{fake: fake, webhook: webhook}
+}
+
+// do sends a request to the webhook and decodes the response, if any.
+func (e *e2eEnv) do(t *testing.T, method, path string, body, result any) int {
+	var reader bytes.Buffer
+	if body != nil {
+		assert.Nil(t, json.NewEncoder(&reader).Encode(body))
+	}
+	req, err := http.NewRequest(method, e.webhook.URL+path, &reader)
+	assert.Nil(t, err)
+	req.Header.Set("Accept", api.MediaTypeFormatAndVersion)
+	req.Header.Set("Content-Type", api.MediaTypeFormatAndVersion)
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	if result != nil && res.StatusCode == http.StatusOK {
+		assert.Equal(t, api.MediaTypeFormatAndVersion, res.Header.Get("Content-Type"))
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(result))
+	}
+	return res.StatusCode
+}
+
+// records returns the A records of the zone in the fake API by name.
+func (e *e2eEnv) records(t *testing.T) map[string][]string {
+	rrsets, err := e.fake.RRSets("alpha.com")
+	assert.Nil(t, err)
+	records := make(map[string][]string)
+	for _, rrset := range rrsets {
+		if rrset.Type == "A" {
+			records[rrset.Name] = rrset.Records
+		}
+	}
+	return records
+}
+
+// imports returns the number of zonefile imports received by the fake API.
+func (e *e2eEnv) imports() int {
+	n := 0
+	for _, r := range e.fake.Requests() {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.Path, "/actions/import_zonefile") {
+			n++
+		}
+	}
+	return n
+}
+
+// findEndpoint returns the endpoint with the given name and type, or nil.
+func findEndpoint(endpoints []*endpoint.Endpoint, name, recordType string) *endpoint.Endpoint {
+	for _, ep := range endpoints {
+		if ep.DNSName == name && ep.RecordType == recordType {
+			return ep
+		}
+	}
+	return nil
+}
+
+// Test_webhook_e2e drives the webhook API against the fake Hetzner API.
+func Test_webhook_e2e(t *testing.T) {
+	type testCase struct {
+		name  string
+		input struct {
+			bulkMode bool
+		}
+		expected struct {
+			imports int
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		env := newE2EEnv(t, tc.input.bulkMode)
+
+		// Negotiation
+		var filter endpoint.DomainFilter
+		assert.Equal(t, http.StatusOK, env.do(t, http.MethodGet, "/", nil, &filter))
+
+		// Records
+		var endpoints []*endpoint.Endpoint
+		assert.Equal(t, http.StatusOK, env.do(t, http.MethodGet, api.UrlRecords, nil, &endpoints))
+		www := findEndpoint(endpoints, "www.alpha.com", "A")
+		assert.NotNil(t, www)
+		ftp := findEndpoint(endpoints, "ftp.alpha.com", "A")
+		assert.NotNil(t, ftp)
+		if www == nil || ftp == nil {
+			return
+		}
+		assert.Equal(t, endpoint.Targets{"127.0.0.1"}, www.Targets)
+		assert.Equal(t, endpoint.TTL(300), www.RecordTTL)
+
+		// Adjusted endpoints
+		created := endpoint.NewEndpointWithTTL("mail.alpha.com", "A", 600, "127.0.0.3")
+		var adjusted []*endpoint.Endpoint
+		assert.Equal(t, http.StatusOK, env.do(t, http.MethodPost, api.UrlAdjustEndpoints,
+			[]*endpoint.Endpoint{created}, &adjusted))
+		assert.Len(t, adjusted, 1)
+
+		// Changes
+		updated := endpoint.NewEndpointWithTTL("www.alpha.com", "A", 300, "127.0.0.4", "127.0.0.5")
+		changes := &plan.Changes{
+			Create:    []*endpoint.Endpoint{created},
+			UpdateOld: []*endpoint.Endpoint{www},
+			UpdateNew: []*endpoint.Endpoint{updated},
+			Delete:    []*endpoint.Endpoint{ftp},
+		}
+		assert.Equal(t, http.StatusNoContent, env.do(t, http.MethodPost, api.UrlRecords, changes, nil))
+		assert.Equal(t, map[string][]string{
+			"mail": {"127.0.0.3"},
+			"www":  {"127.0.0.4", "127.0.0.5"},
+		}, env.records(t))
+		assert.Equal(t, tc.expected.imports, env.imports())
+
+		// The records are read back.
+		endpoints = nil
+		assert.Equal(t, http.StatusOK, env.do(t, http.MethodGet, api.UrlRecords, nil, &endpoints))
+		assert.NotNil(t, findEndpoint(endpoints, "mail.alpha.com", "A"))
+		assert.Nil(t, findEndpoint(endpoints, "ftp.alpha.com", "A"))
+
+		// API errors are reported.
+		env.fake.InjectError(fakeapi.Error{Status: http.StatusForbidden, Code: "forbidden", Message: "test error"})
+		assert.Equal(t, http.StatusInternalServerError, env.do(t, http.MethodGet, api.UrlRecords, nil, nil))
+		deleted := &plan.Changes{Delete: []*endpoint.Endpoint{created}}
+		assert.Equal(t, http.StatusInternalServerError, env.do(t, http.MethodPost, api.UrlRecords, deleted, nil))
+		assert.Contains(t, env.records(t), "mail")
+
+		env.fake.ClearErrors()
+		assert.Equal(t, http.StatusNoContent, env.do(t, http.MethodPost, api.UrlRecords, deleted, nil))
+		assert.NotContains(t, env.records(t), "mail")
+	}
+
+	testCases := []testCase{
+		{
+			name: "RRSet endpoints",
+		},
+		{
+			name: "bulk mode",
+			input: struct {
+				bulkMode bool
+			}{bulkMode: true},
+			expected: struct {
+				imports int
+			}{imports: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Webhook socket - serves the ExternalDNS webhook API.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"external-dns-hetzner-webhook/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/webhook/api"
+)
+
+// WebhookSocket represents the socket that serves the ExternalDNS webhook API.
+// It replaces api.StartHTTPApi, so that the server can be shut down.
+type WebhookSocket struct {
+	socket
+	provider provider.Provider
+	metrics  *metrics.OpenMetrics
+}
+
+// NewWebhookSocket initializes a new WebhookSocket instance.
+func NewWebhookSocket(provider provider.Provider, m *metrics.OpenMetrics) *WebhookSocket {
+	return &WebhookSocket{
+		provider: provider,
+		metrics:  m,
+	}
+}
+
+// handler returns the handler for the webhook endpoints.
+func (s *WebhookSocket) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		s.webhookServer(r).NegotiateHandler(w, r)
+	})
+	mux.HandleFunc(api.UrlRecords, func(w http.ResponseWriter, r *http.Request) {
+		s.webhookServer(r).RecordsHandler(w, r)
+	})
+	mux.HandleFunc(api.UrlAdjustEndpoints, func(w http.ResponseWriter, r *http.Request) {
+		s.webhookServer(r).AdjustEndpointsHandler(w, r)
+	})
+	return mux
+}
+
+// webhookServer returns the ExternalDNS handlers for a request. The provider
+// receives the context of the request.
+func (s *WebhookSocket) webhookServer(r *http.Request) *api.WebhookServer {
+	return &api.WebhookServer{
+		Provider: newRequestProvider(s.provider, r),
+	}
+}
+
+// Start starts the webhook server. It returns when the server is shut down or
+// fails.
+func (s *WebhookSocket) Start(startedChan chan struct{}, options SocketOptions) error {
+	certs, err := newSocketCerts(options.GetWebhookTLS(), options)
+	if err != nil {
+		return fmt.Errorf("cannot configure TLS for the webhook socket: %w", err)
+	}
+	unixMode, err := options.GetUnixSocketMode()
+	if err != nil {
+		return err
+	}
+	auth, err := newAuthenticator(options.WebhookAuthMode, options.WebhookAuthSecretFile, s.metrics)
+	if err != nil {
+		return fmt.Errorf("cannot configure the webhook authentication: %w", err)
+	}
+	handler := s.handler()
+	if auth != nil {
+		log.Infof("Webhook requests authentication enabled: %s.", options.WebhookAuthMode)
+		if options.WebhookAuthAllowLocal {
+			log.Warn("The requests of local clients are accepted without authentication.")
+			auth.allowLocal = true
+		}
+		handler = auth.wrap(handler)
+	}
+	// The rejected requests are traced too.
+	handler = traceRequests(handler)
+
+	srv := &http.Server{
+		Addr:         options.GetWebhookAddress(),
+		Handler:      handler,
+		ReadTimeout:  options.GetReadTimeout(),
+		WriteTimeout: options.GetWriteTimeout(),
+		TLSConfig:    certs.TLSConfig(),
+	}
+	ctx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	go certs.watch(ctx, certCheckInterval)
+	return s.serve(startedChan, srv, unixMode)
+}
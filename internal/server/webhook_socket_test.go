@@ -0,0 +1,80 @@
+/*
+ * Webhook socket - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// testWebhookPort is the test port for the webhook server on localhost.
+const testWebhookPort = testPort + 1
+
+// mockProvider is a provider without records.
+type mockProvider struct {
+	provider.BaseProvider
+}
+
+// Records returns an empty list of endpoints.
+func (p mockProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return []*endpoint.Endpoint{}, nil
+}
+
+// ApplyChanges does nothing.
+func (p mockProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	return nil
+}
+
+func Test_WebhookSocket_Shutdown(t *testing.T) {
+	t.Run("not started", func(t *testing.T) {
+		webhookSocket := NewWebhookSocket(mockProvider{}, nil)
+		assert.Nil(t, webhookSocket.Shutdown(context.Background()))
+	})
+	t.Run("started", func(t *testing.T) {
+		options := SocketOptions{
+			WebhookHost: testHost,
+			WebhookPort: testWebhookPort,
+		}
+		startedChan := make(chan struct{})
+		webhookSocket := NewWebhookSocket(mockProvider{}, nil)
+		done := make(chan error)
+		go func() {
+			done <- webhookSocket.Start(startedChan, options)
+		}()
+		<-startedChan
+
+		url := fmt.Sprintf("http://%s:%d/records", testHost, testWebhookPort)
+		res, err := http.Get(url)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		err = webhookSocket.Shutdown(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, <-done)
+
+		_, err = http.Get(url)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,67 @@
+/*
+ * Options - tracing configuration.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracing
+
+import (
+	"fmt"
+
+	"github.com/codingconcepts/env"
+)
+
+const (
+	// Tracing disabled
+	ExporterNone = ""
+	// Export to an OpenTelemetry collector using OTLP over HTTP
+	ExporterOTLP = "otlp"
+	// Export to the standard output, useful for tests
+	ExporterStdout = "stdout"
+)
+
+// Options contains the tracing configuration passed as environment variables.
+type Options struct {
+	// Span exporter: otlp, stdout or empty to disable the tracing
+	Exporter string `env:"TRACING_EXPORTER" default:""`
+	// OTLP endpoint as host:port
+	OTLPEndpoint string `env:"TRACING_OTLP_ENDPOINT" default:"localhost:4318"`
+	// Use plain HTTP for the OTLP endpoint
+	OTLPInsecure bool `env:"TRACING_OTLP_INSECURE" default:"false"`
+	// Fraction of the traces that are sampled, between 0 and 1
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+// NewOptions reads the tracing options from the environment.
+func NewOptions() (*Options, error) {
+	options := &Options{}
+	if err := env.Set(options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// Validate checks the options.
+func (o Options) Validate() error {
+	switch o.Exporter {
+	case ExporterNone, ExporterOTLP, ExporterStdout:
+	default:
+		return fmt.Errorf("unsupported tracing exporter %s", o.Exporter)
+	}
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio %g out of range [0, 1]", o.SampleRatio)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Options - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewOptions(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", "otlp")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	options, err := NewOptions()
+	assert.Nil(t, err)
+	expected := &Options{
+		Exporter:     ExporterOTLP,
+		OTLPEndpoint: "localhost:4318",
+		SampleRatio:  0.25,
+	}
+	assert.Equal(t, expected, options)
+}
+
+func Test_Options_Validate(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    Options
+		expected bool
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		err := tc.input.Validate()
+		assert.Equal(t, tc.expected, err == nil)
+	}
+
+	testCases := []testCase{
+		{name: "disabled", input: Options{SampleRatio: 1}, expected: true},
+		{name: "otlp", input: Options{Exporter: ExporterOTLP, SampleRatio: 0.5}, expected: true},
+		{name: "stdout", input: Options{Exporter: ExporterStdout}, expected: true},
+		{name: "unsupported exporter", input: Options{Exporter: "jaeger"}, expected: false},
+		{name: "negative ratio", input: Options{SampleRatio: -0.1}, expected: false},
+		{name: "ratio above 1", input: Options{SampleRatio: 1.5}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Tracing - OpenTelemetry tracing of the webhook.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Name of the service and of the tracer
+	serviceName = "external-dns-hetzner-webhook"
+
+	// Zone name
+	AttrZone = attribute.Key("dns.zone")
+	// Hetzner API action
+	AttrAction = attribute.Key("hetzner.action")
+	// Number of records or endpoints
+	AttrRecords = attribute.Key("dns.records")
+	// Number of records to create
+	AttrCreates = attribute.Key("dns.changes.create")
+	// Number of records to update
+	AttrUpdates = attribute.Key("dns.changes.update")
+	// Number of records to delete
+	AttrDeletes = attribute.Key("dns.changes.delete")
+)
+
+// newExporter creates the span exporter for the options.
+func newExporter(ctx context.Context, options Options) (sdktrace.SpanExporter, error) {
+	if options.Exporter == ExporterStdout {
+		return stdouttrace.New()
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(options.OTLPEndpoint)}
+	if options.OTLPInsecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
+
+// Setup configures the global tracer provider and the W3C trace context
+// propagation. It returns a function that flushes the pending spans and stops
+// the exporter. If the tracing is disabled, the spans are discarded.
+func Setup(ctx context.Context, options Options, version string) (func(context.Context) error, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if options.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := newExporter(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the %s span exporter: %w", options.Exporter, err)
+	}
+	res := resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a new internal span as a child of the span in the context, if
+// any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return StartKind(ctx, trace.SpanKindInternal, name, attrs...)
+}
+
+// StartKind starts a new span of the given kind as a child of the span in the
+// context, if any.
+func StartKind(ctx context.Context, kind trace.SpanKind, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(serviceName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,126 @@
+/*
+ * Tracing - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useSpanRecorder records the spans until the end of the test.
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	bkpProvider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(bkpProvider) })
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func Test_Setup(t *testing.T) {
+	bkpProvider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(bkpProvider)
+
+	type testCase struct {
+		name     string
+		input    Options
+		expected struct {
+			sdk bool
+			err bool
+		}
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		otel.SetTracerProvider(bkpProvider)
+		shutdown, err := Setup(context.Background(), tc.input, "test")
+		assert.Equal(t, exp.err, err != nil)
+		if err != nil {
+			return
+		}
+		_, isSDK := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+		assert.Equal(t, exp.sdk, isSDK)
+		assert.Nil(t, shutdown(context.Background()))
+	}
+
+	testCases := []testCase{
+		{
+			name:  "disabled",
+			input: Options{SampleRatio: 1},
+		},
+		{
+			name:  "stdout",
+			input: Options{Exporter: ExporterStdout, SampleRatio: 1},
+			expected: struct {
+				sdk bool
+				err bool
+			}{sdk: true},
+		},
+		{
+			name:  "otlp",
+			input: Options{Exporter: ExporterOTLP, OTLPEndpoint: "localhost:4318", SampleRatio: 1},
+			expected: struct {
+				sdk bool
+				err bool
+			}{sdk: true},
+		},
+		{
+			name:  "invalid options",
+			input: Options{Exporter: "jaeger"},
+			expected: struct {
+				sdk bool
+				err bool
+			}{err: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func Test_StartEnd(t *testing.T) {
+	recorder := useSpanRecorder(t)
+	ctx, parent := StartKind(context.Background(), trace.SpanKindServer, "parent")
+	_, child := Start(ctx, "child", AttrZone.String("alpha.com"), AttrRecords.Int(3))
+	End(child, errors.New("test error"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	childSpan, parentSpan := spans[0], spans[1]
+	assert.Equal(t, "child", childSpan.Name())
+	assert.Equal(t, trace.SpanKindInternal, childSpan.SpanKind())
+	assert.Equal(t, parentSpan.SpanContext().SpanID(), childSpan.Parent().SpanID())
+	assert.Contains(t, childSpan.Attributes(), AttrZone.String("alpha.com"))
+	assert.Contains(t, childSpan.Attributes(), AttrRecords.Int(3))
+	assert.Equal(t, codes.Error, childSpan.Status().Code)
+	assert.Equal(t, "test error", childSpan.Status().Description)
+	assert.Len(t, childSpan.Events(), 1)
+	assert.Equal(t, trace.SpanKindServer, parentSpan.SpanKind())
+	assert.Equal(t, codes.Unset, parentSpan.Status().Code)
+}
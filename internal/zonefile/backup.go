@@ -0,0 +1,183 @@
+/*
+ * Backup - zonefile backups.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zonefile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"external-dns-hetzner-webhook/internal/logging"
+)
+
+// log is the logger of the zonefile subsystem.
+var log = logging.Logger(logging.Zonefile)
+
+const (
+	// format of the timestamp in the backup file names
+	fmtBackupTime = "20060102T150405.000000000Z"
+	// extension of the backup files
+	backupExt = ".zone"
+)
+
+// Backups stores the zonefile backups in a directory, with one subdirectory
+// for each zone. The backups are rotated when a new one is saved: only the
+// newest maxCount backups are kept, and the ones older than maxAge are
+// deleted. A zero or negative value disables the corresponding limit.
+type Backups struct {
+	dir      string
+	maxCount int
+	maxAge   time.Duration
+}
+
+// NewBackups creates a new Backups instance, creating the backup directory if
+// it does not exist.
+func NewBackups(dir string, maxCount int, maxAge time.Duration) (*Backups, error) {
+	if dir == "" {
+		return nil, errors.New("empty backup directory provided")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create backup directory %s: %w", dir, err)
+	}
+	return &Backups{
+		dir:      dir,
+		maxCount: maxCount,
+		maxAge:   maxAge,
+	}, nil
+}
+
+// zoneDir returns the directory containing the backups for a zone. Zone names
+// with path components are rejected, so that the directory is always inside
+// the backup directory.
+func (b Backups) zoneDir(zone string) (string, error) {
+	if zone == "" || zone == "." || zone == ".." || filepath.Base(zone) != zone {
+		return "", fmt.Errorf("invalid zone name %s", zone)
+	}
+	return filepath.Join(b.dir, zone), nil
+}
+
+// Save stores a zonefile backup for the zone and rotates the old backups. It
+// returns the name of the new backup.
+func (b Backups) Save(zone string, zf string) (string, error) {
+	dir, err := b.zoneDir(zone)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("cannot create backup directory for zone %s: %w", zone, err)
+	}
+	name := time.Now().UTC().Format(fmtBackupTime) + backupExt
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(zf), 0o600); err != nil {
+		return "", fmt.Errorf("cannot write backup for zone %s: %w", zone, err)
+	}
+	if err := b.rotate(zone); err != nil {
+		log.Warnf("Cannot rotate the backups for zone %s: %v", zone, err)
+	}
+	return name, nil
+}
+
+// List returns the names of the backups available for the zone, from the
+// newest to the oldest.
+func (b Backups) List(zone string) ([]string, error) {
+	dir, err := b.zoneDir(zone)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot list backups for zone %s: %w", zone, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), backupExt) {
+			names = append(names, e.Name())
+		}
+	}
+	// The timestamp format ensures that the lexical order is chronological.
+	slices.Sort(names)
+	slices.Reverse(names)
+	return names, nil
+}
+
+// Load reads a backup for the zone. If the name is empty, the newest backup
+// is read.
+func (b Backups) Load(zone string, name string) (string, error) {
+	dir, err := b.zoneDir(zone)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		names, err := b.List(zone)
+		if err != nil {
+			return "", err
+		}
+		if len(names) == 0 {
+			return "", fmt.Errorf("no backups found for zone %s", zone)
+		}
+		name = names[0]
+	}
+	if filepath.Base(name) != name {
+		return "", fmt.Errorf("invalid backup name %s", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", fmt.Errorf("cannot read backup %s for zone %s: %w", name, zone, err)
+	}
+	return string(data), nil
+}
+
+// backupTime returns the time a backup was taken, reading it from its name.
+func backupTime(name string) (time.Time, error) {
+	return time.Parse(fmtBackupTime, strings.TrimSuffix(name, backupExt))
+}
+
+// rotate deletes the backups for the zone that exceed the retention limits.
+func (b Backups) rotate(zone string) error {
+	dir, err := b.zoneDir(zone)
+	if err != nil {
+		return err
+	}
+	names, err := b.List(zone)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	errs := make([]error, 0)
+	for i, name := range names {
+		expired := b.maxCount > 0 && i >= b.maxCount
+		if !expired && b.maxAge > 0 {
+			if t, err := backupTime(name); err == nil && now.Sub(t) > b.maxAge {
+				expired = true
+			}
+		}
+		if !expired {
+			continue
+		}
+		log.Debugf("Deleting backup %s for zone %s", name, zone)
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
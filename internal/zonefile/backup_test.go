@@ -0,0 +1,243 @@
+/*
+ * Backup - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zonefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestBackup writes a backup file taken at the given time.
+func writeTestBackup(t *testing.T, dir string, zone string, ts time.Time, content string) string {
+	zoneDir := filepath.Join(dir, zone)
+	assert.Nil(t, os.MkdirAll(zoneDir, 0o700))
+	name := ts.UTC().Format(fmtBackupTime) + backupExt
+	assert.Nil(t, os.WriteFile(filepath.Join(zoneDir, name), []byte(content), 0o600))
+	return name
+}
+
+// Test_NewBackups tests NewBackups().
+func Test_NewBackups(t *testing.T) {
+	t.Run("empty directory", func(t *testing.T) {
+		_, err := NewBackups("", 10, 0)
+		assert.EqualError(t, err, "empty backup directory provided")
+	})
+	t.Run("directory created", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "backups")
+		b, err := NewBackups(dir, 10, time.Hour)
+		assert.Nil(t, err)
+		assert.Equal(t, &Backups{dir: dir, maxCount: 10, maxAge: time.Hour}, b)
+		assert.DirExists(t, dir)
+	})
+}
+
+// Test_Backups_zoneDir tests Backups.zoneDir().
+func Test_Backups_zoneDir(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected struct {
+			dir string
+			err string
+		}
+	}
+
+	dir := t.TempDir()
+	b, err := NewBackups(dir, 0, 0)
+	assert.Nil(t, err)
+
+	run := func(t *testing.T, tc testCase) {
+		exp := tc.expected
+		actual, err := b.zoneDir(tc.input)
+		if exp.err != "" {
+			assert.EqualError(t, err, exp.err)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, exp.dir, actual)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "zone name",
+			input: testZone,
+			expected: struct {
+				dir string
+				err string
+			}{
+				dir: filepath.Join(dir, testZone),
+			},
+		},
+		{
+			name:  "empty zone name",
+			input: "",
+			expected: struct {
+				dir string
+				err string
+			}{
+				err: "invalid zone name ",
+			},
+		},
+		{
+			name:  "parent directory",
+			input: "..",
+			expected: struct {
+				dir string
+				err string
+			}{
+				err: "invalid zone name ..",
+			},
+		},
+		{
+			name:  "relative path",
+			input: "../x",
+			expected: struct {
+				dir string
+				err string
+			}{
+				err: "invalid zone name ../x",
+			},
+		},
+		{
+			name:  "absolute path",
+			input: "/etc",
+			expected: struct {
+				dir string
+				err string
+			}{
+				err: "invalid zone name /etc",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Test_Backups_SaveLoad tests Backups.Save() and Backups.Load().
+func Test_Backups_SaveLoad(t *testing.T) {
+	b, err := NewBackups(t.TempDir(), 0, 0)
+	assert.Nil(t, err)
+
+	_, err = b.Load(testZone, "")
+	assert.EqualError(t, err, "no backups found for zone fastipletonis.eu")
+
+	name, err := b.Save(testZone, testMiniZonefile)
+	assert.Nil(t, err)
+
+	latest, err := b.Load(testZone, "")
+	assert.Nil(t, err)
+	assert.Equal(t, testMiniZonefile, latest)
+
+	named, err := b.Load(testZone, name)
+	assert.Nil(t, err)
+	assert.Equal(t, testMiniZonefile, named)
+
+	_, err = b.Load(testZone, "../"+name)
+	assert.EqualError(t, err, "invalid backup name ../"+name)
+
+	_, err = b.Load("../"+testZone, name)
+	assert.EqualError(t, err, "invalid zone name ../"+testZone)
+}
+
+// Test_Backups_List tests Backups.List().
+func Test_Backups_List(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewBackups(dir, 0, 0)
+	assert.Nil(t, err)
+	now := time.Now()
+	oldest := writeTestBackup(t, dir, testZone, now.Add(-2*time.Hour), "old")
+	newest := writeTestBackup(t, dir, testZone, now, "new")
+	middle := writeTestBackup(t, dir, testZone, now.Add(-time.Hour), "middle")
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, testZone, "notes.txt"), []byte{}, 0o600))
+
+	names, err := b.List(testZone)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{newest, middle, oldest}, names)
+
+	names, err = b.List("other.com")
+	assert.Nil(t, err)
+	assert.Empty(t, names)
+}
+
+// Test_Backups_rotate tests Backups.rotate().
+func Test_Backups_rotate(t *testing.T) {
+	type testCase struct {
+		name     string
+		maxCount int
+		maxAge   time.Duration
+		expected []int
+	}
+
+	ages := []time.Duration{0, time.Hour, 2 * time.Hour, 3 * time.Hour}
+
+	run := func(t *testing.T, tc testCase) {
+		dir := t.TempDir()
+		b, err := NewBackups(dir, tc.maxCount, tc.maxAge)
+		assert.Nil(t, err)
+		now := time.Now()
+		names := make([]string, len(ages))
+		for i, age := range ages {
+			names[i] = writeTestBackup(t, dir, testZone, now.Add(-age), "content")
+		}
+		assert.Nil(t, b.rotate(testZone))
+		expected := make([]string, len(tc.expected))
+		for i, idx := range tc.expected {
+			expected[i] = names[idx]
+		}
+		actual, err := b.List(testZone)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	testCases := []testCase{
+		{
+			name:     "no limits",
+			expected: []int{0, 1, 2, 3},
+		},
+		{
+			name:     "count limit",
+			maxCount: 2,
+			expected: []int{0, 1},
+		},
+		{
+			name:     "age limit",
+			maxAge:   90 * time.Minute,
+			expected: []int{0, 1},
+		},
+		{
+			name:     "both limits",
+			maxCount: 1,
+			maxAge:   90 * time.Minute,
+			expected: []int{0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
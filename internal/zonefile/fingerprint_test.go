@@ -0,0 +1,75 @@
+/*
+ * Fingerprint - unit tests.
+ *
+ * Copyright 2026 Marco Confalonieri.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zonefile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_Fingerprint tests Fingerprint().
+func Test_Fingerprint(t *testing.T) {
+	type testCase struct {
+		name     string
+		first    string
+		second   string
+		expected bool
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		actual := Fingerprint(tc.first) == Fingerprint(tc.second)
+		assert.Equal(t, tc.expected, actual)
+	}
+
+	testCases := []testCase{
+		{
+			name:     "same file",
+			first:    testMiniZonefile,
+			second:   testMiniZonefile,
+			expected: true,
+		},
+		{
+			name:  "different export time",
+			first: testMiniZonefile,
+			second: strings.Replace(testMiniZonefile,
+				"2026-01-19T21:39:41Z", "2026-01-19T21:40:12Z", 1),
+			expected: true,
+		},
+		{
+			name:  "different serial number",
+			first: testMiniZonefile,
+			second: strings.Replace(testMiniZonefile,
+				"2025112009", "2025112010", 1),
+			expected: false,
+		},
+		{
+			name:     "record added",
+			first:    testMiniZonefile,
+			second:   testMiniZonefile + "ftp\t3600\tIN\tA\t116.202.181.3\n",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}